@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is looked up in the working directory before falling back
+// to $XDG_CONFIG_HOME/tsq/config.yaml.
+const configFileName = ".tsq.yaml"
+
+// config holds default flag values loaded from a config file.
+//
+// Precedence, highest first: command-line flag, TSQ_* environment variable,
+// config file, built-in default.
+//
+// Format, from the config file or TSQ_FORMAT, only applies to the commands
+// whose --format accepts it, so a default such as rg doesn't break the
+// commands without that format.
+type config struct {
+	Path       string   `yaml:"path"`
+	Language   string   `yaml:"language"`
+	Jobs       int      `yaml:"jobs"`
+	MaxBytes   int64    `yaml:"max-bytes"`
+	Compact    *bool    `yaml:"compact"`
+	IgnoreDirs []string `yaml:"ignore-dirs"`
+	Format     string   `yaml:"format"`
+}
+
+// envVars maps configurable flag names to the environment variables that
+// override them.
+var envVars = map[string]string{
+	"path":        "TSQ_PATH",
+	"language":    "TSQ_LANGUAGE",
+	"jobs":        "TSQ_JOBS",
+	"max-bytes":   "TSQ_MAX_BYTES",
+	"compact":     "TSQ_COMPACT",
+	"ignore-dirs": "TSQ_IGNORE_DIRS",
+	"format":      "TSQ_FORMAT",
+}
+
+// loadConfig reads .tsq.yaml from dir, or the user config file if dir has
+// none. A missing config file yields an empty config.
+func loadConfig(dir string) (*config, error) {
+	candidates := []string{filepath.Join(dir, configFileName)}
+	if xdg := userConfigDir(); xdg != "" {
+		candidates = append(candidates, filepath.Join(xdg, "tsq", "config.yaml"))
+	}
+
+	for _, path := range candidates {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var cfg config
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return &cfg, nil
+	}
+
+	return &config{}, nil
+}
+
+func userConfigDir() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return xdg
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config")
+}
+
+// apply sets config values as flag defaults on each command and wires up the
+// TSQ_* environment variables, so explicitly passed flags still win.
+func (c *config) apply(cmds []*cli.Command) {
+	for _, cmd := range cmds {
+		for _, flag := range cmd.Flags {
+			switch f := flag.(type) {
+			case *cli.StringFlag:
+				if f.Name == "path" && c.Path != "" {
+					f.Value = c.Path
+				}
+				if f.Name == "language" && c.Language != "" {
+					f.Value = c.Language
+				}
+				if f.Name == "ignore-dirs" && len(c.IgnoreDirs) > 0 {
+					f.Value = strings.Join(c.IgnoreDirs, ",")
+				}
+				if f.Name == "format" {
+					if acceptsFormat(cmd.Name, c.Format) {
+						f.Value = c.Format
+					}
+					if !acceptsFormat(cmd.Name, os.Getenv(envVars["format"])) {
+						continue
+					}
+				}
+				setEnvSource(&f.Sources, f.Name)
+			case *cli.IntFlag:
+				if f.Name == "jobs" && c.Jobs > 0 {
+					f.Value = c.Jobs
+				}
+				setEnvSource(&f.Sources, f.Name)
+			case *cli.Int64Flag:
+				if f.Name == "max-bytes" && c.MaxBytes > 0 {
+					f.Value = c.MaxBytes
+				}
+				setEnvSource(&f.Sources, f.Name)
+			case *cli.BoolFlag:
+				if f.Name == "compact" && c.Compact != nil {
+					f.Value = *c.Compact
+				}
+				setEnvSource(&f.Sources, f.Name)
+			}
+		}
+	}
+}
+
+// acceptsFormat reports whether the --format of the named command accepts
+// format.
+func acceptsFormat(command, format string) bool {
+	return format == "json" || slices.Contains(commandFormats[command], format)
+}
+
+func setEnvSource(sources *cli.ValueSourceChain, flagName string) {
+	if env, ok := envVars[flagName]; ok {
+		*sources = cli.EnvVars(env)
+	}
+}
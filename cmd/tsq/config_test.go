@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+func TestConfigPrecedence(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "tsq-config-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("TSQ_MAX_BYTES", "123")
+
+	err = os.WriteFile(filepath.Join(tmpDir, configFileName), []byte(
+		"path: src\njobs: 3\nmax-bytes: 456\ncompact: true\n",
+	), 0644)
+	require.NoError(t, err)
+
+	cfg, err := loadConfig(tmpDir)
+	require.NoError(t, err)
+
+	cmd := symbolsCommand()
+	var got struct {
+		path     string
+		jobs     int
+		maxBytes int64
+		compact  bool
+	}
+	cmd.Action = func(_ context.Context, cmd *cli.Command) error {
+		got.path = cmd.String("path")
+		got.jobs = cmd.Int("jobs")
+		got.maxBytes = cmd.Int64("max-bytes")
+		got.compact = cmd.Bool("compact")
+		return nil
+	}
+	cfg.apply([]*cli.Command{cmd})
+
+	require.NoError(t, cmd.Run(context.Background(), []string{"symbols", "--jobs", "7"}))
+	require.Equal(t, "src", got.path, "config overrides built-in default")
+	require.Equal(t, 7, got.jobs, "flag overrides config")
+	require.Equal(t, int64(123), got.maxBytes, "env overrides config")
+	require.True(t, got.compact)
+}
+
+func TestLoadConfig(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "tsq-config-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	xdgDir := filepath.Join(tmpDir, "xdg")
+	t.Setenv("XDG_CONFIG_HOME", xdgDir)
+
+	// No config anywhere
+	cfg, err := loadConfig(tmpDir)
+	require.NoError(t, err)
+	require.Equal(t, &config{}, cfg)
+
+	// Falls back to the user config file
+	require.NoError(t, os.MkdirAll(filepath.Join(xdgDir, "tsq"), 0755))
+	err = os.WriteFile(filepath.Join(xdgDir, "tsq", "config.yaml"), []byte("language: go\n"), 0644)
+	require.NoError(t, err)
+	cfg, err = loadConfig(tmpDir)
+	require.NoError(t, err)
+	require.Equal(t, "go", cfg.Language)
+
+	// Unknown keys are rejected
+	err = os.WriteFile(filepath.Join(tmpDir, configFileName), []byte("jbos: 2\n"), 0644)
+	require.NoError(t, err)
+	_, err = loadConfig(tmpDir)
+	require.Error(t, err)
+}
+
+func TestConfigIgnoreDirsAndFormat(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "tsq-config-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
+	err = os.WriteFile(filepath.Join(tmpDir, configFileName), []byte(
+		"ignore-dirs: [gen, testdata]\nformat: rg\n",
+	), 0644)
+	require.NoError(t, err)
+
+	cfg, err := loadConfig(tmpDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"gen", "testdata"}, cfg.IgnoreDirs)
+	require.Equal(t, "rg", cfg.Format)
+
+	run := func(cmd *cli.Command, args ...string) (ignoreDirs []string, format string) {
+		cmd.Action = func(_ context.Context, cmd *cli.Command) error {
+			ignoreDirs = splitList(cmd.String("ignore-dirs"))
+			format, err = parseFormatFlag(cmd)
+			return err
+		}
+		cfg.apply([]*cli.Command{cmd})
+		require.NoError(t, cmd.Run(context.Background(), append([]string{cmd.Name}, args...)))
+		return ignoreDirs, format
+	}
+
+	ignoreDirs, format := run(refsCommand(), "--symbol", "Run")
+	require.Equal(t, []string{"gen", "testdata"}, ignoreDirs)
+	require.Equal(t, "rg", format)
+
+	ignoreDirs, format = run(refsCommand(), "--symbol", "Run", "--ignore-dirs", "tmp", "--format", "quickfix")
+	require.Equal(t, []string{"tmp"}, ignoreDirs, "flag overrides config")
+	require.Equal(t, "quickfix", format, "flag overrides config")
+
+	_, format = run(symbolsCommand())
+	require.Equal(t, "json", format, "commands without the configured format keep their default")
+	_, format = run(treeCommand(), "--file", "main.go")
+	require.Equal(t, "sexp", format)
+
+	t.Setenv("TSQ_FORMAT", "quickfix")
+	_, format = run(refsCommand(), "--symbol", "Run")
+	require.Equal(t, "quickfix", format, "env overrides config")
+	_, format = run(refsCommand(), "--symbol", "Run", "--format", "json")
+	require.Equal(t, "json", format, "flag overrides env")
+	_, format = run(treeCommand(), "--file", "main.go")
+	require.Equal(t, "sexp", format, "commands without the env format keep their default")
+
+	t.Setenv("TSQ_FORMAT", "sexp")
+	_, format = run(refsCommand(), "--symbol", "Run")
+	require.Equal(t, "rg", format, "the config applies where the env format doesn't")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
+	"strings"
 
 	"github.com/urfave/cli/v3"
 )
@@ -11,19 +12,90 @@ import (
 //go:embed example_queries.txt
 var examplesText string
 
+// example is one query of example_queries.txt, as printed by
+// example-queries --format json.
+type example struct {
+	Section     string `json:"section"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Query       string `json:"query"`
+}
+
 func examplesCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "example-queries",
 		Usage: "show example tree-sitter queries",
 		Description: "Print example query patterns for common Go code structures.\n" +
-			"Output is designed to be grep-friendly.\n\n" +
+			"Output is designed to be grep-friendly; --format json lists them as\n" +
+			"{section, title, description, query} objects.\n\n" +
 			"Examples:\n" +
 			"  tsq example-queries                   # show all examples\n" +
 			"  tsq example-queries | grep func       # find function-related patterns\n" +
-			"  tsq example-queries | grep -A2 struct # find struct patterns with context",
-		Action: func(_ context.Context, _ *cli.Command) error {
+			"  tsq example-queries | grep -A2 struct # find struct patterns with context\n" +
+			"  tsq example-queries --format json     # machine-readable examples",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "text",
+				Usage: "output format: text or json",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize JSON output",
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			format, err := parseFormatFlag(cmd)
+			if err != nil {
+				return err
+			}
+			if format == "json" {
+				return writeJSON(parseExamples(examplesText), cmd.Bool("compact"))
+			}
 			fmt.Print(examplesText)
 			return nil
 		},
 	}
 }
+
+// parseExamples splits the examples text into its queries. Paragraphs are
+// separated by blank lines; each example is a paragraph of "# " comment
+// lines (the first is the title, any others the description) followed by
+// the query. Sections are headed by a title between "# ===" rules, and may
+// be divided by "# --- Name ---" lines. Paragraphs without a query, such as
+// the file header, are skipped.
+func parseExamples(text string) []example {
+	var examples []example
+	var section, subsection string
+	for _, para := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		lines := strings.Split(strings.Trim(para, "\n"), "\n")
+
+		var comments []string
+		i := 0
+		for ; i < len(lines) && strings.HasPrefix(lines[i], "#"); i++ {
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(lines[i], "#")))
+		}
+		query := strings.TrimSpace(strings.Join(lines[i:], "\n"))
+
+		if query == "" {
+			switch {
+			case len(comments) == 3 && strings.HasPrefix(comments[0], "==="):
+				section, subsection = comments[1], ""
+			case len(comments) == 1 && strings.HasPrefix(comments[0], "---"):
+				subsection = strings.TrimSpace(strings.Trim(comments[0], "-"))
+			}
+			continue
+		}
+
+		ex := example{Section: section, Query: query}
+		if subsection != "" {
+			ex.Section += " / " + subsection
+		}
+		if len(comments) > 0 {
+			ex.Title = comments[0]
+			ex.Description = strings.Join(comments[1:], " ")
+		}
+		examples = append(examples, ex)
+	}
+	return examples
+}
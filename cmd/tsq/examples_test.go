@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExamples(t *testing.T) {
+	examples := parseExamples(examplesText)
+	require.Greater(t, len(examples), 100)
+
+	for _, ex := range examples {
+		require.NotEmpty(t, ex.Section, "example %q", ex.Title)
+		require.NotEmpty(t, ex.Title, "example %q", ex.Query)
+		require.NotEmpty(t, ex.Query, "example %q", ex.Title)
+	}
+
+	require.Equal(t, example{
+		Section: "BASIC PATTERNS - Start Here / Functions",
+		Title:   "Extract just function names",
+		Query:   "(function_declaration name: (identifier) @name)",
+	}, examples[1])
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"slices"
+	"strings"
 
 	"github.com/arjunmahishi/tsq/tsq"
 	"github.com/urfave/cli/v3"
@@ -18,17 +26,79 @@ func main() {
 	app := &cli.Command{
 		Name:  "tsq",
 		Usage: "tree-sitter query tool (like jq for code)",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "estimate-tokens",
+				Usage: "print an approximate LLM token count of the output to stderr",
+			},
+			&cli.BoolFlag{
+				Name:  "compact-positions",
+				Usage: "encode positions as [line,col] and ranges as [[line,col],[line,col]]",
+			},
+			&cli.IntFlag{
+				Name:  "max-output-bytes",
+				Usage: "cap JSON output at this many bytes, dropping trailing results and wrapping the output as {\"results\": ..., \"truncated\": bool} (0 = no cap; not supported with non-JSON output)",
+			},
+			&cli.StringFlag{
+				Name:  "grammar-dir",
+				Usage: "load extra languages from compiled tree-sitter grammars (.so/.dylib) in this directory, with extensions from its grammars.json",
+			},
+		},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			if cmd.Bool("estimate-tokens") {
+				stdout = &countingWriter{w: os.Stdout}
+			}
+			compactPositions = cmd.Bool("compact-positions")
+			maxOutputBytes = cmd.Int("max-output-bytes")
+			if dir := cmd.String("grammar-dir"); dir != "" {
+				if _, err := tsq.LoadGrammarDir(dir); err != nil {
+					return ctx, err
+				}
+			}
+			return ctx, nil
+		},
+		After: func(_ context.Context, _ *cli.Command) error {
+			if cw, ok := stdout.(*countingWriter); ok {
+				writeTokenEstimate(cw.n)
+			}
+			return nil
+		},
 		Commands: []*cli.Command{
 			queryCommand(),
 			symbolsCommand(),
 			outlineCommand(),
 			refsCommand(),
+			apiCommand(),
+			apiDiffCommand(),
+			importsCommand(),
+			commentsCommand(),
+			hotspotsCommand(),
+			todosCommand(),
+			infoCommand(),
+			churnCommand(),
+			testsCommand(),
+			shadowsCommand(),
+			treeCommand(),
+			statsCommand(),
+			moduleCommand(),
+			getSnippetCommand(),
 			examplesCommand(),
 			skillCommand(),
 		},
 	}
 
-	if err := app.Run(context.Background(), os.Args); err != nil {
+	cfg, err := loadConfig(".")
+	if err != nil {
+		writeError(err)
+		os.Exit(1)
+	}
+	cfg.apply(app.Commands)
+
+	// Interrupting stops in-flight scans instead of waiting for them.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := app.Run(ctx, os.Args); err != nil {
 		writeError(err)
 		os.Exit(1)
 	}
@@ -43,6 +113,8 @@ func queryCommand() *cli.Command {
 			"Use @name syntax to capture nodes:\n" +
 			"  (function_declaration) @fn                       - captures whole function\n" +
 			"  (function_declaration name: (identifier) @name)  - captures just the name\n\n" +
+			"Common Go lookups don't need a query: --find function --name 'Test*'\n" +
+			"generates one and filters names by glob.\n\n" +
 			"Run 'tsq example-queries' for more query patterns.",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
@@ -54,6 +126,18 @@ func queryCommand() *cli.Command {
 				Name:  "query-file",
 				Usage: "path to a tree-sitter query file",
 			},
+			&cli.StringFlag{
+				Name:  "find",
+				Usage: "instead of a query, find Go declarations of a kind: " + strings.Join(tsq.FindTargets(), ", "),
+			},
+			&cli.StringFlag{
+				Name:  "name",
+				Usage: "with --find, only declarations whose name matches this glob (e.g. 'Test*')",
+			},
+			&cli.StringFlag{
+				Name:  "receiver",
+				Usage: "with --find method, only methods whose receiver type matches this glob",
+			},
 			&cli.StringFlag{
 				Name:  "path",
 				Value: ".",
@@ -64,6 +148,57 @@ func queryCommand() *cli.Command {
 				Aliases: []string{"f"},
 				Usage:   "single file to query",
 			},
+			&cli.StringFlag{
+				Name:  "path-relative-to",
+				Value: "root",
+				Usage: "make file paths relative to the scan root (root) or the git worktree root (git)",
+			},
+			&cli.StringFlag{
+				Name:  "base-dir",
+				Usage: "make file paths relative to this directory, wherever the scan starts",
+			},
+			&cli.StringFlag{
+				Name:  "schedule",
+				Value: "fifo",
+				Usage: "order files are handed to workers: fifo (scan order) or size (largest first)",
+			},
+			&cli.IntFlag{
+				Name:  "sample",
+				Usage: "only analyze a random sample of N files (0 = all), reproducible with --seed",
+			},
+			&cli.Int64Flag{
+				Name:  "seed",
+				Usage: "seed for --sample",
+			},
+			&cli.IntFlag{
+				Name:  "max-files",
+				Usage: "stop scanning after this many files (0 = no limit)",
+			},
+			&cli.BoolFlag{
+				Name:  "hidden",
+				Usage: "scan dot-prefixed files and directories (skipped by default)",
+			},
+			&cli.StringFlag{
+				Name:  "ignore-dirs",
+				Usage: "comma-separated directory names to skip besides the default ones (vendor, node_modules, ...)",
+			},
+			&cli.BoolFlag{
+				Name:  "gitignore",
+				Value: true,
+				Usage: "skip files excluded by .gitignore files under --path or above it in its git worktree (--gitignore=false to scan them)",
+			},
+			&cli.BoolFlag{
+				Name:  "respect-ignore-single",
+				Usage: "apply the directory-scan ignore rules (vendor, hidden files, ...) to --file too",
+			},
+			&cli.BoolFlag{
+				Name:  "strict-parse",
+				Usage: "skip files with syntax errors instead of using their partial trees (reported on stderr)",
+			},
+			&cli.StringFlag{
+				Name:  "filter",
+				Usage: "keep only results matching an expression, e.g. 'kind == \"function\" && visibility == \"public\"'",
+			},
 			&cli.BoolFlag{
 				Name:  "compact",
 				Usage: "minimize output for LLM context limits",
@@ -76,41 +211,239 @@ func queryCommand() *cli.Command {
 			},
 			&cli.Int64Flag{
 				Name:  "max-bytes",
-				Value: 2 * 1024 * 1024,
-				Usage: "skip files larger than this",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+			&cli.IntFlag{
+				Name:  "max-per-file",
+				Usage: "cap the number of matches contributed by a single file (0 = no cap)",
+			},
+			&cli.BoolFlag{
+				Name:  "with-legend",
+				Usage: "wrap matches in an envelope with the query's capture names and counts (not with --filter)",
+			},
+			&cli.BoolFlag{
+				Name:  "pattern-coverage",
+				Usage: "wrap matches in an envelope with the number of matches of each query pattern (not with --filter)",
+			},
+			&cli.BoolFlag{
+				Name:  "echo-options",
+				Usage: "wrap matches in an envelope with the query and options they were produced with, after defaults",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "json",
+				Usage: "output format: json, rg (ripgrep-style path:line:col:text lines), quickfix (vim quickfix list JSON), golden (stable text for snapshot tests) or ndjson (one match per line, written as found)",
+			},
+			&cli.IntFlag{
+				Name:  "pattern",
+				Usage: "only keep matches from the query pattern with this (0-based) index (default: all patterns)",
+			},
+			&cli.IntFlag{
+				Name:  "at-line",
+				Usage: "only query the smallest node spanning line N, e.g. the function declared on it",
+			},
+			&cli.StringFlag{
+				Name:  "require-child",
+				Usage: "only keep matches where a captured node has a named child of this type",
+			},
+			&cli.StringFlag{
+				Name:  "forbid-child",
+				Usage: "drop matches where a captured node has a named child of this type",
+			},
+			&cli.IntFlag{
+				Name:  "context-bytes",
+				Usage: "include up to N bytes of surrounding source with each capture",
+			},
+			&cli.BoolFlag{
+				Name:  "sanitize-utf8",
+				Value: true,
+				Usage: "replace invalid UTF-8 in capture text with U+FFFD and mark the capture as sanitized",
+			},
+			&cli.BoolFlag{
+				Name:  "normalize-eol",
+				Value: true,
+				Usage: "convert CRLF line endings to LF before parsing",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "go",
+				Usage:   "language of the source files",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-minified",
+				Usage: "skip files that look minified or binary",
 			},
 		},
 		Action: runQuery,
 	}
 }
 
-func runQuery(_ context.Context, cmd *cli.Command) error {
+func runQuery(ctx context.Context, cmd *cli.Command) error {
 	queryText := cmd.String("query")
 	queryFile := cmd.String("query-file")
 
 	// Resolve query
-	querySource, err := resolveQuery(queryText, queryFile)
+	var querySource string
+	if cmd.String("find") != "" {
+		if queryText != "" || queryFile != "" {
+			return errors.New("use --query, --query-file or --find, not several")
+		}
+	} else {
+		var err error
+		if querySource, err = resolveQuery(queryText, queryFile); err != nil {
+			return err
+		}
+	}
+
+	format, err := parseFormatFlag(cmd)
 	if err != nil {
 		return err
 	}
+	if format != "json" && cmd.Bool("with-legend") {
+		return fmt.Errorf("--with-legend is not supported with --format %s", format)
+	}
+	if format != "json" && cmd.Bool("pattern-coverage") {
+		return fmt.Errorf("--pattern-coverage is not supported with --format %s", format)
+	}
+	if format != "json" && cmd.Bool("echo-options") {
+		return fmt.Errorf("--echo-options is not supported with --format %s", format)
+	}
 
 	opts := tsq.QueryOptions{
-		Query:    querySource,
-		Language: "go",
-		Path:     cmd.String("path"),
-		File:     cmd.String("file"),
-		Jobs:     cmd.Int("jobs"),
-		MaxBytes: cmd.Int64("max-bytes"),
+		Query:               querySource,
+		Find:                cmd.String("find"),
+		Name:                cmd.String("name"),
+		Receiver:            cmd.String("receiver"),
+		Language:            cmd.String("language"),
+		Path:                cmd.String("path"),
+		File:                cmd.String("file"),
+		Jobs:                cmd.Int("jobs"),
+		MaxBytes:            cmd.Int64("max-bytes"),
+		MaxPerFile:          cmd.Int("max-per-file"),
+		ContextBytes:        cmd.Int("context-bytes"),
+		AtLine:              cmd.Int("at-line"),
+		RequireChild:        cmd.String("require-child"),
+		ForbidChild:         cmd.String("forbid-child"),
+		PathRelativeTo:      cmd.String("path-relative-to"),
+		BaseDir:             cmd.String("base-dir"),
+		Schedule:            cmd.String("schedule"),
+		Sample:              cmd.Int("sample"),
+		Seed:                cmd.Int64("seed"),
+		MaxFiles:            cmd.Int("max-files"),
+		Hidden:              cmd.Bool("hidden"),
+		IgnoreDirs:          splitList(cmd.String("ignore-dirs")),
+		RespectGitignore:    cmd.Bool("gitignore"),
+		RespectIgnoreSingle: cmd.Bool("respect-ignore-single"),
+		StrictParse:         cmd.Bool("strict-parse"),
+		PreserveEOL:         !cmd.Bool("normalize-eol"),
+		SkipMinified:        cmd.Bool("skip-minified"),
+
+		PreserveInvalidUTF8: !cmd.Bool("sanitize-utf8"),
+	}
+
+	if cmd.IsSet("pattern") {
+		pattern := cmd.Int("pattern")
+		opts.PatternIndex = &pattern
+	}
+
+	var diags []tsq.Diagnostic
+	opts.Diagnostics = &diags
+
+	var envelope queryEnvelope
+	if cmd.Bool("with-legend") {
+		envelope.Legend = &[]tsq.LegendEntry{}
+		opts.Legend = envelope.Legend
+	}
+	if cmd.Bool("pattern-coverage") {
+		envelope.Coverage = &[]tsq.PatternCount{}
+		opts.PatternCoverage = envelope.Coverage
+	}
+	if cmd.Bool("echo-options") {
+		envelope.Options = &tsq.QueryOptions{}
+		opts.Resolved = envelope.Options
 	}
 
-	matches, err := tsq.Query(opts)
+	filter, err := parseFilterFlag(cmd)
 	if err != nil {
 		return err
 	}
+	// The legend and coverage count every match, including ones the
+	// filter drops.
+	if filter != nil && cmd.Bool("with-legend") {
+		return errors.New("--with-legend can't be combined with --filter")
+	}
+	if filter != nil && cmd.Bool("pattern-coverage") {
+		return errors.New("--pattern-coverage can't be combined with --filter")
+	}
+
+	if format == "ndjson" {
+		if err := streamQuery(ctx, opts, filter); err != nil {
+			return err
+		}
+		writeDiagnostics(diags)
+		return nil
+	}
+
+	matches, err := tsq.QueryContext(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if filter != nil {
+		if matches, err = tsq.FilterSlice(matches, filter); err != nil {
+			return err
+		}
+	}
 
+	writeDiagnostics(diags)
+	switch format {
+	case "rg":
+		return tsq.WriteRgMatches(stdout, matches)
+	case "quickfix":
+		return writeJSON(tsq.QuickfixMatches(matches), cmd.Bool("compact"))
+	case "golden":
+		_, err := io.WriteString(stdout, tsq.GoldenMatches(matches)+"\n")
+		return err
+	}
+	if envelope.Legend != nil || envelope.Coverage != nil || envelope.Options != nil {
+		envelope.Matches = matches
+		return writeJSON(envelope, cmd.Bool("compact"))
+	}
 	return writeJSON(matches, cmd.Bool("compact"))
 }
 
+// streamQuery writes each match kept by filter, if any, as a line of JSON
+// as soon as it is found.
+func streamQuery(ctx context.Context, opts tsq.QueryOptions, filter *tsq.Filter) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	matches, errc := tsq.QueryStream(ctx, opts)
+	for m := range matches {
+		if filter != nil {
+			ok, err := filter.Match(m)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		if err := encodeJSON(stdout, m, true); err != nil {
+			return err
+		}
+	}
+	return <-errc
+}
+
+// queryEnvelope is the query output with --with-legend, --pattern-coverage
+// or --echo-options. Unrequested sections are left out.
+type queryEnvelope struct {
+	Options  *tsq.QueryOptions   `json:"options,omitempty"`
+	Legend   *[]tsq.LegendEntry  `json:"legend,omitempty"`
+	Coverage *[]tsq.PatternCount `json:"coverage,omitempty"`
+	Matches  []tsq.QueryMatch    `json:"matches"`
+}
+
 func resolveQuery(text, filePath string) (string, error) {
 	if text != "" && filePath != "" {
 		return "", errors.New("use --query or --query-file, not both")
@@ -119,7 +452,7 @@ func resolveQuery(text, filePath string) (string, error) {
 		return text, nil
 	}
 	if filePath == "" {
-		return "", errors.New("--query or --query-file is required")
+		return "", errors.New("--query, --query-file or --find is required")
 	}
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -143,20 +476,194 @@ func symbolsCommand() *cli.Command {
 				Aliases: []string{"f"},
 				Usage:   "single file to analyze",
 			},
+			&cli.StringFlag{
+				Name:  "changed-since",
+				Usage: "only analyze files changed relative to this git ref (e.g. main)",
+			},
+			&cli.StringFlag{
+				Name:  "diff",
+				Usage: "only report symbols touching lines changed in this unified diff (- for stdin); paths are relative to the git root",
+			},
 			&cli.StringFlag{
 				Name:  "visibility",
 				Value: "all",
 				Usage: "filter: all, public, private",
 			},
+			&cli.BoolFlag{
+				Name:  "documented",
+				Usage: "only symbols with a doc comment",
+			},
+			&cli.BoolFlag{
+				Name:  "undocumented",
+				Usage: "only symbols without a doc comment",
+			},
+			&cli.BoolFlag{
+				Name:  "fail-on-undocumented",
+				Usage: "with --undocumented, exit nonzero if any public symbol is reported (for CI)",
+			},
+			&cli.BoolFlag{
+				Name:    "with-doc",
+				Aliases: []string{"include-doc"},
+				Usage:   "include each symbol's doc comment",
+			},
 			&cli.BoolFlag{
 				Name:  "include-source",
 				Usage: "include source code snippets",
 			},
+			&cli.BoolFlag{
+				Name:  "source-with-location",
+				Usage: "start each source snippet with a \"// file:line\" comment header (with --include-source; not stored in --snippet-cache)",
+			},
 			&cli.IntFlag{
 				Name:  "max-source-lines",
 				Value: 10,
 				Usage: "max lines for source snippets",
 			},
+			&cli.BoolFlag{
+				Name:  "snippet-refs",
+				Usage: "store source snippets in the snippet cache and emit snippet_ref handles instead (implies --include-source); resolve with get-snippet",
+			},
+			&cli.StringFlag{
+				Name:  "snippet-cache",
+				Usage: "snippet cache directory (default: tsq/snippets in the user cache directory)",
+			},
+			&cli.BoolFlag{
+				Name:  "dedent",
+				Usage: "remove common leading indentation from source snippets",
+			},
+			&cli.BoolFlag{
+				Name:  "with-arity",
+				Usage: "report parameter and result counts for functions and methods",
+			},
+			&cli.BoolFlag{
+				Name:  "returns-error",
+				Usage: "only functions and methods whose last result is an error (Go only)",
+			},
+			&cli.BoolFlag{
+				Name:  "with-directives",
+				Usage: "include the //go: directives (//go:noinline, //go:generate, ...) above each declaration",
+			},
+			&cli.BoolFlag{
+				Name:  "with-mtime",
+				Usage: "include each file's last modification time (RFC3339)",
+			},
+			&cli.BoolFlag{
+				Name:  "include-anonymous",
+				Usage: "report function literals as closure symbols",
+			},
+			&cli.BoolFlag{
+				Name:  "promote-embedded",
+				Usage: "add methods promoted from embedded types to each struct (name-based)",
+			},
+			&cli.StringFlag{
+				Name:  "path-pattern",
+				Usage: "only scan files whose path under --path matches this glob (e.g. '**/handlers/*.go')",
+			},
+			&cli.BoolFlag{
+				Name:  "index-map",
+				Usage: "output one map from qualified name to definitions instead of per-file results",
+			},
+			&cli.BoolFlag{
+				Name:  "outermost-only",
+				Usage: "drop symbols declared inside another symbol, such as types and closures in function bodies",
+			},
+			&cli.BoolFlag{
+				Name:  "injections",
+				Usage: "also report symbols of code embedded in string literals tagged with a language comment, e.g. /* sql */",
+			},
+			&cli.BoolFlag{
+				Name:  "struct-layout",
+				Usage: "estimate struct field offsets and padding, and suggest a smaller field order (Go only)",
+			},
+			&cli.BoolFlag{
+				Name:  "method-qualified-names",
+				Usage: "name methods Receiver.Method (e.g. Server.Handle); functions keep bare names",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "json",
+				Usage: "output format: json or tree-json (results nested by directory)",
+			},
+			&cli.BoolFlag{
+				Name:  "edges",
+				Usage: "output {parent_id, child_id} containment edges (type -> method, function -> closure) instead of symbols",
+			},
+			&cli.BoolFlag{
+				Name:  "resolve-iota",
+				Usage: "set value on constants in iota blocks to their effective integer value",
+			},
+			&cli.BoolFlag{
+				Name:  "detect-enums",
+				Usage: "report const blocks of a single named type as an enum symbol with members and values",
+			},
+			&cli.IntFlag{
+				Name:  "top",
+				Usage: "only output the N best symbols ranked by --by, best first (0 = all)",
+			},
+			&cli.StringFlag{
+				Name:  "by",
+				Value: "size",
+				Usage: "ranking for --top: size (bytes), lines or refs (reference count)",
+			},
+			&cli.BoolFlag{
+				Name:  "with-body",
+				Usage: "include each function's body block as a separate field",
+			},
+			&cli.BoolFlag{
+				Name:  "group-decl-blocks",
+				Usage: "report each const (...) / var (...) block as one symbol with members",
+			},
+			&cli.StringFlag{
+				Name:  "path-relative-to",
+				Value: "root",
+				Usage: "make file paths relative to the scan root (root) or the git worktree root (git)",
+			},
+			&cli.StringFlag{
+				Name:  "base-dir",
+				Usage: "make file paths relative to this directory, wherever the scan starts",
+			},
+			&cli.StringFlag{
+				Name:  "schedule",
+				Value: "fifo",
+				Usage: "order files are handed to workers: fifo (scan order) or size (largest first)",
+			},
+			&cli.IntFlag{
+				Name:  "sample",
+				Usage: "only analyze a random sample of N files (0 = all), reproducible with --seed",
+			},
+			&cli.Int64Flag{
+				Name:  "seed",
+				Usage: "seed for --sample",
+			},
+			&cli.IntFlag{
+				Name:  "max-files",
+				Usage: "stop scanning after this many files (0 = no limit)",
+			},
+			&cli.BoolFlag{
+				Name:  "hidden",
+				Usage: "scan dot-prefixed files and directories (skipped by default)",
+			},
+			&cli.StringFlag{
+				Name:  "ignore-dirs",
+				Usage: "comma-separated directory names to skip besides the default ones (vendor, node_modules, ...)",
+			},
+			&cli.BoolFlag{
+				Name:  "gitignore",
+				Value: true,
+				Usage: "skip files excluded by .gitignore files under --path or above it in its git worktree (--gitignore=false to scan them)",
+			},
+			&cli.BoolFlag{
+				Name:  "respect-ignore-single",
+				Usage: "apply the directory-scan ignore rules (vendor, hidden files, ...) to --file too",
+			},
+			&cli.BoolFlag{
+				Name:  "strict-parse",
+				Usage: "skip files with syntax errors instead of using their partial trees (reported on stderr)",
+			},
+			&cli.StringFlag{
+				Name:  "filter",
+				Usage: "keep only results matching an expression, e.g. 'kind == \"function\" && visibility == \"public\"'",
+			},
 			&cli.BoolFlag{
 				Name:  "compact",
 				Usage: "minimize output",
@@ -169,44 +676,184 @@ func symbolsCommand() *cli.Command {
 			},
 			&cli.Int64Flag{
 				Name:  "max-bytes",
-				Value: 2 * 1024 * 1024,
-				Usage: "skip files larger than this",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+			&cli.BoolFlag{
+				Name:  "normalize-eol",
+				Value: true,
+				Usage: "convert CRLF line endings to LF before parsing",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "auto",
+				Usage:   "language of the source files, or auto to detect each file's language by extension",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-minified",
+				Usage: "skip files that look minified or binary",
 			},
 		},
 		Action: runSymbols,
 	}
 }
 
-func runSymbols(_ context.Context, cmd *cli.Command) error {
+func runSymbols(ctx context.Context, cmd *cli.Command) error {
 	opts := tsq.SymbolsOptions{
-		Language:       "go",
-		Path:           cmd.String("path"),
-		File:           cmd.String("file"),
-		Visibility:     cmd.String("visibility"),
-		IncludeSource:  cmd.Bool("include-source"),
-		MaxSourceLines: cmd.Int("max-source-lines"),
-		Jobs:           cmd.Int("jobs"),
-		MaxBytes:       cmd.Int64("max-bytes"),
+		Language:            cmd.String("language"),
+		Path:                cmd.String("path"),
+		File:                cmd.String("file"),
+		ChangedSince:        cmd.String("changed-since"),
+		Visibility:          cmd.String("visibility"),
+		WithDoc:             cmd.Bool("with-doc"),
+		IncludeSource:       cmd.Bool("include-source") || cmd.Bool("snippet-refs"),
+		MaxSourceLines:      cmd.Int("max-source-lines"),
+		SourceWithLocation:  cmd.Bool("source-with-location"),
+		Dedent:              cmd.Bool("dedent"),
+		WithArity:           cmd.Bool("with-arity"),
+		ReturnsError:        cmd.Bool("returns-error"),
+		WithDirectives:      cmd.Bool("with-directives"),
+		WithModTime:         cmd.Bool("with-mtime"),
+		IncludeAnonymous:    cmd.Bool("include-anonymous"),
+		PromoteEmbedded:     cmd.Bool("promote-embedded"),
+		GroupDeclBlocks:     cmd.Bool("group-decl-blocks"),
+		WithBody:            cmd.Bool("with-body"),
+		QualifyNames:        cmd.Bool("index-map"),
+		ResolveIota:         cmd.Bool("resolve-iota"),
+		DetectEnums:         cmd.Bool("detect-enums"),
+		Top:                 cmd.Int("top"),
+		By:                  cmd.String("by"),
+		PathPattern:         cmd.String("path-pattern"),
+		PathRelativeTo:      cmd.String("path-relative-to"),
+		BaseDir:             cmd.String("base-dir"),
+		Schedule:            cmd.String("schedule"),
+		Sample:              cmd.Int("sample"),
+		Seed:                cmd.Int64("seed"),
+		MaxFiles:            cmd.Int("max-files"),
+		Hidden:              cmd.Bool("hidden"),
+		IgnoreDirs:          splitList(cmd.String("ignore-dirs")),
+		RespectGitignore:    cmd.Bool("gitignore"),
+		RespectIgnoreSingle: cmd.Bool("respect-ignore-single"),
+		StrictParse:         cmd.Bool("strict-parse"),
+		Jobs:                cmd.Int("jobs"),
+		MaxBytes:            cmd.Int64("max-bytes"),
+		PreserveEOL:         !cmd.Bool("normalize-eol"),
+		SkipMinified:        cmd.Bool("skip-minified"),
+
+		MethodQualifiedNames: cmd.Bool("method-qualified-names"),
+		StructLayout:         cmd.Bool("struct-layout"),
+		OutermostOnly:        cmd.Bool("outermost-only"),
+		Injections:           cmd.Bool("injections"),
+	}
+
+	switch {
+	case cmd.Bool("documented") && cmd.Bool("undocumented"):
+		return errors.New("use --documented or --undocumented, not both")
+	case cmd.Bool("documented"):
+		opts.Documented = "documented"
+	case cmd.Bool("undocumented"):
+		opts.Documented = "undocumented"
+	}
+	if cmd.Bool("fail-on-undocumented") && !cmd.Bool("undocumented") {
+		return errors.New("--fail-on-undocumented requires --undocumented")
 	}
 
-	results, err := tsq.Symbols(opts)
+	format, err := parseFormatFlag(cmd)
 	if err != nil {
 		return err
 	}
+	if format != "json" && cmd.Bool("index-map") {
+		return fmt.Errorf("--index-map is not supported with --format %s", format)
+	}
+	if cmd.Bool("edges") && (format != "json" || cmd.Bool("index-map") || cmd.Bool("method-qualified-names")) {
+		return errors.New("--edges can't be combined with --index-map, --method-qualified-names or --format")
+	}
 
-	return writeJSON(results, cmd.Bool("compact"))
+	filter, err := parseFilterFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	if opts.ChangedLines, err = parseDiffFlag(cmd); err != nil {
+		return err
+	}
+
+	if cmd.Bool("snippet-refs") {
+		if opts.SnippetCache, err = snippetCacheDir(cmd); err != nil {
+			return err
+		}
+	}
+
+	var diags []tsq.Diagnostic
+	opts.Diagnostics = &diags
+
+	results, err := tsq.SymbolsContext(ctx, opts)
+	if err != nil {
+		return err
+	}
+	writeDiagnostics(diags)
+	if filter != nil {
+		if results, err = tsq.FilterSymbols(results, filter); err != nil {
+			return err
+		}
+	}
+
+	var out any = results
+	switch {
+	case cmd.Bool("index-map"):
+		out = tsq.SymbolIndex(results)
+	case format == "tree-json":
+		out = tsq.SymbolTree(results)
+	case cmd.Bool("edges"):
+		out = tsq.ContainmentEdges(results)
+	}
+	if err := writeJSON(out, cmd.Bool("compact")); err != nil {
+		return err
+	}
+
+	if cmd.Bool("fail-on-undocumented") {
+		if n := countPublic(results); n > 0 {
+			return fmt.Errorf("%d undocumented public symbols", n)
+		}
+	}
+	return nil
+}
+
+// countPublic counts the public symbols in results.
+func countPublic(results []tsq.SymbolsResult) int {
+	n := 0
+	for _, r := range results {
+		for _, sym := range r.Symbols {
+			if sym.Visibility == "public" {
+				n++
+			}
+		}
+	}
+	return n
 }
 
 func outlineCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "outline",
 		Usage: "get file structure overview",
+		Description: "Outline one file with --file, or every file under a directory with --path.\n" +
+			"Directory outlines are streamed as ndjson, one FileOutline per line, as\n" +
+			"each file is done, so memory stays bounded on large trees.",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:     "file",
-				Aliases:  []string{"f"},
-				Usage:    "file to analyze (required)",
-				Required: true,
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "file to analyze",
+			},
+			&cli.StringFlag{
+				Name:  "path",
+				Usage: "outline every file under this directory, as ndjson",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers (with --path)",
 			},
 			&cli.BoolFlag{
 				Name:  "compact",
@@ -221,27 +868,121 @@ func outlineCommand() *cli.Command {
 				Value: 5,
 				Usage: "max lines for source snippets",
 			},
-		},
-		Action: runOutline,
-	}
-}
-
-func runOutline(_ context.Context, cmd *cli.Command) error {
+			&cli.BoolFlag{
+				Name:  "dedent",
+				Usage: "remove common leading indentation from source snippets",
+			},
+			&cli.BoolFlag{
+				Name:  "classify-imports",
+				Usage: "group imports as std, third_party or local (using go.mod)",
+			},
+			&cli.BoolFlag{
+				Name:  "count-import-usage",
+				Usage: "report how many times each import's package name is referenced (0 = unused)",
+			},
+			&cli.BoolFlag{
+				Name:  "with-mtime",
+				Usage: "include the file's last modification time (RFC3339)",
+			},
+			&cli.BoolFlag{
+				Name:    "with-doc",
+				Aliases: []string{"include-doc"},
+				Usage:   "include each symbol's doc comment",
+			},
+			&cli.BoolFlag{
+				Name:  "index-positions",
+				Usage: "output declarations sorted by start position with inclusive ends, for looking up the symbol at a line",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "json",
+				Usage: "output format: json or tree-json (the outline nested under its directories)",
+			},
+			&cli.BoolFlag{
+				Name:  "normalize-eol",
+				Value: true,
+				Usage: "convert CRLF line endings to LF before parsing",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "auto",
+				Usage:   "language of the source files, or auto to detect each file's language by extension",
+			},
+		},
+		Action: runOutline,
+	}
+}
+
+func runOutline(ctx context.Context, cmd *cli.Command) error {
 	opts := tsq.OutlineOptions{
-		Language:       "go",
-		File:           cmd.String("file"),
-		IncludeSource:  cmd.Bool("include-source"),
-		MaxSourceLines: cmd.Int("max-source-lines"),
+		Language:        cmd.String("language"),
+		File:            cmd.String("file"),
+		IncludeSource:   cmd.Bool("include-source"),
+		MaxSourceLines:  cmd.Int("max-source-lines"),
+		Dedent:          cmd.Bool("dedent"),
+		ClassifyImports: cmd.Bool("classify-imports"),
+		PreserveEOL:     !cmd.Bool("normalize-eol"),
+
+		CountImportUsage: cmd.Bool("count-import-usage"),
+		WithModTime:      cmd.Bool("with-mtime"),
+		WithDoc:          cmd.Bool("with-doc"),
+	}
+
+	switch {
+	case opts.File != "" && cmd.String("path") != "":
+		return errors.New("--file and --path are mutually exclusive")
+	case opts.File == "" && cmd.String("path") == "":
+		return errors.New("--file or --path is required")
+	case cmd.String("path") != "":
+		opts.Path = cmd.String("path")
+		opts.Jobs = cmd.Int("jobs")
+		return streamOutlines(ctx, cmd, opts)
+	}
+
+	format, err := parseFormatFlag(cmd)
+	if err != nil {
+		return err
+	}
+	if format != "json" && cmd.Bool("index-positions") {
+		return fmt.Errorf("--index-positions is not supported with --format %s", format)
 	}
 
-	outline, err := tsq.Outline(opts)
+	outline, err := tsq.OutlineContext(ctx, opts)
 	if err != nil {
 		return err
 	}
 
+	if cmd.Bool("index-positions") {
+		return writeJSON(tsq.PositionIndex(outline), cmd.Bool("compact"))
+	}
+	if format == "tree-json" {
+		return writeJSON(tsq.OutlineTree(outline), cmd.Bool("compact"))
+	}
 	return writeJSON(outline, cmd.Bool("compact"))
 }
 
+// streamOutlines writes the outline of each file under opts.Path as a line
+// of ndjson as soon as it is built.
+func streamOutlines(ctx context.Context, cmd *cli.Command, opts tsq.OutlineOptions) error {
+	if cmd.IsSet("format") || cmd.Bool("index-positions") {
+		return errors.New("--format and --index-positions are not supported with --path, which writes ndjson")
+	}
+	if err := checkUnboundedOutput("outline --path"); err != nil {
+		return err
+	}
+	var diags []tsq.Diagnostic
+	opts.Diagnostics = &diags
+	err := tsq.StreamOutlines(ctx, opts, func(outline tsq.FileOutline) error {
+		return encodeJSON(stdout, outline, true)
+	})
+	if err != nil {
+		return err
+	}
+	writeDiagnostics(diags)
+	return nil
+}
+
 func refsCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "refs",
@@ -263,15 +1004,99 @@ func refsCommand() *cli.Command {
 				Aliases: []string{"f"},
 				Usage:   "single file to search",
 			},
+			&cli.StringFlag{
+				Name:  "path-relative-to",
+				Value: "root",
+				Usage: "make file paths relative to the scan root (root) or the git worktree root (git)",
+			},
+			&cli.StringFlag{
+				Name:  "base-dir",
+				Usage: "make file paths relative to this directory, wherever the scan starts",
+			},
+			&cli.StringFlag{
+				Name:  "schedule",
+				Value: "fifo",
+				Usage: "order files are handed to workers: fifo (scan order) or size (largest first)",
+			},
+			&cli.IntFlag{
+				Name:  "sample",
+				Usage: "only analyze a random sample of N files (0 = all), reproducible with --seed",
+			},
+			&cli.Int64Flag{
+				Name:  "seed",
+				Usage: "seed for --sample",
+			},
+			&cli.IntFlag{
+				Name:  "max-files",
+				Usage: "stop scanning after this many files (0 = no limit)",
+			},
+			&cli.BoolFlag{
+				Name:  "hidden",
+				Usage: "scan dot-prefixed files and directories (skipped by default)",
+			},
+			&cli.StringFlag{
+				Name:  "ignore-dirs",
+				Usage: "comma-separated directory names to skip besides the default ones (vendor, node_modules, ...)",
+			},
+			&cli.BoolFlag{
+				Name:  "gitignore",
+				Value: true,
+				Usage: "skip files excluded by .gitignore files under --path or above it in its git worktree (--gitignore=false to scan them)",
+			},
+			&cli.BoolFlag{
+				Name:  "respect-ignore-single",
+				Usage: "apply the directory-scan ignore rules (vendor, hidden files, ...) to --file too",
+			},
+			&cli.BoolFlag{
+				Name:  "strict-parse",
+				Usage: "skip files with syntax errors instead of using their partial trees (reported on stderr)",
+			},
+			&cli.StringFlag{
+				Name:  "filter",
+				Usage: "keep only results matching an expression, e.g. 'kind == \"function\" && visibility == \"public\"'",
+			},
 			&cli.BoolFlag{
 				Name:  "compact",
 				Usage: "minimize output",
 			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "json",
+				Usage: "output format: json, rg (ripgrep-style path:line:col:text lines) or quickfix (vim quickfix list JSON)",
+			},
 			&cli.BoolFlag{
 				Name:  "include-context",
 				Value: true,
 				Usage: "include surrounding code context",
 			},
+			&cli.BoolFlag{
+				Name:  "offsets",
+				Usage: "include each reference's byte span in the file on disk ({start, end}, end exclusive)",
+			},
+			&cli.BoolFlag{
+				Name:  "with-enclosing",
+				Usage: "name the function or method (Receiver.Name) each reference is in",
+			},
+			&cli.BoolFlag{
+				Name:  "with-id",
+				Usage: "give each reference an id from its file, enclosing symbol and kind, stable under reformatting",
+			},
+			&cli.BoolFlag{
+				Name:  "by-package",
+				Usage: "report the number of references per package instead of each reference (Go only)",
+			},
+			&cli.StringFlag{
+				Name:  "ref-node-types",
+				Usage: "comma-separated node types that count as references; parent/type also matches the parent (e.g. call_expression/identifier)",
+			},
+			&cli.StringFlag{
+				Name:  "path-pattern",
+				Usage: "only scan files whose path under --path matches this glob (e.g. '**/handlers/*.go')",
+			},
+			&cli.StringFlag{
+				Name:  "diff",
+				Usage: "only report references touching lines changed in this unified diff (- for stdin); paths are relative to the git root",
+			},
 			&cli.IntFlag{
 				Name:    "jobs",
 				Aliases: []string{"j"},
@@ -280,41 +1105,1384 @@ func refsCommand() *cli.Command {
 			},
 			&cli.Int64Flag{
 				Name:  "max-bytes",
-				Value: 2 * 1024 * 1024,
-				Usage: "skip files larger than this",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+			&cli.BoolFlag{
+				Name:  "normalize-eol",
+				Value: true,
+				Usage: "convert CRLF line endings to LF before parsing",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "auto",
+				Usage:   "language of the source files, or auto to detect each file's language by extension",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-minified",
+				Usage: "skip files that look minified or binary",
 			},
 		},
 		Action: runRefs,
 	}
 }
 
-func runRefs(_ context.Context, cmd *cli.Command) error {
+func runRefs(ctx context.Context, cmd *cli.Command) error {
 	opts := tsq.RefsOptions{
-		Symbol:         cmd.String("symbol"),
-		Language:       "go",
-		Path:           cmd.String("path"),
-		File:           cmd.String("file"),
-		IncludeContext: cmd.Bool("include-context"),
-		Jobs:           cmd.Int("jobs"),
-		MaxBytes:       cmd.Int64("max-bytes"),
+		Symbol:              cmd.String("symbol"),
+		Language:            cmd.String("language"),
+		Path:                cmd.String("path"),
+		File:                cmd.String("file"),
+		IncludeContext:      cmd.Bool("include-context"),
+		IncludeOffsets:      cmd.Bool("offsets"),
+		WithEnclosing:       cmd.Bool("with-enclosing"),
+		WithID:              cmd.Bool("with-id"),
+		ByPackage:           cmd.Bool("by-package"),
+		RefNodeTypes:        splitList(cmd.String("ref-node-types")),
+		PathPattern:         cmd.String("path-pattern"),
+		PathRelativeTo:      cmd.String("path-relative-to"),
+		BaseDir:             cmd.String("base-dir"),
+		Schedule:            cmd.String("schedule"),
+		Sample:              cmd.Int("sample"),
+		Seed:                cmd.Int64("seed"),
+		MaxFiles:            cmd.Int("max-files"),
+		Hidden:              cmd.Bool("hidden"),
+		IgnoreDirs:          splitList(cmd.String("ignore-dirs")),
+		RespectGitignore:    cmd.Bool("gitignore"),
+		RespectIgnoreSingle: cmd.Bool("respect-ignore-single"),
+		StrictParse:         cmd.Bool("strict-parse"),
+		Jobs:                cmd.Int("jobs"),
+		MaxBytes:            cmd.Int64("max-bytes"),
+		PreserveEOL:         !cmd.Bool("normalize-eol"),
+		SkipMinified:        cmd.Bool("skip-minified"),
 	}
 
-	result, err := tsq.Refs(opts)
+	format, err := parseFormatFlag(cmd)
 	if err != nil {
 		return err
 	}
+	if format != "json" && opts.ByPackage {
+		return fmt.Errorf("--by-package is not supported with --format %s", format)
+	}
+
+	filter, err := parseFilterFlag(cmd)
+	if err != nil {
+		return err
+	}
+	if filter != nil && opts.ByPackage {
+		return errors.New("--by-package can't be combined with --filter")
+	}
+
+	if opts.ChangedLines, err = parseDiffFlag(cmd); err != nil {
+		return err
+	}
+
+	var diags []tsq.Diagnostic
+	opts.Diagnostics = &diags
+
+	result, err := tsq.RefsContext(ctx, opts)
+	if err != nil {
+		return err
+	}
+	writeDiagnostics(diags)
+	if filter != nil {
+		if result.References, err = tsq.FilterSlice(result.References, filter); err != nil {
+			return err
+		}
+	}
 
+	switch format {
+	case "rg":
+		return tsq.WriteRgRefs(stdout, result.References)
+	case "quickfix":
+		return writeJSON(tsq.QuickfixRefs(result.References), cmd.Bool("compact"))
+	}
+	if opts.ByPackage {
+		return writeJSON(refsByPackage{Symbol: result.Symbol, Packages: result.Packages}, cmd.Bool("compact"))
+	}
 	return writeJSON(result, cmd.Bool("compact"))
 }
 
-// JSON output helpers
-func writeJSON(v any, compact bool) error {
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetEscapeHTML(false)
-	if !compact {
-		enc.SetIndent("", "  ")
+// refsByPackage is the refs output with --by-package.
+type refsByPackage struct {
+	Symbol   string            `json:"symbol"`
+	Packages []tsq.PackageRefs `json:"packages"`
+}
+
+func apiCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "api",
+		Usage: "list the exported API surface grouped by package",
+		Description: "List exported symbols with signatures and qualified names, grouped by package.\n" +
+			"Methods on unexported types and test files are excluded.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "root path to scan",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers",
+			},
+			&cli.Int64Flag{
+				Name:  "max-bytes",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "go",
+				Usage:   "language of the source files",
+			},
+		},
+		Action: runAPI,
 	}
-	return enc.Encode(v)
+}
+
+func runAPI(_ context.Context, cmd *cli.Command) error {
+	opts := tsq.PublicAPIOptions{
+		Language: cmd.String("language"),
+		Path:     cmd.String("path"),
+		Jobs:     cmd.Int("jobs"),
+		MaxBytes: cmd.Int64("max-bytes"),
+	}
+
+	results, err := tsq.PublicAPI(opts)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(results, cmd.Bool("compact"))
+}
+
+func apiDiffCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "apidiff",
+		Usage: "classify public API changes between git revisions by semver impact",
+		Description: "Compare the exported API surface at --old-rev with --new-rev (or the working tree).\n" +
+			"Removed or changed exported symbols are \"major\", added ones \"minor\";\n" +
+			"if there are neither, the suggested bump is \"patch\".\n\n" +
+			"Examples:\n" +
+			"  tsq apidiff --old-rev v1.2.0                  # working tree against v1.2.0\n" +
+			"  tsq apidiff --old-rev v1.2.0 --new-rev HEAD --bump",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "old-rev",
+				Usage:    "git revision of the old API (required)",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "new-rev",
+				Usage: "git revision of the new API (default: working tree)",
+			},
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "directory to compare, inside a git repository",
+			},
+			&cli.BoolFlag{
+				Name:  "bump",
+				Usage: "print only the suggested bump (major, minor or patch)",
+			},
+			&cli.BoolFlag{
+				Name:  "ignore-formatting",
+				Usage: "normalize whitespace in signatures before comparing, so reformatted declarations are unchanged",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers",
+			},
+			&cli.Int64Flag{
+				Name:  "max-bytes",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "go",
+				Usage:   "language of the source files",
+			},
+		},
+		Action: runAPIDiff,
+	}
+}
+
+func runAPIDiff(_ context.Context, cmd *cli.Command) error {
+	opts := tsq.APIDiffOptions{
+		Language: cmd.String("language"),
+		Path:     cmd.String("path"),
+		OldRev:   cmd.String("old-rev"),
+		NewRev:   cmd.String("new-rev"),
+		Jobs:     cmd.Int("jobs"),
+		MaxBytes: cmd.Int64("max-bytes"),
+
+		IgnoreFormatting: cmd.Bool("ignore-formatting"),
+	}
+
+	result, err := tsq.APIDiff(opts)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Bool("bump") {
+		if err := checkUnboundedOutput("--bump"); err != nil {
+			return err
+		}
+		_, err := io.WriteString(stdout, result.Bump+"\n")
+		return err
+	}
+	return writeJSON(result, cmd.Bool("compact"))
+}
+
+func importsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "imports",
+		Usage: "output the package import graph",
+		Description: "List package -> imported package edges across the scanned tree.\n" +
+			"Output is a JSON edge list, or a graphviz digraph with --dot.\n\n" +
+			"Examples:\n" +
+			"  tsq imports --path .                          # all edges\n" +
+			"  tsq imports --path . --local-only --dot | dot -Tsvg > deps.svg",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "root path to scan",
+			},
+			&cli.BoolFlag{
+				Name:  "local-only",
+				Usage: "only include imports of packages in the same module",
+			},
+			&cli.BoolFlag{
+				Name:  "dot",
+				Usage: "output a graphviz digraph instead of JSON",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "go",
+				Usage:   "language of the source files",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers",
+			},
+			&cli.Int64Flag{
+				Name:  "max-bytes",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+		},
+		Action: runImports,
+	}
+}
+
+func runImports(_ context.Context, cmd *cli.Command) error {
+	opts := tsq.ImportsOptions{
+		Language:  cmd.String("language"),
+		Path:      cmd.String("path"),
+		LocalOnly: cmd.Bool("local-only"),
+		Jobs:      cmd.Int("jobs"),
+		MaxBytes:  cmd.Int64("max-bytes"),
+	}
+
+	edges, err := tsq.Imports(opts)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Bool("dot") {
+		if err := checkUnboundedOutput("--dot"); err != nil {
+			return err
+		}
+		return writeDot(edges)
+	}
+	return writeJSON(edges, cmd.Bool("compact"))
+}
+
+func commentsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "comments",
+		Usage: "list comments, or exported symbols without doc comments",
+		Description: "List comments with their kind (line, block, doc), position and the\n" +
+			"declaration they document or sit in.\n\n" +
+			"With --undocumented, list exported symbols that have no doc comment instead.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "root path to scan",
+			},
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "single file to analyze",
+			},
+			&cli.BoolFlag{
+				Name:  "undocumented",
+				Usage: "list exported symbols without a doc comment",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "go",
+				Usage:   "language of the source files",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers",
+			},
+			&cli.Int64Flag{
+				Name:  "max-bytes",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+		},
+		Action: runComments,
+	}
+}
+
+func runComments(_ context.Context, cmd *cli.Command) error {
+	opts := tsq.CommentsOptions{
+		Language: cmd.String("language"),
+		Path:     cmd.String("path"),
+		File:     cmd.String("file"),
+		Jobs:     cmd.Int("jobs"),
+		MaxBytes: cmd.Int64("max-bytes"),
+	}
+
+	if cmd.Bool("undocumented") {
+		results, err := tsq.Undocumented(opts)
+		if err != nil {
+			return err
+		}
+		return writeJSON(results, cmd.Bool("compact"))
+	}
+
+	results, err := tsq.Comments(opts)
+	if err != nil {
+		return err
+	}
+	return writeJSON(results, cmd.Bool("compact"))
+}
+
+func hotspotsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "hotspots",
+		Usage: "rank symbols by reference count",
+		Description: "Rank top-level symbols by how often they are referenced across the tree,\n" +
+			"most referenced first. References are matched by name, so symbols that\n" +
+			"share a name share a count.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "root path to scan",
+			},
+			&cli.IntFlag{
+				Name:  "top",
+				Usage: "only show the N most referenced symbols (0 = all)",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "go",
+				Usage:   "language of the source files",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers",
+			},
+			&cli.Int64Flag{
+				Name:  "max-bytes",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+		},
+		Action: runHotspots,
+	}
+}
+
+func runHotspots(_ context.Context, cmd *cli.Command) error {
+	opts := tsq.HotspotsOptions{
+		Language: cmd.String("language"),
+		Path:     cmd.String("path"),
+		Top:      cmd.Int("top"),
+		Jobs:     cmd.Int("jobs"),
+		MaxBytes: cmd.Int64("max-bytes"),
+	}
+
+	spots, err := tsq.Hotspots(opts)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(spots, cmd.Bool("compact"))
+}
+
+func todosCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "todos",
+		Usage: "list TODO/FIXME/XXX/HACK annotations in comments",
+		Description: "List TODO-style annotations with their owner, parsed from TODO(owner),\n" +
+			"and the declaration they sit in.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "root path to scan",
+			},
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "single file to analyze",
+			},
+			&cli.StringFlag{
+				Name:  "owner",
+				Usage: "only show annotations attributed to this owner",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "go",
+				Usage:   "language of the source files",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers",
+			},
+			&cli.Int64Flag{
+				Name:  "max-bytes",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+		},
+		Action: runTodos,
+	}
+}
+
+func runTodos(_ context.Context, cmd *cli.Command) error {
+	opts := tsq.TodosOptions{
+		Language: cmd.String("language"),
+		Path:     cmd.String("path"),
+		File:     cmd.String("file"),
+		Owner:    cmd.String("owner"),
+		Jobs:     cmd.Int("jobs"),
+		MaxBytes: cmd.Int64("max-bytes"),
+	}
+
+	todos, err := tsq.Todos(opts)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(todos, cmd.Bool("compact"))
+}
+
+func infoCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "info",
+		Usage: "show definitions, docs and references of a symbol",
+		Description: "Combine symbols, comments and refs into one view of a symbol:\n" +
+			"{symbol, definitions: [...], references: [...]}.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "symbol",
+				Aliases:  []string{"s"},
+				Usage:    "symbol name to describe",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "root path to scan",
+			},
+			&cli.BoolFlag{
+				Name:  "include-context",
+				Value: true,
+				Usage: "include surrounding code context in references",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "go",
+				Usage:   "language of the source files",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers",
+			},
+			&cli.Int64Flag{
+				Name:  "max-bytes",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+		},
+		Action: runInfo,
+	}
+}
+
+func runInfo(_ context.Context, cmd *cli.Command) error {
+	opts := tsq.InfoOptions{
+		Symbol:         cmd.String("symbol"),
+		Language:       cmd.String("language"),
+		Path:           cmd.String("path"),
+		IncludeContext: cmd.Bool("include-context"),
+		Jobs:           cmd.Int("jobs"),
+		MaxBytes:       cmd.Int64("max-bytes"),
+	}
+
+	info, err := tsq.Info(opts)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(info, cmd.Bool("compact"))
+}
+
+func churnCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "churn",
+		Usage: "count the git commits that changed a symbol",
+		Description: "Follow the lines of each declaration of a symbol through git history\n" +
+			"(git log -L): [{symbol, file, range, commit_count, last_changed}, ...].",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "symbol",
+				Aliases:  []string{"s"},
+				Usage:    "symbol name, or Receiver.Name for a method",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "root path to scan (inside a git repository)",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "go",
+				Usage:   "language of the source files",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers",
+			},
+			&cli.Int64Flag{
+				Name:  "max-bytes",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+		},
+		Action: runChurn,
+	}
+}
+
+func runChurn(_ context.Context, cmd *cli.Command) error {
+	churn, err := tsq.Churn(tsq.ChurnOptions{
+		Symbol:   cmd.String("symbol"),
+		Language: cmd.String("language"),
+		Path:     cmd.String("path"),
+		Jobs:     cmd.Int("jobs"),
+		MaxBytes: cmd.Int64("max-bytes"),
+	})
+	if err != nil {
+		return err
+	}
+	return writeJSON(churn, cmd.Bool("compact"))
+}
+
+func testsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "tests",
+		Usage: "list Go tests, benchmarks, examples and fuzz targets",
+		Description: "List Test*, Benchmark*, Example* and Fuzz* functions in _test.go files with\n" +
+			"the function or method each one targets, inferred from its name\n" +
+			"(TestParse -> Parse, TestServer_Start -> Server.Start). Go only.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "root path to scan",
+			},
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "single _test.go file to analyze",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers",
+			},
+			&cli.Int64Flag{
+				Name:  "max-bytes",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+		},
+		Action: runTests,
+	}
+}
+
+func runTests(_ context.Context, cmd *cli.Command) error {
+	opts := tsq.TestsOptions{
+		Path:     cmd.String("path"),
+		File:     cmd.String("file"),
+		Jobs:     cmd.Int("jobs"),
+		MaxBytes: cmd.Int64("max-bytes"),
+	}
+
+	tests, err := tsq.Tests(opts)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(tests, cmd.Bool("compact"))
+}
+
+func shadowsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "shadows",
+		Usage: "find locals that shadow package-level names",
+		Description: "List local variables, parameters and constants that reuse the name of a\n" +
+			"package-level symbol declared in the same directory, with the definition\n" +
+			"they hide. Name-based; Go only.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "root path to scan",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers",
+			},
+			&cli.Int64Flag{
+				Name:  "max-bytes",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+		},
+		Action: runShadows,
+	}
+}
+
+func runShadows(_ context.Context, cmd *cli.Command) error {
+	shadows, err := tsq.Shadows(tsq.ShadowsOptions{
+		Path:     cmd.String("path"),
+		Jobs:     cmd.Int("jobs"),
+		MaxBytes: cmd.Int64("max-bytes"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(shadows, cmd.Bool("compact"))
+}
+
+func treeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "tree",
+		Usage: "print a file's syntax tree",
+		Description: "Print the syntax tree of a file, as an s-expression (anonymous nodes\n" +
+			"quoted) or as nested JSON objects with type, named, range and children.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "file",
+				Aliases:  []string{"f"},
+				Usage:    "file to parse (required)",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "sexp",
+				Usage: "output format: sexp or json",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize JSON output",
+			},
+			&cli.BoolFlag{
+				Name:  "named-only",
+				Usage: "leave out anonymous nodes (keywords and punctuation)",
+			},
+			&cli.IntFlag{
+				Name:  "max-depth",
+				Usage: "stop descending below this depth, the root being 1 (0 = no limit)",
+			},
+			&cli.BoolFlag{
+				Name:  "normalize-eol",
+				Value: true,
+				Usage: "convert CRLF line endings to LF before parsing",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "go",
+				Usage:   "language of the source file",
+			},
+		},
+		Action: runTree,
+	}
+}
+
+func runTree(_ context.Context, cmd *cli.Command) error {
+	format, err := parseFormatFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	root, err := tsq.SyntaxTree(tsq.SyntaxTreeOptions{
+		Language:    cmd.String("language"),
+		File:        cmd.String("file"),
+		NamedOnly:   cmd.Bool("named-only"),
+		MaxDepth:    cmd.Int("max-depth"),
+		PreserveEOL: !cmd.Bool("normalize-eol"),
+	})
+	if err != nil {
+		return err
+	}
+
+	if format == "sexp" {
+		_, err := io.WriteString(stdout, root.Sexp()+"\n")
+		return err
+	}
+	return writeJSON(root, cmd.Bool("compact"))
+}
+
+func statsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "stats",
+		Usage: "count files, lines and symbols per language",
+		Description: "Summarize a tree like cloc, with symbol counts: for each language, the\n" +
+			"number of files, their total lines and their symbols by kind.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "root path to scan",
+			},
+			&cli.StringFlag{
+				Name:    "language",
+				Aliases: []string{"l"},
+				Value:   "go",
+				Usage:   "language to count",
+			},
+			&cli.BoolFlag{
+				Name:  "all-languages",
+				Usage: "count every supported language that has files, instead of --language",
+			},
+			&cli.BoolFlag{
+				Name:  "hidden",
+				Usage: "scan dot-prefixed files and directories (skipped by default)",
+			},
+			&cli.StringFlag{
+				Name:  "ignore-dirs",
+				Usage: "comma-separated directory names to skip besides the default ones (vendor, node_modules, ...)",
+			},
+			&cli.BoolFlag{
+				Name:  "gitignore",
+				Value: true,
+				Usage: "skip files excluded by .gitignore files under --path or above it in its git worktree (--gitignore=false to scan them)",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Value:   runtime.NumCPU(),
+				Usage:   "number of parallel workers",
+			},
+			&cli.Int64Flag{
+				Name:  "max-bytes",
+				Usage: "skip files larger than this (0 = language default, 2MB for go)",
+			},
+		},
+		Action: runStats,
+	}
+}
+
+func runStats(_ context.Context, cmd *cli.Command) error {
+	stats, err := tsq.Stats(tsq.StatsOptions{
+		Language:         cmd.String("language"),
+		AllLanguages:     cmd.Bool("all-languages"),
+		Path:             cmd.String("path"),
+		Hidden:           cmd.Bool("hidden"),
+		IgnoreDirs:       splitList(cmd.String("ignore-dirs")),
+		RespectGitignore: cmd.Bool("gitignore"),
+		Jobs:             cmd.Int("jobs"),
+		MaxBytes:         cmd.Int64("max-bytes"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(stats, cmd.Bool("compact"))
+}
+
+func moduleCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "module",
+		Usage: "report the Go module's path, Go version and direct dependencies",
+		Description: "Find the go.mod file of the module containing --path (looking in its\n" +
+			"parents too) and report the module path, go and toolchain versions, and\n" +
+			"the requirements not marked // indirect.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "path",
+				Value: ".",
+				Usage: "a directory in the module",
+			},
+			&cli.BoolFlag{
+				Name:  "compact",
+				Usage: "minimize output",
+			},
+		},
+		Action: runModule,
+	}
+}
+
+func runModule(_ context.Context, cmd *cli.Command) error {
+	info, err := tsq.Module(tsq.ModuleOptions{Path: cmd.String("path")})
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(info, cmd.Bool("compact"))
+}
+
+func getSnippetCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "get-snippet",
+		Usage:     "print a source snippet stored by symbols --snippet-refs",
+		ArgsUsage: "<ref>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "snippet-cache",
+				Usage: "snippet cache directory (default: tsq/snippets in the user cache directory)",
+			},
+		},
+		Action: runGetSnippet,
+	}
+}
+
+func runGetSnippet(_ context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return errors.New("expected one snippet ref")
+	}
+	dir, err := snippetCacheDir(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := checkUnboundedOutput("get-snippet"); err != nil {
+		return err
+	}
+	snippet, err := tsq.ReadSnippet(dir, cmd.Args().First())
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, snippet+"\n")
+	return err
+}
+
+// snippetCacheDir returns --snippet-cache, defaulting to tsq/snippets in the
+// user cache directory so refs stay resolvable across runs.
+func snippetCacheDir(cmd *cli.Command) (string, error) {
+	if dir := cmd.String("snippet-cache"); dir != "" {
+		return dir, nil
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locate snippet cache: %w", err)
+	}
+	return filepath.Join(cache, "tsq", "snippets"), nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseFilterFlag compiles --filter, returning nil if it is unset.
+func parseFilterFlag(cmd *cli.Command) (*tsq.Filter, error) {
+	expr := cmd.String("filter")
+	if expr == "" {
+		return nil, nil
+	}
+	return tsq.ParseFilter(expr)
+}
+
+// parseDiffFlag reads the changed lines of the unified diff named by --diff,
+// returning nil if it is unset.
+func parseDiffFlag(cmd *cli.Command) (map[string][]tsq.LineRange, error) {
+	name := cmd.String("diff")
+	if name == "" {
+		return nil, nil
+	}
+	if name == "-" {
+		return tsq.ParseUnifiedDiff(os.Stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return tsq.ParseUnifiedDiff(f)
+}
+
+// commandFormats lists the formats besides json that each command's --format
+// accepts.
+var commandFormats = map[string][]string{
+	"query":           {"rg", "quickfix", "golden", "ndjson"},
+	"symbols":         {"tree-json"},
+	"outline":         {"tree-json"},
+	"refs":            {"rg", "quickfix"},
+	"tree":            {"sexp"},
+	"example-queries": {"text"},
+}
+
+// textFormats are the formats that aren't a single JSON value, which
+// --max-output-bytes can't bound.
+var textFormats = map[string]bool{"rg": true, "golden": true, "ndjson": true, "sexp": true, "text": true}
+
+// parseFormatFlag validates --format against json and the command's other
+// formats, returning the format.
+func parseFormatFlag(cmd *cli.Command) (string, error) {
+	formats := commandFormats[cmd.Name]
+	format := cmd.String("format")
+	if format == "" || format == "json" {
+		return "json", nil
+	}
+	if slices.Contains(formats, format) {
+		if textFormats[format] {
+			if err := checkUnboundedOutput("--format " + format); err != nil {
+				return "", err
+			}
+		}
+		return format, nil
+	}
+	want := "json"
+	for i, f := range formats {
+		if i == len(formats)-1 {
+			want += " or " + f
+		} else {
+			want += ", " + f
+		}
+	}
+	return "", fmt.Errorf("invalid format %q: want %s", format, want)
+}
+
+// writeDot writes import edges as a graphviz digraph.
+func writeDot(edges []tsq.ImportEdge) error {
+	var sb strings.Builder
+	sb.WriteString("digraph imports {\n")
+	for _, e := range edges {
+		fmt.Fprintf(&sb, "  %q -> %q;\n", e.From, e.To)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(stdout, sb.String())
+	return err
+}
+
+// stdout is where command output is written. It is wrapped to count bytes
+// when --estimate-tokens is set.
+var stdout io.Writer = os.Stdout
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// writeTokenEstimate reports the approximate token cost of n bytes of output
+// on stderr.
+func writeTokenEstimate(n int) {
+	enc := json.NewEncoder(os.Stderr)
+	enc.Encode(map[string]int{
+		"output_bytes":     n,
+		"estimated_tokens": tsq.EstimateTokens(n),
+	})
+}
+
+// JSON output helpers
+func writeJSON(v any, compact bool) error {
+	if maxOutputBytes > 0 {
+		return writeBoundedJSON(v, compact)
+	}
+	return encodeJSON(stdout, v, compact)
+}
+
+func encodeJSON(w io.Writer, v any, compact bool) error {
+	if compactPositions {
+		v = tsq.CompactPositions(v)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if !compact {
+		enc.SetIndent("", jsonIndent)
+	}
+	return enc.Encode(v)
+}
+
+// maxOutputBytes is set by --max-output-bytes.
+var maxOutputBytes int
+
+// boundedOutput is the JSON output with --max-output-bytes.
+type boundedOutput struct {
+	Results   any  `json:"results"`
+	Truncated bool `json:"truncated"`
+}
+
+// checkUnboundedOutput rejects --max-output-bytes for output, named by
+// what, that isn't a single JSON value and so can't be bounded.
+func checkUnboundedOutput(what string) error {
+	if maxOutputBytes > 0 {
+		return fmt.Errorf("--max-output-bytes is not supported with %s", what)
+	}
+	return nil
+}
+
+// groupedResults are the result types that group one file's results in a
+// slice field. --max-output-bytes cuts within a group, so a large file
+// keeps as many of its results as fit.
+var groupedResults = map[reflect.Type]bool{
+	reflect.TypeOf(tsq.SymbolsResult{}):  true,
+	reflect.TypeOf(tsq.CommentsResult{}): true,
+}
+
+// writeBoundedJSON writes v wrapped in a boundedOutput of at most
+// maxOutputBytes bytes, keeping as many leading results (see
+// countResults) as fit.
+func writeBoundedJSON(v any, compact bool) error {
+	var full bytes.Buffer
+	if err := encodeJSON(&full, boundedOutput{Results: v}, compact); err != nil {
+		return err
+	}
+	if full.Len() <= maxOutputBytes {
+		_, err := stdout.Write(full.Bytes())
+		return err
+	}
+
+	empty, err := encodedLen(boundedOutput{Results: truncateResults(v, 0), Truncated: true}, 0, compact)
+	if err != nil {
+		return err
+	}
+	if empty+1 > maxOutputBytes { // the encoder ends the output with a newline
+		return fmt.Errorf("--max-output-bytes %d is too small for any output", maxOutputBytes)
+	}
+	m := &resultMeter{compact: compact, left: maxOutputBytes - empty - 1}
+	if err := m.fit(v); err != nil {
+		return err
+	}
+	return encodeJSON(stdout, boundedOutput{Results: truncateResults(v, m.kept), Truncated: true}, compact)
+}
+
+// resultMeter counts the leading results of a command's output that fit in
+// a number of bytes. Each result is encoded once, on its own, and measured
+// by the bytes it adds to the output.
+type resultMeter struct {
+	compact bool
+	left    int  // bytes not yet taken
+	kept    int  // results that fit
+	full    bool // a result didn't fit, so no later one is kept
+}
+
+// take reports whether n more bytes fit, taking them if so.
+func (m *resultMeter) take(n int) bool {
+	if n > m.left {
+		m.full = true
+		return false
+	}
+	m.left -= n
+	return true
+}
+
+// fit counts the results of v that fit, in the order of countResults.
+func (m *resultMeter) fit(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	wrap := func(x reflect.Value) any { return boundedOutput{Results: x.Interface(), Truncated: true} }
+	switch rv.Kind() {
+	case reflect.Slice:
+		if !groupedResults[rv.Type().Elem()] {
+			return m.fitElements(rv, 0, wrap, 0, 1)
+		}
+		return m.fitGroups(rv, wrap)
+	case reflect.Struct:
+		shell := cutFields(rv, 0)
+		for i := 0; i < rv.NumField() && !m.full; i++ {
+			if !rv.Type().Field(i).IsExported() || rv.Field(i).Kind() != reflect.Slice {
+				continue
+			}
+			withField := func(x reflect.Value) any {
+				s := reflect.New(rv.Type()).Elem()
+				s.Set(shell)
+				s.Field(i).Set(x)
+				return wrap(s)
+			}
+			if err := m.fitElements(rv.Field(i), 0, withField, 0, 2); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fitGroups counts the results of groups, a slice of groupedResults, that
+// fit. A group with results is added with its first one, so no group is
+// kept empty.
+func (m *resultMeter) fitGroups(groups reflect.Value, wrap func(reflect.Value) any) error {
+	for i := 0; i < groups.Len() && !m.full; i++ {
+		group := groups.Index(i)
+		first := cutFields(group, 1)
+		var n int
+		var err error
+		if i == 0 {
+			one := reflect.MakeSlice(groups.Type(), 1, 1)
+			one.Index(0).Set(first)
+			n, err = m.firstElementLen(wrap, one, 0)
+		} else {
+			n, err = elementLen(first.Interface(), 1, m.compact)
+		}
+		if err != nil {
+			return err
+		}
+		if !m.take(n) {
+			return nil
+		}
+		m.kept++
+
+		for j := 0; j < group.NumField(); j++ {
+			if group.Type().Field(j).IsExported() && group.Field(j).Kind() == reflect.Slice {
+				if err := m.fitElements(group.Field(j), 1, nil, 0, 3); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fitElements counts the elements of s from index from that fit. s is an
+// array nested depth levels deep in the output; its first element, if
+// from is 0, is measured as the bytes it adds to wrap(s), encoded
+// wrapDepth levels deep.
+func (m *resultMeter) fitElements(s reflect.Value, from int, wrap func(reflect.Value) any, wrapDepth, depth int) error {
+	for i := from; i < s.Len() && !m.full; i++ {
+		var n int
+		var err error
+		if i == 0 {
+			n, err = m.firstElementLen(wrap, s.Slice(0, 1), wrapDepth)
+		} else {
+			n, err = elementLen(s.Index(i).Interface(), depth, m.compact)
+		}
+		if err != nil {
+			return err
+		}
+		if !m.take(n) {
+			return nil
+		}
+		m.kept++
+	}
+	return nil
+}
+
+// firstElementLen returns the bytes one, a slice of one element, adds to
+// wrap's value over an empty slice, both encoded depth levels deep. The
+// empty slice may encode as [], null or not at all.
+func (m *resultMeter) firstElementLen(wrap func(reflect.Value) any, one reflect.Value, depth int) (int, error) {
+	with, err := encodedLen(wrap(one), depth, m.compact)
+	if err != nil {
+		return 0, err
+	}
+	without, err := encodedLen(wrap(one.Slice(0, 0)), depth, m.compact)
+	return with - without, err
+}
+
+// elementLen returns the bytes x adds as a further element of a non-empty
+// array nested depth levels deep: a comma, and in indented output a new
+// line and its indentation, before x itself.
+func elementLen(x any, depth int, compact bool) (int, error) {
+	n, err := encodedLen(x, depth+1, compact)
+	if compact {
+		return n + 1, err
+	}
+	return n + 2 + len(jsonIndent)*(depth+1), err
+}
+
+// jsonIndent is the indentation of each level of indented JSON output.
+const jsonIndent = "  "
+
+// encodedLen returns the length of x encoded as by encodeJSON, without the
+// trailing newline, as a value nested depth levels deep in the output.
+func encodedLen(x any, depth int, compact bool) (int, error) {
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, x, true); err != nil {
+		return 0, err
+	}
+	b := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	if compact {
+		return len(b), nil
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, b, strings.Repeat(jsonIndent, depth), jsonIndent); err != nil {
+		return 0, err
+	}
+	return indented.Len(), nil
+}
+
+// countResults returns the number of results in v, a command's output: its
+// elements if it is a slice, or the elements of its exported slice fields
+// if it is a struct or a pointer to one. The elements of groupedResults are
+// counted by their own results, or as one if they have none. Other values
+// have none.
+func countResults(v any) int {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Slice:
+		if !groupedResults[rv.Type().Elem()] {
+			return rv.Len()
+		}
+		n := 0
+		for i := 0; i < rv.Len(); i++ {
+			n += max(countResults(rv.Index(i).Interface()), 1)
+		}
+		return n
+	case reflect.Struct:
+		n := 0
+		for i := 0; i < rv.NumField(); i++ {
+			if rv.Type().Field(i).IsExported() && rv.Field(i).Kind() == reflect.Slice {
+				n += rv.Field(i).Len()
+			}
+		}
+		return n
+	}
+	return 0
+}
+
+// truncateResults returns a copy of v with only its first k results, as
+// counted by countResults. Struct fields are cut in order, so later slice
+// fields are emptied first, and groups are cut within, so a group is
+// either dropped or kept with at least one result.
+func truncateResults(v any, k int) any {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Slice:
+		if !groupedResults[rv.Type().Elem()] {
+			return rv.Slice(0, min(k, rv.Len())).Interface()
+		}
+		out := reflect.MakeSlice(rv.Type(), 0, 0)
+		for i := 0; i < rv.Len() && k > 0; i++ {
+			group := rv.Index(i)
+			n := max(countResults(group.Interface()), 1)
+			out = reflect.Append(out, cutFields(group, k))
+			k -= min(k, n)
+		}
+		return out.Interface()
+	case reflect.Struct:
+		return cutFields(rv, k).Interface()
+	}
+	return v
+}
+
+// cutFields returns a copy of the struct s with its exported slice fields
+// cut to k elements in all, in field order.
+func cutFields(s reflect.Value, k int) reflect.Value {
+	out := reflect.New(s.Type()).Elem()
+	out.Set(s)
+	for i := 0; i < out.NumField(); i++ {
+		f := out.Field(i)
+		if !s.Type().Field(i).IsExported() || f.Kind() != reflect.Slice {
+			continue
+		}
+		keep := min(k, f.Len())
+		f.Set(f.Slice(0, keep))
+		k -= keep
+	}
+	return out
+}
+
+// compactPositions is set by --compact-positions.
+var compactPositions bool
+
+// writeDiagnostics reports non-fatal diagnostics on stderr so stdout stays
+// valid JSON.
+func writeDiagnostics(diags []tsq.Diagnostic) {
+	if len(diags) == 0 {
+		return
+	}
+	enc := json.NewEncoder(os.Stderr)
+	enc.Encode(map[string][]tsq.Diagnostic{
+		"diagnostics": diags,
+	})
 }
 
 func writeError(err error) {
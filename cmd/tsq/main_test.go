@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/arjunmahishi/tsq/tsq"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+// captureBoundedJSON writes v with --max-output-bytes set to limit and
+// returns the output.
+func captureBoundedJSON(t *testing.T, v any, limit int, compact bool) ([]byte, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	oldStdout, oldMax := stdout, maxOutputBytes
+	stdout, maxOutputBytes = &buf, limit
+	t.Cleanup(func() { stdout, maxOutputBytes = oldStdout, oldMax })
+
+	err := writeJSON(v, compact)
+	return buf.Bytes(), err
+}
+
+func TestWriteJSONMaxOutputBytes(t *testing.T) {
+	var symbols []tsq.Symbol
+	for i := range 50 {
+		symbols = append(symbols, tsq.Symbol{Kind: "function", Name: fmt.Sprintf("Func%02d", i), Visibility: "public"})
+	}
+
+	for _, compact := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compact=%v", compact), func(t *testing.T) {
+			out, err := captureBoundedJSON(t, symbols, 1000, compact)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(out), 1000)
+			require.True(t, json.Valid(out), "%s", out)
+
+			var got struct {
+				Results   []tsq.Symbol `json:"results"`
+				Truncated bool         `json:"truncated"`
+			}
+			require.NoError(t, json.Unmarshal(out, &got))
+			require.True(t, got.Truncated)
+			require.NotEmpty(t, got.Results)
+			require.Less(t, len(got.Results), len(symbols))
+			require.Equal(t, symbols[:len(got.Results)], got.Results)
+
+			// One more result would not have fit.
+			more, err := json.Marshal(symbols[len(got.Results)])
+			require.NoError(t, err)
+			require.Greater(t, len(out)+len(more), 1000)
+		})
+	}
+}
+
+func TestWriteJSONMaxOutputBytesFits(t *testing.T) {
+	symbols := []tsq.Symbol{{Kind: "function", Name: "main", Visibility: "private"}}
+	out, err := captureBoundedJSON(t, symbols, 1<<20, true)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"results": [{"kind": "function", "name": "main", "visibility": "private", "file": "", "range": {"start": {"line": 0, "column": 0}, "end": {"line": 0, "column": 0}}}], "truncated": false}`, string(out))
+}
+
+func TestWriteJSONMaxOutputBytesStruct(t *testing.T) {
+	result := &tsq.RefsResult{Symbol: "Parse"}
+	for i := range 40 {
+		result.References = append(result.References, tsq.Reference{
+			Symbol: "Parse", Kind: "call", File: "main.go", Position: tsq.Position{Line: i + 1, Column: 2},
+		})
+	}
+
+	out, err := captureBoundedJSON(t, result, 800, true)
+	require.NoError(t, err)
+	require.LessOrEqual(t, len(out), 800)
+
+	var got struct {
+		Results   tsq.RefsResult `json:"results"`
+		Truncated bool           `json:"truncated"`
+	}
+	require.NoError(t, json.Unmarshal(out, &got))
+	require.True(t, got.Truncated)
+	require.Equal(t, "Parse", got.Results.Symbol)
+	require.NotEmpty(t, got.Results.References)
+	require.Equal(t, result.References[:len(got.Results.References)], got.Results.References)
+	require.Len(t, result.References, 40, "the original result is left as is")
+}
+
+func TestWriteJSONMaxOutputBytesTooSmall(t *testing.T) {
+	_, err := captureBoundedJSON(t, []tsq.Symbol{}, 10, true)
+	require.EqualError(t, err, "--max-output-bytes 10 is too small for any output")
+}
+
+func TestWriteJSONMaxOutputBytesGrouped(t *testing.T) {
+	results := []tsq.SymbolsResult{{File: "big.go"}, {File: "empty.go", Symbols: []tsq.Symbol{}}, {File: "small.go"}}
+	for i := range 30 {
+		results[0].Symbols = append(results[0].Symbols, tsq.Symbol{Kind: "function", Name: fmt.Sprintf("Big%02d", i), File: "big.go"})
+	}
+	results[2].Symbols = []tsq.Symbol{{Kind: "type", Name: "Small", File: "small.go"}}
+	require.Equal(t, 32, countResults(results))
+
+	for _, compact := range []bool{false, true} {
+		// Every limit keeps as many results as fit: one more would not.
+		for limit := 60; limit < 6000; limit += 37 {
+			out, err := captureBoundedJSON(t, results, limit, compact)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(out), limit)
+
+			var got struct {
+				Results   []tsq.SymbolsResult `json:"results"`
+				Truncated bool                `json:"truncated"`
+			}
+			require.NoError(t, json.Unmarshal(out, &got))
+			kept := countResults(got.Results)
+			require.Equal(t, truncateResults(results, kept), got.Results)
+			if !got.Truncated {
+				require.Equal(t, results, got.Results)
+				continue
+			}
+			var more bytes.Buffer
+			require.NoError(t, encodeJSON(&more, boundedOutput{Results: truncateResults(results, kept+1), Truncated: true}, compact))
+			require.Greater(t, more.Len(), limit, "limit %d, compact=%v", limit, compact)
+		}
+	}
+
+	// A single large file is cut within, not dropped whole.
+	out, err := captureBoundedJSON(t, results[:1], 400, true)
+	require.NoError(t, err)
+	var got struct {
+		Results []tsq.SymbolsResult `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(out, &got))
+	require.Len(t, got.Results, 1)
+	require.NotEmpty(t, got.Results[0].Symbols)
+	require.Less(t, len(got.Results[0].Symbols), 30)
+}
+
+func TestMaxOutputBytesTextFormats(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	file := filepath.Join(dir, "main.go")
+
+	for _, args := range [][]string{
+		{"query", "--find", "function", "--path", dir, "--format", "rg"},
+		{"query", "--find", "function", "--path", dir, "--format", "golden"},
+		{"query", "--find", "function", "--path", dir, "--format", "ndjson"},
+		{"refs", "--symbol", "main", "--path", dir, "--format", "rg"},
+		{"tree", "--file", file, "--format", "sexp"},
+		{"example-queries", "--format", "text"},
+		{"imports", "--path", dir, "--dot"},
+	} {
+		var buf bytes.Buffer
+		oldStdout, oldMax := stdout, maxOutputBytes
+		stdout, maxOutputBytes = &buf, 100
+		app := &cli.Command{Commands: []*cli.Command{queryCommand(), refsCommand(), treeCommand(), examplesCommand(), importsCommand()}}
+		err := app.Run(context.Background(), append([]string{"tsq"}, args...))
+		stdout, maxOutputBytes = oldStdout, oldMax
+		require.ErrorContains(t, err, "--max-output-bytes is not supported with", "%v", args)
+		require.Empty(t, buf.String(), "%v", args)
+	}
+}
+
+func TestOutlinePathNDJSON(t *testing.T) {
+	dir := t.TempDir()
+	const goFiles = 30
+	for i := range goFiles {
+		name := filepath.Join(dir, fmt.Sprintf("pkg%d", i%3), fmt.Sprintf("file%d.go", i))
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, os.WriteFile(name, []byte(fmt.Sprintf("package pkg\n\nfunc F%d() {}\n", i)), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.ts"), []byte("export function start() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not code\n"), 0644))
+
+	var buf bytes.Buffer
+	oldStdout := stdout
+	stdout = &buf
+	t.Cleanup(func() { stdout = oldStdout })
+
+	app := &cli.Command{Commands: []*cli.Command{outlineCommand()}}
+	require.NoError(t, app.Run(context.Background(), []string{"tsq", "outline", "--path", dir, "-j", "4"}))
+
+	files := make(map[string]bool)
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var outline tsq.FileOutline
+		dec := json.NewDecoder(bytes.NewReader(sc.Bytes()))
+		dec.DisallowUnknownFields()
+		require.NoError(t, dec.Decode(&outline), sc.Text())
+		require.NotEmpty(t, outline.Symbols, outline.File)
+		require.False(t, files[outline.File], "%s outlined twice", outline.File)
+		files[outline.File] = true
+	}
+	require.NoError(t, sc.Err())
+	require.Len(t, files, goFiles+1, "every .go and .ts file, and nothing else")
+	require.True(t, files["pkg1/file4.go"])
+	require.True(t, files["app.ts"])
+
+	err := app.Run(context.Background(), []string{"tsq", "outline", "--path", dir, "--format", "tree-json"})
+	require.EqualError(t, err, "--format and --index-positions are not supported with --path, which writes ndjson")
+}
+
+func TestQueryNDJSON(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 20 {
+		src := fmt.Sprintf("package pkg\n\nfunc F%d() {}\n\nfunc G%d() {}\n", i, i)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.go", i)), []byte(src), 0644))
+	}
+
+	var buf bytes.Buffer
+	oldStdout := stdout
+	stdout = &buf
+	t.Cleanup(func() { stdout = oldStdout })
+
+	app := &cli.Command{Commands: []*cli.Command{queryCommand()}}
+	require.NoError(t, app.Run(context.Background(), []string{
+		"tsq", "query", "--path", dir, "-j", "4", "--format", "ndjson",
+		"--query", "(function_declaration name: (identifier) @name)",
+		"--filter", `file != "file3.go"`,
+	}))
+
+	lines := 0
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var m tsq.QueryMatch
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &m), sc.Text())
+		require.Len(t, m.Captures, 1)
+		require.NotEqual(t, "file3.go", m.File)
+		lines++
+	}
+	require.NoError(t, sc.Err())
+	require.Equal(t, 38, lines, "two functions in each file but file3.go")
+}
+
+func TestQueryEchoOptions(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {}\n"), 0644))
+
+	var buf bytes.Buffer
+	oldStdout := stdout
+	stdout = &buf
+	t.Cleanup(func() { stdout = oldStdout })
+
+	const query = "(function_declaration name: (identifier) @name)"
+	app := &cli.Command{Commands: []*cli.Command{queryCommand()}}
+	require.NoError(t, app.Run(context.Background(), []string{
+		"tsq", "query", "--path", dir, "--query", query, "--echo-options", "--max-bytes", "1000",
+	}))
+
+	var envelope struct {
+		Options map[string]any   `json:"options"`
+		Matches []tsq.QueryMatch `json:"matches"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &envelope))
+	require.Len(t, envelope.Matches, 1)
+	require.Equal(t, map[string]any{
+		"query":             query,
+		"language":          "go",
+		"path":              dir,
+		"path_relative_to":  "root",
+		"jobs":              float64(runtime.NumCPU()),
+		"schedule":          "fifo",
+		"max_bytes":         float64(1000),
+		"respect_gitignore": true,
+	}, envelope.Options)
+
+	err := app.Run(context.Background(), []string{"tsq", "query", "--path", dir, "--query", query, "--echo-options", "--format", "rg"})
+	require.EqualError(t, err, "--echo-options is not supported with --format rg")
+}
+
+func TestQueryEnvelopeFilter(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {}\n\nfunc B() {}\n"), 0644))
+
+	app := &cli.Command{Commands: []*cli.Command{queryCommand()}}
+	query := []string{"tsq", "query", "--path", dir, "--query", "(function_declaration name: (identifier) @name)", "--filter", `file == "a.go"`}
+	err := app.Run(context.Background(), append(query, "--with-legend"))
+	require.EqualError(t, err, "--with-legend can't be combined with --filter")
+	err = app.Run(context.Background(), append(query, "--pattern-coverage"))
+	require.EqualError(t, err, "--pattern-coverage can't be combined with --filter")
+}
+
+func TestSymbolsWithArity(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {}\n"), 0644))
+
+	run := func(args ...string) map[string]any {
+		t.Helper()
+		var buf bytes.Buffer
+		oldStdout := stdout
+		stdout = &buf
+		defer func() { stdout = oldStdout }()
+
+		app := &cli.Command{Commands: []*cli.Command{symbolsCommand()}}
+		require.NoError(t, app.Run(context.Background(), append([]string{"tsq", "symbols", "--path", dir}, args...)))
+
+		var results []struct {
+			Symbols []map[string]any `json:"symbols"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &results))
+		require.Len(t, results, 1)
+		require.Len(t, results[0].Symbols, 1)
+		return results[0].Symbols[0]
+	}
+
+	sym := run("--with-arity")
+	require.Equal(t, float64(0), sym["num_params"], "a requested zero arity is reported")
+	require.Equal(t, float64(0), sym["num_results"])
+
+	sym = run()
+	require.NotContains(t, sym, "num_params")
+	require.NotContains(t, sym, "num_results")
+}
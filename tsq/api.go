@@ -1,78 +1,270 @@
 package tsq
 
 import (
+	"cmp"
+	"context"
 	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"unicode"
+	"unicode/utf8"
+
+	sitter "github.com/smacker/go-tree-sitter"
 )
 
 // Query executes a custom tree-sitter query and returns matches.
 func Query(opts QueryOptions) ([]QueryMatch, error) {
-	if opts.Query == "" {
-		return nil, errors.New("query is required")
+	return QueryContext(context.Background(), opts)
+}
+
+// QueryContext is Query with a context: once ctx is done, files are no
+// longer dispatched to the workers and ctx.Err() is returned.
+func QueryContext(ctx context.Context, opts QueryOptions) ([]QueryMatch, error) {
+	matches := []QueryMatch{}
+	stream, errc := QueryStream(ctx, opts)
+	for m := range stream {
+		matches = append(matches, m)
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// QueryStream runs a query like QueryContext but sends each match on the
+// returned channel as soon as its file has been processed, so matches
+// needn't all be held in memory. Matches arrive in completion order. Once
+// every match has been sent, the error channel receives the error, if any,
+// and both channels are closed; Diagnostics, Legend and PatternCoverage are
+// written before that.
+//
+// A caller that stops reading matches early must cancel ctx, or the query
+// is left blocked.
+func QueryStream(ctx context.Context, opts QueryOptions) (<-chan QueryMatch, <-chan error) {
+	matches := make(chan QueryMatch, 128)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(matches)
+		err := streamQuery(ctx, opts, func(m QueryMatch) {
+			select {
+			case matches <- m:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			errc <- err
+		}
+	}()
+	return matches, errc
+}
+
+// streamQuery runs a query and hands each match to emit, on the calling
+// goroutine.
+func streamQuery(ctx context.Context, opts QueryOptions, emit func(QueryMatch)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if opts.Query == "" && opts.Find == "" {
+		return errors.New("query is required")
 	}
 	if opts.Language == "" {
 		opts.Language = "go" // Default to Go
 	}
+	if opts.Find != "" {
+		q, err := findQuery(opts)
+		if err != nil {
+			return err
+		}
+		opts.Query = q
+	} else if opts.Name != "" || opts.Receiver != "" {
+		return errors.New("name and receiver filters require find")
+	}
 	if opts.Path == "" {
 		opts.Path = "."
 	}
 	if opts.Jobs == 0 {
 		opts.Jobs = runtime.NumCPU()
 	}
-	if opts.MaxBytes == 0 {
-		opts.MaxBytes = 2 * 1024 * 1024
+	if err := validSchedule(opts.Schedule); err != nil {
+		return err
 	}
 
 	language := Get(opts.Language)
 	if language == nil {
-		return nil, errors.New(opts.Language + " language not registered")
+		return errors.New(opts.Language + " language not registered")
 	}
 
 	query, err := newQuery(opts.Query, language)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	query.preserveInvalidUTF8 = opts.PreserveInvalidUTF8
+	if opts.Resolved != nil {
+		*opts.Resolved = resolvedQueryOptions(opts, language)
 	}
 
+	var diags diagnostics
 	var files []FileJob
 	if opts.File != "" {
-		sc := newScanner(scannerConfig{language: language})
-		job, err := sc.collectSingle(opts.File)
+		sc := newScanner(scannerConfig{
+			language:     language,
+			relativeTo:   opts.PathRelativeTo,
+			baseDir:      opts.BaseDir,
+			hidden:       opts.Hidden,
+			ignoreDirs:   ignoreDirSet(opts.IgnoreDirs),
+			ignoreSingle: opts.RespectIgnoreSingle,
+			fsys:         opts.FS,
+		})
+		files, err = sc.collectFile(opts.File, &diags)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		files = []FileJob{job}
 	} else {
 		sc := newScanner(scannerConfig{
-			root:     opts.Path,
-			language: language,
-			maxBytes: opts.MaxBytes,
+			root:         opts.Path,
+			language:     language,
+			maxBytes:     opts.MaxBytes,
+			skipMinified: opts.SkipMinified,
+			relativeTo:   opts.PathRelativeTo,
+			baseDir:      opts.BaseDir,
+			sample:       opts.Sample,
+			seed:         opts.Seed,
+			maxFiles:     opts.MaxFiles,
+			hidden:       opts.Hidden,
+			ignoreDirs:   ignoreDirSet(opts.IgnoreDirs),
+			gitignore:    opts.RespectGitignore,
+			fsys:         opts.FS,
 		})
 		files, err = sc.collect()
 		if err != nil {
-			return nil, err
+			return err
 		}
+		sc.reportTruncated(&diags)
 	}
 
+	tally := newMatchTally(query, opts.Legend != nil, opts.PatternCoverage != nil)
 	if len(files) == 0 {
-		return []QueryMatch{}, nil
+		diags.flush(opts.Diagnostics)
+		tally.write(opts.Legend, opts.PatternCoverage)
+		return nil
+	}
+
+	cfg := workerConfig{
+		ctx:         ctx,
+		jobs:        opts.Jobs,
+		preserveEOL: opts.PreserveEOL,
+		overrides:   absOverrides(opts.FS, opts.FileOverrides),
+		fsys:        opts.FS,
+		schedule:    opts.Schedule,
+		atLine:      opts.AtLine,
+		strictParse: opts.StrictParse,
+		diags:       &diags,
+	}
+	children := childFilter{require: opts.RequireChild, forbid: opts.ForbidChild}
+	names := nameFilter{name: opts.Name, receiver: opts.Receiver}
+	streamQueryWorkers(language, query, files, cfg, opts.PatternIndex, children, names, opts.MaxPerFile, opts.ContextBytes, func(m QueryMatch) {
+		tally.add(m)
+		emit(m)
+	})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	diags.flush(opts.Diagnostics)
+	tally.write(opts.Legend, opts.PatternCoverage)
+	return nil
+}
+
+// resolvedQueryOptions returns opts, whose Language, Path and Jobs
+// defaults are already filled in, as reported by QueryOptions.Resolved.
+func resolvedQueryOptions(opts QueryOptions, language Language) QueryOptions {
+	if opts.Schedule == "" {
+		opts.Schedule = "fifo"
+	}
+	if opts.MaxBytes == 0 && opts.File == "" {
+		opts.MaxBytes = LanguageMaxBytes(language)
 	}
+	opts.Diagnostics = nil
+	opts.Legend = nil
+	opts.PatternCoverage = nil
+	opts.Resolved = nil
+	return opts
+}
 
-	return runQueryWorkers(language, query, files, opts.Jobs), nil
+// matchTally counts, as matches are emitted, the captures of each name and
+// the matches of each pattern of a query, for the Legend and
+// PatternCoverage options. Counts that weren't requested aren't kept.
+type matchTally struct {
+	query    *query
+	captures map[string]int // by capture name, if a legend is wanted
+	patterns []int          // by pattern index, if coverage is wanted
+}
+
+func newMatchTally(query *query, legend, coverage bool) *matchTally {
+	t := &matchTally{query: query}
+	if legend {
+		t.captures = make(map[string]int)
+	}
+	if coverage {
+		t.patterns = make([]int, query.query.PatternCount())
+	}
+	return t
+}
+
+func (t *matchTally) add(m QueryMatch) {
+	if t.captures != nil {
+		for _, c := range m.Captures {
+			t.captures[c.Name]++
+		}
+	}
+	if m.Pattern >= 0 && m.Pattern < len(t.patterns) {
+		t.patterns[m.Pattern]++
+	}
+}
+
+// write stores, if legend is non-nil, one LegendEntry per capture name
+// defined in the query, in definition order, and, if coverage is non-nil,
+// one PatternCount per pattern of the query.
+func (t *matchTally) write(legend *[]LegendEntry, coverage *[]PatternCount) {
+	if legend != nil {
+		entries := make([]LegendEntry, len(t.query.captureNames))
+		for i, name := range t.query.captureNames {
+			entries[i] = LegendEntry{Name: name, Captures: t.captures[name]}
+		}
+		*legend = entries
+	}
+	if coverage != nil {
+		counts := make([]PatternCount, len(t.patterns))
+		for i, n := range t.patterns {
+			counts[i] = PatternCount{Pattern: i, Matches: n}
+		}
+		*coverage = counts
+	}
 }
 
 // SymbolsResult is the output format for symbols extraction.
 type SymbolsResult struct {
 	File    string   `json:"file"`
+	ModTime string   `json:"mod_time,omitempty"` // file modification time, RFC3339 (optional)
 	Symbols []Symbol `json:"symbols"`
 }
 
 // Symbols extracts symbols from code files.
 func Symbols(opts SymbolsOptions) ([]SymbolsResult, error) {
-	if opts.Language == "" {
-		opts.Language = "go"
+	return SymbolsContext(context.Background(), opts)
+}
+
+// SymbolsContext is Symbols with a context: once ctx is done, files are no
+// longer dispatched to the workers and ctx.Err() is returned.
+func SymbolsContext(ctx context.Context, opts SymbolsOptions) ([]SymbolsResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 	if opts.Path == "" {
 		opts.Path = "."
@@ -80,74 +272,156 @@ func Symbols(opts SymbolsOptions) ([]SymbolsResult, error) {
 	if opts.Visibility == "" {
 		opts.Visibility = "all"
 	}
+	if opts.Documented == "" {
+		opts.Documented = "all"
+	}
 	if opts.MaxSourceLines == 0 {
 		opts.MaxSourceLines = 10
 	}
 	if opts.Jobs == 0 {
 		opts.Jobs = runtime.NumCPU()
 	}
-	if opts.MaxBytes == 0 {
-		opts.MaxBytes = 2 * 1024 * 1024
+	if err := validSchedule(opts.Schedule); err != nil {
+		return nil, err
 	}
-
-	language := Get(opts.Language)
-	if language == nil {
-		return nil, errors.New(opts.Language + " language not registered")
+	if err := validTop(opts); err != nil {
+		return nil, err
 	}
 
-	query, err := newQuery(language.SymbolsQuery(), language)
+	language, err := resolveLanguage(opts.Language)
 	if err != nil {
 		return nil, err
 	}
 
+	switch opts.Documented {
+	case "all", "documented", "undocumented":
+	default:
+		return nil, fmt.Errorf("invalid documented filter %q: want all, documented or undocumented", opts.Documented)
+	}
+
+	var query *query
+	if language != nil {
+		if query, err = newQuery(symbolsQuery(language, opts), language); err != nil {
+			return nil, err
+		}
+	}
+
+	var diags diagnostics
+	defer diags.flush(opts.Diagnostics)
+
 	var files []FileJob
 	if opts.File != "" {
-		sc := newScanner(scannerConfig{language: language})
-		job, err := sc.collectSingle(opts.File)
+		sc := newScanner(scannerConfig{
+			language:     language,
+			relativeTo:   opts.PathRelativeTo,
+			baseDir:      opts.BaseDir,
+			hidden:       opts.Hidden,
+			ignoreDirs:   ignoreDirSet(opts.IgnoreDirs),
+			ignoreSingle: opts.RespectIgnoreSingle,
+			fsys:         opts.FS,
+		})
+		files, err = sc.collectFile(opts.File, &diags)
 		if err != nil {
 			return nil, err
 		}
-		files = []FileJob{job}
 	} else {
 		sc := newScanner(scannerConfig{
-			root:     opts.Path,
-			language: language,
-			maxBytes: opts.MaxBytes,
+			root:         opts.Path,
+			language:     language,
+			maxBytes:     opts.MaxBytes,
+			skipMinified: opts.SkipMinified,
+			relativeTo:   opts.PathRelativeTo,
+			baseDir:      opts.BaseDir,
+			pathPattern:  opts.PathPattern,
+			sample:       opts.Sample,
+			seed:         opts.Seed,
+			maxFiles:     opts.MaxFiles,
+			hidden:       opts.Hidden,
+			ignoreDirs:   ignoreDirSet(opts.IgnoreDirs),
+			gitignore:    opts.RespectGitignore,
+			fsys:         opts.FS,
 		})
-		files, err = sc.collect()
+		if opts.ChangedSince != "" {
+			files, err = sc.collectChanged(opts.ChangedSince)
+		} else {
+			files, err = sc.collect()
+		}
 		if err != nil {
 			return nil, err
 		}
+		sc.reportTruncated(&diags)
 	}
 
+	changed := resolveChangedLines(opts.ChangedLines, scanRoot(opts.Path, opts.File), opts.FS)
+	files = changed.filterFiles(files)
 	if len(files) == 0 {
 		return []SymbolsResult{}, nil
 	}
 
-	return runSymbolsWorkers(language, query, files, opts.Jobs, opts.Visibility, opts.IncludeSource, opts.MaxSourceLines), nil
+	var results []SymbolsResult
+	if opts.Top > 0 {
+		if results, err = topSymbols(ctx, language, query, files, opts, changed, &diags); err != nil {
+			return nil, err
+		}
+	} else {
+		results = runSymbolsWorkers(ctx, language, query, files, opts, changed, &diags)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if opts.PromoteEmbedded {
+			promoteEmbedded(results)
+		}
+	}
+
+	if opts.MethodQualifiedNames {
+		methodQualifyNames(results)
+	}
+	if opts.SnippetCache != "" {
+		if err := storeSnippets(opts.SnippetCache, results); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
 }
 
 // Outline returns the structural overview of a file.
 func Outline(opts OutlineOptions) (FileOutline, error) {
+	return OutlineContext(context.Background(), opts)
+}
+
+// OutlineContext is Outline with a context: ctx.Err() is returned if ctx is
+// done before the file is parsed or before its outline is built.
+func OutlineContext(ctx context.Context, opts OutlineOptions) (FileOutline, error) {
+	if err := ctx.Err(); err != nil {
+		return FileOutline{}, err
+	}
 	if opts.File == "" {
 		return FileOutline{}, errors.New("file is required")
 	}
-	if opts.Language == "" {
-		opts.Language = "go"
-	}
 	if opts.MaxSourceLines == 0 {
 		opts.MaxSourceLines = 5
 	}
 
-	language := Get(opts.Language)
+	language, err := resolveLanguage(opts.Language)
+	if err != nil {
+		return FileOutline{}, err
+	}
 	if language == nil {
-		return FileOutline{}, errors.New(opts.Language + " language not registered")
+		if language = fileLanguage(opts.File); language == nil {
+			return FileOutline{}, noLanguageError(opts.File)
+		}
 	}
 
-	query, err := newQuery(language.OutlineQuery(), language)
+	outlineQuery, err := newQuery(language.OutlineQuery(), language)
 	if err != nil {
 		return FileOutline{}, err
 	}
+	var docQuery *query
+	if opts.WithDoc {
+		if docQuery, err = newQuery(commentsQuery(language), language); err != nil {
+			return FileOutline{}, err
+		}
+	}
 
 	sc := newScanner(scannerConfig{language: language})
 	job, err := sc.collectSingle(opts.File)
@@ -156,107 +430,363 @@ func Outline(opts OutlineOptions) (FileOutline, error) {
 	}
 
 	p := newParser(language)
+	p.preserveEOL = opts.PreserveEOL
+	p.overrides = absOverrides(nil, opts.FileOverrides)
 	tree, source, err := p.parseFile(job.AbsPath)
 	if err != nil {
 		return FileOutline{}, err
 	}
+	if err := ctx.Err(); err != nil {
+		return FileOutline{}, err
+	}
+
+	root := tree.RootNode()
+	matches := outlineQuery.run(root, source, job.DisplayPath)
+	return fileOutline(job, language, root, source, matches, docQuery, opts), nil
+}
 
-	matches := query.run(tree, source, job.DisplayPath)
-	outline := buildOutline(job.DisplayPath, matches, source, opts.IncludeSource, opts.MaxSourceLines)
-	return outline, nil
+// fileOutline builds the outline of a parsed file from the matches of its
+// language's outline query. docQuery is the comments query of the language
+// if opts.WithDoc is set, else nil.
+func fileOutline(job FileJob, language Language, root *sitter.Node, source []byte, matches []QueryMatch, docQuery *query, opts OutlineOptions) FileOutline {
+	src := sourceOptions{include: opts.IncludeSource, maxLines: opts.MaxSourceLines, dedent: opts.Dedent}
+	outline := buildOutline(job.DisplayPath, matches, root, src)
+	if visibility := visibilityFunc(language, matches); visibility != nil {
+		for i := range outline.Symbols {
+			outline.Symbols[i].Visibility = visibility(outline.Symbols[i])
+		}
+	}
+	if opts.WithModTime {
+		outline.ModTime = job.modTime()
+	}
+	if docQuery != nil {
+		_, docs := classifyComments(docQuery.run(root, source, job.DisplayPath), source)
+		for i := range outline.Symbols {
+			outline.Symbols[i].Doc = docs[outline.Symbols[i].Range.Start.Line]
+		}
+	}
+	if opts.ClassifyImports {
+		modulePath := findModulePath(filepath.Dir(job.AbsPath))
+		for i := range outline.Imports {
+			outline.Imports[i].Group = classifyImport(outline.Imports[i].Path, modulePath)
+		}
+	}
+	if opts.CountImportUsage {
+		countImportUsage(outline.Imports, root, source)
+	}
+	return outline
 }
 
 // RefsResult is the output format for reference finding.
 type RefsResult struct {
 	Symbol     string      `json:"symbol"`
 	References []Reference `json:"references"`
+
+	// Packages counts the references per package, most referencing first,
+	// if RefsOptions.ByPackage is set.
+	Packages []PackageRefs `json:"packages,omitempty"`
+}
+
+// PackageRefs is the number of references from the files of a package:
+// the distinct positions that use the symbol, not counting its declaration.
+type PackageRefs struct {
+	Package string `json:"package"`
+	Count   int    `json:"count"`
 }
 
+// goPackageQuery captures the package name of a Go file, appended to the
+// refs query for RefsOptions.ByPackage.
+const goPackageQuery = `
+(package_clause (package_identifier) @package)
+`
+
 // Refs finds references to a symbol.
 func Refs(opts RefsOptions) (*RefsResult, error) {
+	return RefsContext(context.Background(), opts)
+}
+
+// RefsContext is Refs with a context: once ctx is done, files are no longer
+// dispatched to the workers and ctx.Err() is returned.
+func RefsContext(ctx context.Context, opts RefsOptions) (*RefsResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if opts.Symbol == "" {
 		return nil, errors.New("symbol is required")
 	}
-	if opts.Language == "" {
-		opts.Language = "go"
-	}
 	if opts.Path == "" {
 		opts.Path = "."
 	}
 	if opts.Jobs == 0 {
 		opts.Jobs = runtime.NumCPU()
 	}
-	if opts.MaxBytes == 0 {
-		opts.MaxBytes = 2 * 1024 * 1024
-	}
-
-	language := Get(opts.Language)
-	if language == nil {
-		return nil, errors.New(opts.Language + " language not registered")
+	if err := validSchedule(opts.Schedule); err != nil {
+		return nil, err
 	}
 
-	query, err := newQuery(language.RefsQuery(), language)
+	language, err := resolveLanguage(opts.Language)
 	if err != nil {
 		return nil, err
 	}
 
+	var query *query
+	if language != nil {
+		if query, err = newQuery(refsQuery(language, opts), language); err != nil {
+			return nil, err
+		}
+	}
+
+	var diags diagnostics
 	var files []FileJob
 	if opts.File != "" {
-		sc := newScanner(scannerConfig{language: language})
-		job, err := sc.collectSingle(opts.File)
+		sc := newScanner(scannerConfig{
+			language:     language,
+			relativeTo:   opts.PathRelativeTo,
+			baseDir:      opts.BaseDir,
+			hidden:       opts.Hidden,
+			ignoreDirs:   ignoreDirSet(opts.IgnoreDirs),
+			ignoreSingle: opts.RespectIgnoreSingle,
+			fsys:         opts.FS,
+		})
+		files, err = sc.collectFile(opts.File, &diags)
 		if err != nil {
 			return nil, err
 		}
-		files = []FileJob{job}
 	} else {
 		sc := newScanner(scannerConfig{
-			root:     opts.Path,
-			language: language,
-			maxBytes: opts.MaxBytes,
+			root:         opts.Path,
+			language:     language,
+			maxBytes:     opts.MaxBytes,
+			skipMinified: opts.SkipMinified,
+			relativeTo:   opts.PathRelativeTo,
+			baseDir:      opts.BaseDir,
+			pathPattern:  opts.PathPattern,
+			sample:       opts.Sample,
+			seed:         opts.Seed,
+			maxFiles:     opts.MaxFiles,
+			hidden:       opts.Hidden,
+			ignoreDirs:   ignoreDirSet(opts.IgnoreDirs),
+			gitignore:    opts.RespectGitignore,
+			fsys:         opts.FS,
 		})
 		files, err = sc.collect()
 		if err != nil {
 			return nil, err
 		}
+		sc.reportTruncated(&diags)
 	}
 
+	changed := resolveChangedLines(opts.ChangedLines, scanRoot(opts.Path, opts.File), opts.FS)
+	files = changed.filterFiles(files)
 	if len(files) == 0 {
+		diags.flush(opts.Diagnostics)
 		return &RefsResult{Symbol: opts.Symbol, References: []Reference{}}, nil
 	}
 
-	refs := runRefsWorkers(language, query, files, opts.Jobs, opts.Symbol, opts.IncludeContext)
-	return &RefsResult{
+	cfg := workerConfig{
+		ctx:         ctx,
+		jobs:        opts.Jobs,
+		preserveEOL: opts.PreserveEOL || opts.IncludeOffsets,
+		overrides:   absOverrides(opts.FS, opts.FileOverrides),
+		fsys:        opts.FS,
+		schedule:    opts.Schedule,
+		strictParse: opts.StrictParse,
+		diags:       &diags,
+	}
+	if language == nil {
+		cfg.queries = newLanguageQueries(func(language Language) string {
+			return refsQuery(language, opts)
+		})
+	}
+	refs := runRefsWorkers(language, query, files, cfg, opts, changed)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	diags.flush(opts.Diagnostics)
+	result := &RefsResult{
 		Symbol:     opts.Symbol,
 		References: refs,
-	}, nil
+	}
+	if opts.ByPackage {
+		result.Packages = countPackageRefs(refs)
+	}
+	return result, nil
+}
+
+// refsQuery returns the query Refs runs on files of language.
+func refsQuery(language Language, opts RefsOptions) string {
+	if opts.ByPackage && language.Name() == "go" {
+		return language.RefsQuery() + goPackageQuery
+	}
+	return language.RefsQuery()
+}
+
+// countPackageRefs counts refs by Package, most references first, then by
+// package name. A position captured as several kinds of reference, such as
+// a call and an identifier, counts once, and declarations don't count.
+func countPackageRefs(refs []Reference) []PackageRefs {
+	type key struct {
+		file string
+		pos  Position
+	}
+	seen := make(map[key]bool)
+	counts := make(map[string]int)
+	for _, ref := range refs {
+		k := key{ref.File, ref.Position}
+		if ref.declaration || seen[k] {
+			continue
+		}
+		seen[k] = true
+		counts[ref.Package]++
+	}
+	packages := []PackageRefs{}
+	for pkg, count := range counts {
+		packages = append(packages, PackageRefs{Package: pkg, Count: count})
+	}
+	slices.SortFunc(packages, func(a, b PackageRefs) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Package, b.Package))
+	})
+	return packages
+}
+
+// workerConfig controls how runWorkers reads and parses files.
+type workerConfig struct {
+	// queries, if set, gives the query for each file's language, which
+	// replaces the pool's language and query. Each worker creates a parser
+	// per language as it meets its files.
+	queries *languageQueries
+
+	// ctx, if set, stops the pool once it is done: no more files are
+	// dispatched and workers drop their pending results.
+	ctx context.Context
+
+	jobs        int
+	preserveEOL bool
+	overrides   map[string][]byte     // absolute path -> contents, see absOverrides
+	fsys        fs.FS                 // if set, files are read from fsys
+	schedule    string                // "size" dispatches the largest files first
+	atLine      int                   // if positive, run the query on the smallest node containing this line
+	strictParse bool                  // skip files whose tree has syntax errors, reporting them to diags
+	keep        func(QueryMatch) bool // if set, matches it rejects are dropped as the query runs
+	maxMatches  int                   // if positive, stop querying a file at this many matches, reporting it to diags
+	diags       *diagnostics
 }
 
 // runWorkers is a generic worker pool that processes files concurrently.
+// It collects every result; see streamWorkers to consume them as they arrive.
 // The process function is called for each file and should return a slice of results to emit.
+// With cfg.queries set, language and query may be nil: files are then parsed
+// and queried by their own Language.
 func runWorkers[R any](
 	language Language,
 	query *query,
 	files []FileJob,
-	jobs int,
+	cfg workerConfig,
 	process func(job FileJob, matches []QueryMatch, source []byte) []R,
 ) []R {
+	var allResults []R
+	streamWorkers(language, query, files, cfg, process, func(r R) {
+		allResults = append(allResults, r)
+	})
+	return allResults
+}
+
+// streamWorkers is runWorkers with results handed to emit, on the calling
+// goroutine, as they are produced.
+func streamWorkers[R any](
+	language Language,
+	query *query,
+	files []FileJob,
+	cfg workerConfig,
+	process func(job FileJob, matches []QueryMatch, source []byte) []R,
+	emit func(R),
+) {
+	streamTreeWorkers(language, query, files, cfg, func(job FileJob, _ *sitter.Node, matches []QueryMatch, source []byte) []R {
+		return process(job, matches, source)
+	}, emit)
+}
+
+// streamTreeWorkers is streamWorkers for process functions that also need
+// the root node the query ran on.
+func streamTreeWorkers[R any](
+	language Language,
+	query *query,
+	files []FileJob,
+	cfg workerConfig,
+	process func(job FileJob, root *sitter.Node, matches []QueryMatch, source []byte) []R,
+	emit func(R),
+) {
+	ctx := cfg.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	results := make(chan R, 128)
 	jobQueue := make(chan FileJob, 128)
 	var wg sync.WaitGroup
 
-	workerCount := min(max(jobs, 1), len(files))
+	workerCount := min(max(cfg.jobs, 1), len(files))
 	worker := func() {
 		defer wg.Done()
-		p := newParser(language)
-		for job := range jobQueue {
+		parsers := make(map[string]*parser) // by language name
+		for {
+			var job FileJob
+			select {
+			case <-ctx.Done():
+				return
+			case next, ok := <-jobQueue:
+				if !ok {
+					return
+				}
+				job = next
+			}
+			language, query := language, query
+			if job.Language != nil && cfg.queries != nil {
+				language, query = job.Language, cfg.queries.get(job.Language, job.DisplayPath, cfg.diags)
+			}
+			if language == nil || query == nil {
+				continue
+			}
+			p, ok := parsers[language.Name()]
+			if !ok {
+				p = newParser(language)
+				p.preserveEOL = cfg.preserveEOL
+				p.overrides = cfg.overrides
+				p.fsys = cfg.fsys
+				parsers[language.Name()] = p
+			}
 			tree, source, err := p.parseFile(job.AbsPath)
 			if err != nil {
 				continue
 			}
-			matches := query.run(tree, source, job.DisplayPath)
-			items := process(job, matches, source)
+			root := tree.RootNode()
+			if cfg.strictParse && hasSyntaxErrors(root) {
+				cfg.diags.add(Diagnostic{
+					File:    job.DisplayPath,
+					Kind:    "parse_error",
+					Message: "skipped: file has syntax errors",
+				})
+				continue
+			}
+			if cfg.atLine > 0 {
+				if root = nodeAtLine(root, source, cfg.atLine); root == nil {
+					continue
+				}
+			}
+			matches, capped := query.runLimited(root, source, job.DisplayPath, cfg.keep, cfg.maxMatches)
+			if capped {
+				cfg.diags.add(Diagnostic{
+					File:    job.DisplayPath,
+					Kind:    "capped",
+					Message: fmt.Sprintf("first %d matches kept", cfg.maxMatches),
+				})
+			}
+			items := process(job, root, matches, source)
 			for _, item := range items {
-				results <- item
+				select {
+				case results <- item:
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}
@@ -266,11 +796,19 @@ func runWorkers[R any](
 		go worker()
 	}
 
+	if cfg.schedule == "size" {
+		files = largestFirst(files)
+	}
+
 	go func() {
+		defer close(jobQueue)
 		for _, f := range files {
-			jobQueue <- f
+			select {
+			case jobQueue <- f:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(jobQueue)
 	}()
 
 	go func() {
@@ -278,71 +816,325 @@ func runWorkers[R any](
 		close(results)
 	}()
 
-	var allResults []R
 	for result := range results {
-		allResults = append(allResults, result)
+		emit(result)
 	}
-
-	return allResults
 }
 
-// Worker pool for Query
-func runQueryWorkers(language Language, query *query, files []FileJob, jobs int) []QueryMatch {
-	return runWorkers(language, query, files, jobs, func(_ FileJob, matches []QueryMatch, _ []byte) []QueryMatch {
-		return matches
+// largestFirst returns a copy of files sorted by size, largest first, so
+// that big files start early instead of holding up the tail of a run.
+func largestFirst(files []FileJob) []FileJob {
+	sorted := slices.Clone(files)
+	slices.SortStableFunc(sorted, func(a, b FileJob) int {
+		return cmp.Compare(b.Size, a.Size)
 	})
+	return sorted
 }
 
-// Worker pool for Symbols
-func runSymbolsWorkers(
+// validSchedule reports an unknown Schedule option.
+func validSchedule(schedule string) error {
+	switch schedule {
+	case "", "fifo", "size":
+		return nil
+	}
+	return fmt.Errorf("invalid schedule %q: want fifo or size", schedule)
+}
+
+// Worker pool for Query
+func streamQueryWorkers(
 	language Language,
 	query *query,
 	files []FileJob,
-	jobs int,
-	visibility string,
-	includeSource bool,
-	maxSourceLines int,
-) []SymbolsResult {
-	return runWorkers(language, query, files, jobs, func(job FileJob, matches []QueryMatch, source []byte) []SymbolsResult {
-		symbols := extractSymbols(matches, visibility, includeSource, maxSourceLines)
+	cfg workerConfig,
+	pattern *int,
+	children childFilter,
+	names nameFilter,
+	maxPerFile int,
+	contextBytes int,
+	emit func(QueryMatch),
+) {
+	if pattern != nil || children.active() || names.active() {
+		cfg.keep = func(m QueryMatch) bool {
+			return (pattern == nil || m.Pattern == *pattern) && children.keep(m) && names.keep(m)
+		}
+	}
+	cfg.maxMatches = maxPerFile
+	streamWorkers(language, query, files, cfg, func(job FileJob, matches []QueryMatch, source []byte) []QueryMatch {
+		if contextBytes > 0 {
+			for i := range matches {
+				for j := range matches[i].Captures {
+					c := &matches[i].Captures[j]
+					c.Context = byteContext(source, c.startByte, c.endByte, contextBytes)
+				}
+			}
+		}
+		// Drop node references so returned matches don't keep trees alive.
+		for i := range matches {
+			for j := range matches[i].Captures {
+				matches[i].Captures[j].node = nil
+			}
+		}
+		return matches
+	}, emit)
+}
+
+// childFilter selects matches by the named children of their captured nodes.
+type childFilter struct {
+	require string // keep matches with a capture that has a named child of this type
+	forbid  string // drop matches with a capture that has a named child of this type
+}
+
+func (f childFilter) active() bool {
+	return f.require != "" || f.forbid != ""
+}
+
+func (f childFilter) keep(m QueryMatch) bool {
+	if f.require != "" && !slices.ContainsFunc(m.Captures, func(c CaptureResult) bool {
+		return hasNamedChild(c.node, f.require)
+	}) {
+		return false
+	}
+	if f.forbid != "" && slices.ContainsFunc(m.Captures, func(c CaptureResult) bool {
+		return hasNamedChild(c.node, f.forbid)
+	}) {
+		return false
+	}
+	return true
+}
+
+// hasNamedChild reports whether n has a direct named child of the given type.
+func hasNamedChild(n *sitter.Node, nodeType string) bool {
+	if n == nil {
+		return false
+	}
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		if n.NamedChild(i).Type() == nodeType {
+			return true
+		}
+	}
+	return false
+}
+
+// byteContext returns source[start-n:end+n], clamped to the file bounds and
+// shrunk so that it never splits a multibyte rune.
+func byteContext(source []byte, start, end, n int) string {
+	from := max(start-n, 0)
+	to := min(end+n, len(source))
+	for from < start && !utf8.RuneStart(source[from]) {
+		from++
+	}
+	for to > end && to < len(source) && !utf8.RuneStart(source[to]) {
+		to--
+	}
+	return string(source[from:to])
+}
+
+// symbolsQuery returns the query Symbols runs on files of language.
+func symbolsQuery(language Language, opts SymbolsOptions) string {
+	queryStr := language.SymbolsQuery()
+	if opts.WithDoc || opts.Documented != "all" {
+		queryStr = commentsQuery(language)
+	}
+	if opts.Injections {
+		queryStr += injectionsQuery(language)
+	}
+	return queryStr
+}
+
+// Worker pool for Symbols
+func runSymbolsWorkers(ctx context.Context, language Language, query *query, files []FileJob, opts SymbolsOptions, changed changedLines, diags *diagnostics) []SymbolsResult {
+	return runWorkers(language, query, files, symbolsWorkerConfig(ctx, language, opts, diags), func(job FileJob, matches []QueryMatch, source []byte) []SymbolsResult {
+		symbols := changed.filterSymbols(job.AbsPath, fileSymbols(matches, source, jobSymbolsOptions(job, opts)), matches)
 		if len(symbols) > 0 {
-			return []SymbolsResult{{
+			result := SymbolsResult{
 				File:    job.DisplayPath,
 				Symbols: symbols,
-			}}
+			}
+			if opts.WithModTime {
+				result.ModTime = job.modTime()
+			}
+			return []SymbolsResult{result}
 		}
 		return nil
 	})
 }
 
+// symbolsWorkerConfig returns the worker configuration of Symbols. A nil
+// language has each file searched with its own language's query.
+func symbolsWorkerConfig(ctx context.Context, language Language, opts SymbolsOptions, diags *diagnostics) workerConfig {
+	cfg := workerConfig{
+		ctx:         ctx,
+		jobs:        opts.Jobs,
+		preserveEOL: opts.PreserveEOL,
+		overrides:   absOverrides(opts.FS, opts.FileOverrides),
+		fsys:        opts.FS,
+		schedule:    opts.Schedule,
+		strictParse: opts.StrictParse,
+		diags:       diags,
+	}
+	if language == nil {
+		cfg.queries = newLanguageQueries(func(language Language) string {
+			return symbolsQuery(language, opts)
+		})
+	}
+	return cfg
+}
+
+// jobSymbolsOptions returns opts with Language set to the language job is
+// parsed with, which varies by file when it is auto-detected.
+func jobSymbolsOptions(job FileJob, opts SymbolsOptions) SymbolsOptions {
+	if job.Language != nil {
+		opts.Language = job.Language.Name()
+	}
+	return opts
+}
+
+// fileSymbols extracts the symbols of one file's matches.
+func fileSymbols(matches []QueryMatch, source []byte, opts SymbolsOptions) []Symbol {
+	symbols := extractSymbols(matches, source, opts)
+	if opts.Injections {
+		symbols = append(symbols, injectedSymbols(matches, source, opts)...)
+	}
+	if opts.OutermostOnly {
+		symbols = outermostSymbols(symbols, matches)
+	}
+	if opts.QualifyNames {
+		qualifySymbols(matches, symbols)
+	}
+	return symbols
+}
+
+// outermostSymbols drops symbols whose declaration lies inside another
+// symbol's declaration, such as types declared in a function body, and
+// repeated entries for the same name and span. Symbols declared together
+// (var a, b = 1, 2) share a span and are all kept. Order is preserved.
+func outermostSymbols(symbols []Symbol, matches []QueryMatch) []Symbol {
+	decls := declsByName(matches)
+	ranges := make([]Range, len(symbols))
+	order := make([]int, len(symbols))
+	for i, sym := range symbols {
+		ranges[i] = declRange(sym, decls)
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		ra, rb := ranges[a], ranges[b]
+		return cmp.Or(comparePositions(ra.Start, rb.Start), comparePositions(rb.End, ra.End))
+	})
+
+	// Sorted by start, a symbol is nested if it ends within the furthest
+	// reaching span seen so far.
+	drop := make([]bool, len(symbols))
+	outer := -1
+	for _, i := range order {
+		if outer >= 0 && !positionBefore(ranges[outer].End, ranges[i].End) {
+			if ranges[i] != ranges[outer] || symbols[i].Name == symbols[outer].Name {
+				drop[i] = true
+				continue
+			}
+		}
+		if outer < 0 || positionBefore(ranges[outer].End, ranges[i].End) {
+			outer = i
+		}
+	}
+
+	var kept []Symbol
+	for i, sym := range symbols {
+		if !drop[i] {
+			kept = append(kept, sym)
+		}
+	}
+	return kept
+}
+
+// declRange returns the range of sym's whole declaration, looked up in decls
+// (see declsByName), or sym.Range if it has none.
+func declRange(sym Symbol, decls map[Position]CaptureResult) Range {
+	if decl, ok := decls[sym.Range.Start]; ok {
+		return decl.Range
+	}
+	return sym.Range
+}
+
 // Worker pool for Refs
 func runRefsWorkers(
 	language Language,
 	query *query,
 	files []FileJob,
-	jobs int,
-	symbolName string,
-	includeContext bool,
+	cfg workerConfig,
+	opts RefsOptions,
+	changed changedLines,
 ) []Reference {
-	return runWorkers(language, query, files, jobs, func(job FileJob, matches []QueryMatch, source []byte) []Reference {
-		return findReferences(matches, source, symbolName, includeContext)
+	allowed := newRefNodeTypes(opts.RefNodeTypes)
+	return runWorkers(language, query, files, cfg, func(job FileJob, matches []QueryMatch, source []byte) []Reference {
+		refs := findReferences(matches, source, opts, allowed)
+		if opts.ByPackage {
+			var pkg string
+			for _, match := range matches {
+				if c, ok := findCapture(match, "package"); ok {
+					pkg = c.Text
+				}
+			}
+			for i := range refs {
+				refs[i].Package = pkg
+			}
+		}
+		if changed == nil {
+			return refs
+		}
+		var kept []Reference
+		for _, ref := range refs {
+			if changed.touches(job.AbsPath, ref.Position.Line, ref.Position.Line) {
+				kept = append(kept, ref)
+			}
+		}
+		return kept
 	})
 }
 
 // Symbol extraction logic
-func extractSymbols(
-	matches []QueryMatch, visibility string, includeSource bool, maxSourceLines int,
-) []Symbol {
+func extractSymbols(matches []QueryMatch, source []byte, opts SymbolsOptions) []Symbol {
 	var symbols []Symbol
+	var blocks []CaptureResult // enclosing decl block per symbol, for GroupDeclBlocks
+
+	var embeds map[string][]string
+	if opts.PromoteEmbedded {
+		embeds = collectEmbeds(matches)
+	}
+
+	iotaValues := make(map[int]map[Position]string) // by const block start byte
+
+	var docs map[int]string // by declaration start line
+	if opts.WithDoc || (opts.Documented != "" && opts.Documented != "all") {
+		_, docs = classifyComments(matches, source)
+	}
+
+	visibility := visibilityFunc(Get(opts.Language), matches)
+
+	// Cached snippets are content-addressed, so they get no header.
+	location := ""
+	if opts.SourceWithLocation && opts.SnippetCache == "" && len(matches) > 0 {
+		location = lineComment(Get(opts.Language)) + " " + matches[0].File
+	}
 
 	for _, match := range matches {
-		sym := parseSymbolFromMatch(match, includeSource, maxSourceLines)
+		src := sourceOptions{include: opts.IncludeSource, maxLines: opts.MaxSourceLines, dedent: opts.Dedent, body: opts.WithBody, location: location}
+		sym := parseSymbolFromMatch(match, src)
 		if sym == nil {
 			continue
 		}
+		if visibility != nil {
+			sym.Visibility = visibility(*sym)
+		}
+
+		if sym.Kind == "closure" {
+			if !opts.IncludeAnonymous {
+				continue
+			}
+			sym.Enclosing = enclosingDecl(matches, sym.Range.Start)
+		}
 
 		// Filter by visibility
-		switch visibility {
+		switch opts.Visibility {
 		case "public":
 			if sym.Visibility != "public" {
 				continue
@@ -353,13 +1145,120 @@ func extractSymbols(
 			}
 		}
 
+		if docs != nil {
+			decl, _ := declCapture(match)
+			doc := docs[decl.Range.Start.Line]
+			if (opts.Documented == "documented" && doc == "") || (opts.Documented == "undocumented" && doc != "") {
+				continue
+			}
+			if opts.WithDoc {
+				sym.Doc = doc
+			}
+		}
+
+		if sym.Kind == "struct" {
+			sym.Embeds = embeds[sym.Name]
+			if opts.StructLayout {
+				def, _ := findCapture(match, "type_def")
+				sym.LayoutHint = structLayout(def)
+			}
+		}
+
+		if sym.Kind == "function" || sym.Kind == "method" || sym.Kind == "closure" {
+			if opts.WithArity {
+				params, results := countArity(match)
+				sym.NumParams, sym.NumResults = &params, &results
+			}
+			sym.ReturnsError = returnsError(match)
+		}
+		if opts.ReturnsError && !sym.ReturnsError {
+			continue
+		}
+		if opts.WithDirectives {
+			sym.Directives = symbolDirectives(match, source)
+		}
+
+		block, _ := findCapture(match, "decl_block")
+		if opts.ResolveIota && sym.Kind == "const" {
+			values, ok := iotaValues[block.startByte]
+			if !ok {
+				values = resolveIota(block)
+				iotaValues[block.startByte] = values
+			}
+			sym.Value = values[sym.Range.Start]
+		}
+
+		if opts.SymbolHook != nil && !opts.SymbolHook(sym) {
+			continue
+		}
 		symbols = append(symbols, *sym)
+		blocks = append(blocks, block)
+	}
+
+	src := sourceOptions{include: opts.IncludeSource, maxLines: opts.MaxSourceLines, dedent: opts.Dedent, location: location}
+	if opts.DetectEnums {
+		symbols, blocks = groupEnums(symbols, blocks, src)
+	}
+	if opts.GroupDeclBlocks {
+		symbols = groupDeclBlocks(symbols, blocks, src)
 	}
 
 	return symbols
 }
 
-func parseSymbolFromMatch(match QueryMatch, includeSource bool, maxSourceLines int) *Symbol {
+// groupDeclBlocks replaces consecutive const or var symbols declared in the
+// same parenthesized block with a single "const_block" or "var_block" symbol
+// whose Members are the individual specs. The block is public if any member is.
+func groupDeclBlocks(symbols []Symbol, blocks []CaptureResult, src sourceOptions) []Symbol {
+	var grouped []Symbol
+	for i := 0; i < len(symbols); {
+		block := blocks[i]
+		if block.Name == "" || !isParenthesizedBlock(block.Text) {
+			grouped = append(grouped, symbols[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(blocks) && blocks[j].Name != "" && blocks[j].Range == block.Range {
+			j++
+		}
+
+		members := append([]Symbol(nil), symbols[i:j]...)
+		names := make([]string, len(members))
+		visibility := "private"
+		for k, m := range members {
+			names[k] = m.Name
+			if m.Visibility == "public" {
+				visibility = "public"
+			}
+		}
+
+		sym := Symbol{
+			Name:       strings.Join(names, ", "),
+			Kind:       members[0].Kind + "_block",
+			Visibility: visibility,
+			File:       members[0].File,
+			Range:      block.Range,
+			Members:    members,
+		}
+		if src.include {
+			sym.Source = src.snippet(block)
+		}
+		grouped = append(grouped, sym)
+		i = j
+	}
+	return grouped
+}
+
+// isParenthesizedBlock reports whether a const or var declaration uses the
+// grouped "const ( ... )" form.
+func isParenthesizedBlock(decl string) bool {
+	_, rest, _ := strings.Cut(decl, " ")
+	return strings.HasPrefix(strings.TrimSpace(rest), "(")
+}
+
+func parseSymbolFromMatch(match QueryMatch, src sourceOptions) *Symbol {
 	captures := make(map[string]CaptureResult)
 	for _, c := range match.Captures {
 		captures[c.Name] = c
@@ -397,15 +1296,36 @@ func parseSymbolFromMatch(match QueryMatch, includeSource bool, maxSourceLines i
 		}
 		if recv, ok := captures["receiver"]; ok {
 			sym.Receiver = extractReceiverType(recv.Text)
+			sym.ReceiverPointer = isPointerReceiver(recv)
+		} else if class, ok := captures["class_name"]; ok {
+			sym.Receiver = class.Text
+		} else if impl, ok := captures["impl_type"]; ok {
+			sym.Receiver = impl.Text
+			if trait, ok := captures["impl_trait"]; ok {
+				sym.Receiver += " for " + trait.Text
+			}
+			if isAssociatedFunction(captures["params"]) {
+				sym.Kind = "function"
+			}
 		}
 		sym.Signature = buildFuncSignature(captures)
+	} else if closure, ok := captures["closure"]; ok {
+		sym.Kind = "closure"
+		sym.Name = fmt.Sprintf("func@%d:%d", closure.Range.Start.Line, closure.Range.Start.Column)
+		sym.Range = closure.Range
+		sym.Signature = buildFuncSignature(captures)
 	} else if typeDef, ok := captures["type"]; ok {
 		if typeSpec, ok := captures["type_def"]; ok {
-			if strings.HasPrefix(typeSpec.NodeType, "struct") {
+			switch {
+			case strings.HasPrefix(typeSpec.NodeType, "struct"):
 				sym.Kind = "struct"
-			} else if strings.HasPrefix(typeSpec.NodeType, "interface") {
+			case strings.HasPrefix(typeSpec.NodeType, "interface"):
 				sym.Kind = "interface"
-			} else {
+			case strings.HasPrefix(typeSpec.NodeType, "enum"):
+				sym.Kind = "enum"
+			case strings.HasPrefix(typeSpec.NodeType, "trait"):
+				sym.Kind = "trait"
+			default:
 				sym.Kind = "type"
 			}
 		} else {
@@ -416,6 +1336,33 @@ func parseSymbolFromMatch(match QueryMatch, includeSource bool, maxSourceLines i
 			sym.Range = name.Range
 		}
 		sym.Range = typeDef.Range
+	} else if _, ok := captures["class"]; ok {
+		sym.Kind = "class"
+		if name, ok := captures["name"]; ok {
+			sym.Name = name.Text
+			sym.Range = name.Range
+		}
+	} else if _, ok := captures["enum"]; ok {
+		sym.Kind = "enum"
+		if name, ok := captures["name"]; ok {
+			sym.Name = name.Text
+			sym.Range = name.Range
+		}
+	} else if _, ok := captures["field"]; ok {
+		sym.Kind = "field"
+		if name, ok := captures["name"]; ok {
+			sym.Name = name.Text
+			sym.Range = name.Range
+		}
+		if class, ok := captures["class_name"]; ok {
+			sym.Receiver = class.Text
+		}
+	} else if decl, ok := schemaObjectCapture(match); ok {
+		sym.Kind = decl.Name
+		if name, ok := captures["name"]; ok {
+			sym.Name = name.Text
+		}
+		sym.Range = decl.Range
 	} else {
 		return nil
 	}
@@ -427,22 +1374,146 @@ func parseSymbolFromMatch(match QueryMatch, includeSource bool, maxSourceLines i
 	// Determine visibility
 	sym.Visibility = getVisibility(sym.Name)
 
+	if typeParams, ok := captures["type_params"]; ok {
+		sym.TypeParams = typeParams.Text
+	}
+
 	// Include source if requested
-	if includeSource {
+	if src.include {
 		for _, c := range match.Captures {
 			// Find the outermost capture (function, method, type, const, var)
-			if c.Name == "function" || c.Name == "method" || c.Name == "type" || c.Name == "const" || c.Name == "var" {
-				sym.Source = truncateSource(c.Text, maxSourceLines)
+			if c.Name == "function" || c.Name == "method" || c.Name == "type" || c.Name == "const" || c.Name == "var" || c.Name == "closure" || c.Name == "class" || c.Name == "enum" || c.Name == "field" || slices.Contains(sqlObjectKinds, c.Name) {
+				sym.Source = src.snippet(c)
 				sym.Range = c.Range
 				break
 			}
 		}
 	}
 
+	if body, ok := captures["body"]; ok && src.body {
+		sym.Body = body.Text
+		if src.dedent {
+			sym.Body = dedentSource(sym.Body, body.Range.Start.Column-1)
+		}
+	}
+
 	sym.File = match.File
 	return &sym
 }
 
+// enclosingDecl returns the name of the innermost function, method, var or
+// const declaration whose source contains pos, or "" if there is none.
+// Methods are named Receiver.Name.
+func enclosingDecl(matches []QueryMatch, pos Position) string {
+	var best string
+	var bestStart Position
+	for _, match := range matches {
+		var outer, name CaptureResult
+		var receiver string
+		for _, c := range match.Captures {
+			switch c.Name {
+			case "function", "method", "var", "const":
+				outer = c
+			case "name":
+				name = c
+			case "receiver":
+				receiver = extractReceiverType(c.Text)
+			}
+		}
+		if outer.Name == "" || name.Text == "" || !rangeContains(outer.Range, pos) {
+			continue
+		}
+		if best != "" && positionBefore(outer.Range.Start, bestStart) {
+			continue
+		}
+		best, bestStart = name.Text, outer.Range.Start
+		if outer.Name == "method" && receiver != "" {
+			best = receiver + "." + name.Text
+		}
+	}
+	return best
+}
+
+// enclosingSymbol walks up from n to the innermost function or method
+// declaration containing it and returns its name, with methods named
+// Receiver.Name as in enclosingDecl. Outside functions it returns the first
+// name of the package-level var or const spec containing n, or "".
+func enclosingSymbol(n *sitter.Node, source []byte) string {
+	var spec string
+	for ; n != nil; n = n.Parent() {
+		switch n.Type() {
+		case "function_declaration":
+			return n.ChildByFieldName("name").Content(source)
+		case "method_declaration":
+			name := n.ChildByFieldName("name").Content(source)
+			if recv := receiverTypeNode(n.ChildByFieldName("receiver")); recv != nil {
+				return recv.Content(source) + "." + name
+			}
+			return name
+		case "var_spec", "const_spec":
+			if name := n.ChildByFieldName("name"); name != nil && spec == "" {
+				spec = name.Content(source)
+			}
+		}
+	}
+	return spec
+}
+
+// isDeclarationName reports whether n is the name of a Go declaration, as
+// in func n() or var n int.
+func isDeclarationName(n *sitter.Node) bool {
+	if n == nil || n.Parent() == nil {
+		return false
+	}
+	parent := n.Parent()
+	switch parent.Type() {
+	case "function_declaration", "method_declaration", "type_spec", "type_alias", "const_spec", "var_spec":
+	default:
+		return false
+	}
+	for i := 0; i < int(parent.ChildCount()); i++ {
+		if parent.FieldNameForChild(i) == "name" && parent.Child(i).Equal(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// receiverTypeNode returns the type name node of a method's receiver
+// parameter list, without pointer or type arguments.
+func receiverTypeNode(receiver *sitter.Node) *sitter.Node {
+	if receiver == nil || receiver.NamedChildCount() == 0 {
+		return nil
+	}
+	typ := receiver.NamedChild(0).ChildByFieldName("type")
+	for typ != nil {
+		switch typ.Type() {
+		case "pointer_type":
+			typ = typ.NamedChild(0)
+		case "generic_type":
+			typ = typ.ChildByFieldName("type")
+		case "parenthesized_type":
+			typ = typ.NamedChild(0)
+		default:
+			return typ
+		}
+	}
+	return nil
+}
+
+func rangeContains(r Range, p Position) bool {
+	return !positionBefore(p, r.Start) && positionBefore(p, r.End)
+}
+
+func positionBefore(a, b Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+// comparePositions orders positions like cmp.Compare.
+func comparePositions(a, b Position) int {
+	return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+}
+
 func getVisibility(name string) string {
 	if len(name) == 0 {
 		return "private"
@@ -455,8 +1526,27 @@ func getVisibility(name string) string {
 }
 
 func buildFuncSignature(captures map[string]CaptureResult) string {
+	if params, ok := captures["params"]; ok && isJavaParams(params) {
+		return javaSignature(captures)
+	}
+
+	// The keyword and what precedes the result type depend on the
+	// language, told by its parameter list: Go's parameter_list, Rust's
+	// parameters, or JavaScript and TypeScript otherwise, whose
+	// type_annotation results include their colon.
+	keyword, arrow := "func", " "
+	if params, ok := captures["params"]; ok {
+		switch params.NodeType {
+		case "parameter_list":
+		case "parameters":
+			keyword, arrow = "fn", " -> "
+		default:
+			keyword, arrow = "function", ""
+		}
+	}
+
 	var sb strings.Builder
-	sb.WriteString("func")
+	sb.WriteString(keyword)
 
 	if recv, ok := captures["receiver"]; ok {
 		sb.WriteString(" ")
@@ -468,18 +1558,114 @@ func buildFuncSignature(captures map[string]CaptureResult) string {
 		sb.WriteString(name.Text)
 	}
 
+	if typeParams, ok := captures["type_params"]; ok {
+		sb.WriteString(typeParams.Text)
+	}
+
 	if params, ok := captures["params"]; ok {
-		sb.WriteString(params.Text)
+		if params.NodeType == "identifier" {
+			// The unparenthesized parameter of a JavaScript arrow function.
+			sb.WriteString("(" + params.Text + ")")
+		} else {
+			sb.WriteString(params.Text)
+		}
 	}
 
 	if result, ok := captures["result"]; ok {
-		sb.WriteString(" ")
+		sb.WriteString(arrow)
 		sb.WriteString(result.Text)
 	}
 
 	return sb.String()
 }
 
+// countArity returns the number of parameters and results declared by a
+// function or method match. Grouped parameters like "a, b int" count once
+// per name.
+func countArity(match QueryMatch) (params, results int) {
+	for _, c := range match.Captures {
+		switch c.Name {
+		case "params":
+			params = len(splitTopLevel(trimParens(c.Text)))
+		case "result":
+			if c.NodeType == "parameter_list" {
+				results = len(splitTopLevel(trimParens(c.Text)))
+			} else {
+				results = 1
+			}
+		}
+	}
+	return params, results
+}
+
+// returnsError reports whether the last result of a function match is of
+// type error, as in func() error or func() (n int, err error).
+func returnsError(match QueryMatch) bool {
+	result, ok := findCapture(match, "result")
+	if !ok || result.node == nil {
+		return false
+	}
+	last := result.node
+	if last.Type() == "parameter_list" {
+		n := int(last.NamedChildCount())
+		if n == 0 {
+			return false
+		}
+		if last = last.NamedChild(n - 1).ChildByFieldName("type"); last == nil {
+			return false
+		}
+	}
+	return last.Type() == "type_identifier" && captureNodeText(result, last) == "error"
+}
+
+// captureNodeText returns the source of n, a node within capture c, sliced
+// from the capture's text.
+func captureNodeText(c CaptureResult, n *sitter.Node) string {
+	if n == nil {
+		return ""
+	}
+	start, end := int(n.StartByte())-c.startByte, int(n.EndByte())-c.startByte
+	if start < 0 || end > len(c.Text) || start > end {
+		return ""
+	}
+	return c.Text[start:end]
+}
+
+func trimParens(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "(")
+	return strings.TrimSuffix(s, ")")
+}
+
+// splitTopLevel splits s on commas that are not nested inside brackets,
+// dropping empty entries (e.g. from a trailing comma).
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+
+	nonEmpty := parts[:0]
+	for _, p := range parts {
+		if strings.TrimSpace(p) != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return nonEmpty
+}
+
 func extractReceiverType(receiver string) string {
 	// Extract type from receiver like "(r *MyType)" -> "MyType"
 	receiver = strings.TrimPrefix(receiver, "(")
@@ -495,6 +1681,82 @@ func extractReceiverType(receiver string) string {
 	return receiver
 }
 
+// isPointerReceiver reports whether a captured receiver, a Go receiver
+// parameter list like "(r *MyType)" or a receiver type like "*MyType", is
+// a pointer_type node.
+func isPointerReceiver(receiver CaptureResult) bool {
+	n := receiver.node
+	if n != nil && n.Type() == "parameter_list" {
+		param := n.NamedChild(0)
+		if param == nil {
+			return false
+		}
+		n = param.ChildByFieldName("type")
+	}
+	return n != nil && n.Type() == "pointer_type"
+}
+
+// sourceOptions controls how source snippets are rendered.
+type sourceOptions struct {
+	include  bool
+	maxLines int
+	dedent   bool
+	body     bool   // populate Symbol.Body from the @body capture
+	location string // if set, the comment marker and file of a "// file:line" header of snippets
+}
+
+// snippet returns the source of a capture, dedented if requested and
+// truncated to maxLines, after a location header if requested.
+func (o sourceOptions) snippet(c CaptureResult) string {
+	text := c.Text
+	if o.dedent {
+		text = dedentSource(text, c.Range.Start.Column-1)
+	}
+	text = truncateSource(text, o.maxLines)
+	if o.location != "" {
+		text = fmt.Sprintf("%s:%d\n%s", o.location, c.Range.Start.Line, text)
+	}
+	return text
+}
+
+// dedentSource removes the common leading whitespace from every line after
+// the first. The first line of a capture starts at the node itself, so its
+// original indentation is implied by the node's column; at most firstIndent
+// bytes are removed so lines never move left of where the node began.
+func dedentSource(source string, firstIndent int) string {
+	lines := strings.Split(source, "\n")
+	if len(lines) < 2 || firstIndent <= 0 {
+		return source
+	}
+
+	prefix := ""
+	found := false
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			prefix, found = indent, true
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > firstIndent {
+		prefix = prefix[:firstIndent]
+	}
+	if prefix == "" {
+		return source
+	}
+
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.TrimPrefix(lines[i], prefix)
+	}
+	return strings.Join(lines, "\n")
+}
+
 func truncateSource(source string, maxLines int) string {
 	if maxLines <= 0 {
 		return source
@@ -509,13 +1771,12 @@ func truncateSource(source string, maxLines int) string {
 }
 
 // Outline building logic
-func buildOutline(
-	file string, matches []QueryMatch, _ []byte, includeSource bool, maxSourceLines int,
-) FileOutline {
+func buildOutline(file string, matches []QueryMatch, root *sitter.Node, src sourceOptions) FileOutline {
 	outline := FileOutline{
 		File:    file,
 		Symbols: []Symbol{},
 		Imports: []ImportInfo{},
+		Errors:  errorRanges(root),
 	}
 
 	for _, match := range matches {
@@ -530,6 +1791,12 @@ func buildOutline(
 			continue
 		}
 
+		// go:embed directives
+		if comment, ok := captures["comment"]; ok {
+			outline.Embeds = append(outline.Embeds, parseEmbedDirective(comment.Text)...)
+			continue
+		}
+
 		// Imports
 		if path, ok := captures["path"]; ok {
 			imp := ImportInfo{
@@ -552,8 +1819,8 @@ func buildOutline(
 					Range:      captures["function"].Range,
 					Visibility: getVisibility(name.Text),
 				}
-				if includeSource {
-					sym.Source = truncateSource(captures["function"].Text, maxSourceLines)
+				if src.include {
+					sym.Source = src.snippet(captures["function"])
 				}
 				outline.Symbols = append(outline.Symbols, sym)
 			}
@@ -572,9 +1839,16 @@ func buildOutline(
 				}
 				if recv, ok := captures["receiver_type"]; ok {
 					sym.Receiver = strings.TrimPrefix(recv.Text, "*")
+					sym.ReceiverPointer = isPointerReceiver(recv)
+				}
+				if trait, ok := captures["impl_trait"]; ok {
+					sym.Receiver += " for " + trait.Text
 				}
-				if includeSource {
-					sym.Source = truncateSource(captures["method"].Text, maxSourceLines)
+				if params, ok := captures["params"]; ok && isAssociatedFunction(params) {
+					sym.Kind = "function"
+				}
+				if src.include {
+					sym.Source = src.snippet(captures["method"])
 				}
 				outline.Symbols = append(outline.Symbols, sym)
 			}
@@ -591,8 +1865,62 @@ func buildOutline(
 					Range:      captures["struct"].Range,
 					Visibility: getVisibility(name.Text),
 				}
-				if includeSource {
-					sym.Source = truncateSource(captures["struct"].Text, maxSourceLines)
+				if src.include {
+					sym.Source = src.snippet(captures["struct"])
+				}
+				outline.Symbols = append(outline.Symbols, sym)
+			}
+			continue
+		}
+
+		// Classes
+		if _, ok := captures["class"]; ok {
+			if name, ok := captures["type_name"]; ok {
+				sym := Symbol{
+					Kind:       "class",
+					Name:       name.Text,
+					File:       file,
+					Range:      captures["class"].Range,
+					Visibility: getVisibility(name.Text),
+				}
+				if src.include {
+					sym.Source = src.snippet(captures["class"])
+				}
+				outline.Symbols = append(outline.Symbols, sym)
+			}
+			continue
+		}
+
+		// Enums
+		if _, ok := captures["enum"]; ok {
+			if name, ok := captures["type_name"]; ok {
+				sym := Symbol{
+					Kind:       "enum",
+					Name:       name.Text,
+					File:       file,
+					Range:      captures["enum"].Range,
+					Visibility: getVisibility(name.Text),
+				}
+				if src.include {
+					sym.Source = src.snippet(captures["enum"])
+				}
+				outline.Symbols = append(outline.Symbols, sym)
+			}
+			continue
+		}
+
+		// Traits
+		if _, ok := captures["trait"]; ok {
+			if name, ok := captures["type_name"]; ok {
+				sym := Symbol{
+					Kind:       "trait",
+					Name:       name.Text,
+					File:       file,
+					Range:      captures["trait"].Range,
+					Visibility: getVisibility(name.Text),
+				}
+				if src.include {
+					sym.Source = src.snippet(captures["trait"])
 				}
 				outline.Symbols = append(outline.Symbols, sym)
 			}
@@ -609,8 +1937,8 @@ func buildOutline(
 					Range:      captures["interface"].Range,
 					Visibility: getVisibility(name.Text),
 				}
-				if includeSource {
-					sym.Source = truncateSource(captures["interface"].Text, maxSourceLines)
+				if src.include {
+					sym.Source = src.snippet(captures["interface"])
 				}
 				outline.Symbols = append(outline.Symbols, sym)
 			}
@@ -628,8 +1956,8 @@ func buildOutline(
 						Range:      typeDecl.Range,
 						Visibility: getVisibility(name.Text),
 					}
-					if includeSource {
-						sym.Source = truncateSource(typeDecl.Text, maxSourceLines)
+					if src.include {
+						sym.Source = src.snippet(typeDecl)
 					}
 					outline.Symbols = append(outline.Symbols, sym)
 				}
@@ -647,8 +1975,8 @@ func buildOutline(
 					Range:      captures["const"].Range,
 					Visibility: getVisibility(name.Text),
 				}
-				if includeSource {
-					sym.Source = truncateSource(captures["const"].Text, maxSourceLines)
+				if src.include {
+					sym.Source = src.snippet(captures["const"])
 				}
 				outline.Symbols = append(outline.Symbols, sym)
 			}
@@ -665,8 +1993,8 @@ func buildOutline(
 					Range:      captures["var"].Range,
 					Visibility: getVisibility(name.Text),
 				}
-				if includeSource {
-					sym.Source = truncateSource(captures["var"].Text, maxSourceLines)
+				if src.include {
+					sym.Source = src.snippet(captures["var"])
 				}
 				outline.Symbols = append(outline.Symbols, sym)
 			}
@@ -677,17 +2005,49 @@ func buildOutline(
 	return outline
 }
 
+// parseEmbedDirective returns the patterns of a //go:embed comment, or nil
+// for any other comment. Quoted patterns have their quotes removed.
+func parseEmbedDirective(comment string) []string {
+	rest, ok := strings.CutPrefix(comment, "//go:embed")
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return nil
+	}
+
+	var patterns []string
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		var field string
+		if q := rest[0]; q == '"' || q == '`' {
+			end := strings.IndexByte(rest[1:], q)
+			if end < 0 {
+				end = len(rest) - 2
+			}
+			field, rest = rest[:end+2], rest[end+2:]
+			if unquoted, err := strconv.Unquote(field); err == nil {
+				field = unquoted
+			}
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			field, rest = rest[:end], rest[end:]
+		}
+		patterns = append(patterns, field)
+	}
+	return patterns
+}
+
 // Reference finding logic
 func findReferences(
-	matches []QueryMatch, source []byte, symbolName string, includeContext bool,
+	matches []QueryMatch, source []byte, opts RefsOptions, allowed refNodeTypes,
 ) []Reference {
 	var refs []Reference
-	lines := strings.Split(string(source), "\n")
+	symbolName := opts.Symbol
 
 	for _, match := range matches {
 		for _, capture := range match.Captures {
 			// Check if this capture matches the symbol we're looking for
-			if capture.Text != symbolName {
+			if capture.Text != symbolName || capture.Name == "package" || !allowed.allows(capture) {
 				continue
 			}
 
@@ -698,6 +2058,7 @@ func findReferences(
 					Line:   capture.Range.Start.Line,
 					Column: capture.Range.Start.Column,
 				},
+				declaration: isDeclarationName(capture.node),
 			}
 
 			// Determine reference kind based on capture name
@@ -715,10 +2076,21 @@ func findReferences(
 			}
 
 			// Add context if requested
-			if includeContext {
-				lineIdx := capture.Range.Start.Line - 1
-				if lineIdx >= 0 && lineIdx < len(lines) {
-					ref.Context = strings.TrimSpace(lines[lineIdx])
+			if opts.IncludeContext {
+				ref.Context = strings.TrimSpace(lineAround(source, capture.startByte))
+			}
+
+			if opts.IncludeOffsets {
+				ref.Offsets = &ByteRange{Start: capture.startByte, End: capture.endByte}
+			}
+
+			if opts.WithEnclosing || opts.WithID {
+				enclosing := enclosingSymbol(capture.node, source)
+				if opts.WithEnclosing {
+					ref.EnclosingSymbol = enclosing
+				}
+				if opts.WithID {
+					ref.ID = ref.File + "#" + enclosing + ":" + ref.Kind + ":" + symbolName
 				}
 			}
 
@@ -726,5 +2098,56 @@ func findReferences(
 		}
 	}
 
+	if opts.WithID {
+		numberReferenceIDs(refs)
+	}
 	return refs
 }
+
+// numberReferenceIDs appends ":N" to the ID of the Nth reference, N > 1,
+// sharing an ID in refs, counting in source order.
+func numberReferenceIDs(refs []Reference) {
+	order := make([]int, len(refs))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(i, j int) int {
+		return comparePositions(refs[i].Position, refs[j].Position)
+	})
+	seen := make(map[string]int)
+	for _, i := range order {
+		id := refs[i].ID
+		seen[id]++
+		if n := seen[id]; n > 1 {
+			refs[i].ID = id + ":" + strconv.Itoa(n)
+		}
+	}
+}
+
+// refNodeTypes is the set of node types, or parent/type pairs, that count as
+// references (see RefsOptions.RefNodeTypes). A nil set allows every capture.
+type refNodeTypes map[string]bool
+
+func newRefNodeTypes(types []string) refNodeTypes {
+	if len(types) == 0 {
+		return nil
+	}
+	allowed := make(refNodeTypes, len(types))
+	for _, t := range types {
+		allowed[t] = true
+	}
+	return allowed
+}
+
+// allows reports whether c's node type, alone or with its parent's, is in
+// the set.
+func (a refNodeTypes) allows(c CaptureResult) bool {
+	if a == nil || a[c.NodeType] {
+		return true
+	}
+	if c.node == nil {
+		return false
+	}
+	parent := c.node.Parent()
+	return parent != nil && a[parent.Type()+"/"+c.NodeType]
+}
@@ -0,0 +1,192 @@
+package tsq
+
+import (
+	"cmp"
+	"errors"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Semver impacts of public API changes, from most to least severe.
+const (
+	ImpactMajor = "major"
+	ImpactMinor = "minor"
+	ImpactPatch = "patch"
+)
+
+// APIChange is a change to one exported symbol between two revisions.
+type APIChange struct {
+	// Kind is "removed", "added" or "changed".
+	Kind string `json:"kind"`
+	// Impact is the semver impact of the change: removed and changed
+	// symbols are "major", added ones "minor".
+	Impact string `json:"impact"`
+	Dir    string `json:"dir"`
+	Name   string `json:"name"` // qualified name
+	// SymbolKind is the kind of the symbol, in the new revision unless it
+	// was removed.
+	SymbolKind string `json:"symbol_kind"`
+	Old        string `json:"old,omitempty"` // old signature or kind
+	New        string `json:"new,omitempty"` // new signature or kind
+}
+
+// APIDiffResult is the public API difference between two revisions.
+type APIDiffResult struct {
+	// Bump is the suggested version bump: "major" if any exported symbol
+	// was removed or changed, "minor" if any was added, else "patch".
+	Bump    string      `json:"bump"`
+	Changes []APIChange `json:"changes"`
+}
+
+// APIDiff compares the public API (see PublicAPI) of a directory at two git
+// revisions and classifies each difference by its semver impact. Symbols
+// are matched by package directory and qualified name; a symbol whose kind,
+// signature or type parameters differ is changed.
+func APIDiff(opts APIDiffOptions) (*APIDiffResult, error) {
+	if opts.OldRev == "" {
+		return nil, errors.New("old revision is required")
+	}
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+
+	oldAPI, err := revisionAPI(opts, opts.OldRev)
+	if err != nil {
+		return nil, err
+	}
+	newAPI, err := revisionAPI(opts, opts.NewRev)
+	if err != nil {
+		return nil, err
+	}
+	return diffAPIs(oldAPI, newAPI, opts.IgnoreFormatting), nil
+}
+
+// revisionAPI returns the public API of opts.Path at rev, or of the files on
+// disk if rev is empty.
+func revisionAPI(opts APIDiffOptions, rev string) ([]PackageAPI, error) {
+	apiOpts := PublicAPIOptions{
+		Language: opts.Language,
+		Path:     opts.Path,
+		Jobs:     opts.Jobs,
+		MaxBytes: opts.MaxBytes,
+	}
+	if rev == "" {
+		return PublicAPI(apiOpts)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "tsq-apidiff-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := gitExtractTree(opts.Path, rev, tmpDir); err != nil {
+		return nil, err
+	}
+	apiOpts.Path = tmpDir
+	return PublicAPI(apiOpts)
+}
+
+// diffAPIs classifies the differences between two public APIs. Changes are
+// ordered by directory and name. With ignoreFormatting, signatures that
+// differ only in formatting are unchanged.
+func diffAPIs(oldAPI, newAPI []PackageAPI, ignoreFormatting bool) *APIDiffResult {
+	oldSymbols := apiSymbols(oldAPI)
+	newSymbols := apiSymbols(newAPI)
+
+	changes := []APIChange{}
+	for key, old := range oldSymbols {
+		sym, ok := newSymbols[key]
+		switch {
+		case !ok:
+			changes = append(changes, APIChange{
+				Kind: "removed", Impact: ImpactMajor, Dir: key[0], Name: key[1],
+				SymbolKind: old.Kind, Old: apiDescription(old),
+			})
+		case !sameAPI(old, sym, ignoreFormatting):
+			changes = append(changes, APIChange{
+				Kind: "changed", Impact: ImpactMajor, Dir: key[0], Name: key[1],
+				SymbolKind: sym.Kind, Old: apiDescription(old), New: apiDescription(sym),
+			})
+		}
+	}
+	for key, sym := range newSymbols {
+		if _, ok := oldSymbols[key]; !ok {
+			changes = append(changes, APIChange{
+				Kind: "added", Impact: ImpactMinor, Dir: key[0], Name: key[1],
+				SymbolKind: sym.Kind, New: apiDescription(sym),
+			})
+		}
+	}
+	slices.SortFunc(changes, func(a, b APIChange) int {
+		return cmp.Or(cmp.Compare(a.Dir, b.Dir), cmp.Compare(a.Name, b.Name))
+	})
+
+	bump := ImpactPatch
+	for _, c := range changes {
+		if c.Impact == ImpactMajor {
+			bump = ImpactMajor
+			break
+		}
+		bump = ImpactMinor
+	}
+	return &APIDiffResult{Bump: bump, Changes: changes}
+}
+
+// apiSymbols indexes the symbols of an API by directory and qualified name.
+// Of symbols sharing a name, such as Rust methods of different trait impls,
+// the first is kept.
+func apiSymbols(api []PackageAPI) map[[2]string]Symbol {
+	symbols := make(map[[2]string]Symbol)
+	for _, pkg := range api {
+		for _, sym := range pkg.Symbols {
+			key := [2]string{pkg.Dir, sym.QualifiedName}
+			if _, ok := symbols[key]; !ok {
+				symbols[key] = sym
+			}
+		}
+	}
+	return symbols
+}
+
+// sameAPI reports whether two versions of a symbol present the same API.
+// If ignoreFormatting is set, signatures and type parameters are compared
+// after normalizeSignature, so reformatting a declaration doesn't change it.
+func sameAPI(a, b Symbol, ignoreFormatting bool) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	if ignoreFormatting {
+		return normalizeSignature(a.Signature) == normalizeSignature(b.Signature) &&
+			normalizeSignature(a.TypeParams) == normalizeSignature(b.TypeParams)
+	}
+	return a.Signature == b.Signature && a.TypeParams == b.TypeParams
+}
+
+// signatureListEnd matches the trailing comma gofmt requires when a
+// parameter or type parameter list is split over lines.
+var signatureListEnd = regexp.MustCompile(`,\s*([)\]])`)
+
+// signatureComma matches a comma and the spacing after it.
+var signatureComma = regexp.MustCompile(`,\s*`)
+
+// normalizeSignature collapses the formatting of a signature: runs of
+// whitespace, including newlines, become one space, commas are followed by
+// exactly one space, and spaces inside the brackets of lists and the
+// trailing comma of a multi-line list are dropped.
+func normalizeSignature(sig string) string {
+	sig = strings.Join(strings.Fields(sig), " ")
+	sig = signatureListEnd.ReplaceAllString(sig, "$1")
+	sig = signatureComma.ReplaceAllString(sig, ", ")
+	return strings.NewReplacer("( ", "(", " )", ")", "[ ", "[", " ]", "]").Replace(sig)
+}
+
+// apiDescription describes a symbol for an APIChange: its signature, or its
+// kind for symbols without one.
+func apiDescription(sym Symbol) string {
+	if sym.Signature != "" {
+		return sym.Signature
+	}
+	return sym.Kind
+}
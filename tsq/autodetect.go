@@ -0,0 +1,88 @@
+package tsq
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// AutoDetect is the Language option value that has Symbols, Refs and
+// Outline pick each file's language by its extension (see ByExtension),
+// so a mixed-language tree is analyzed in one pass. An empty Language means
+// the same for them. Files of no registered language are skipped.
+const AutoDetect = "auto"
+
+// resolveLanguage returns the registered language named name, or nil for
+// auto-detection.
+func resolveLanguage(name string) (Language, error) {
+	if name == "" || name == AutoDetect {
+		return nil, nil
+	}
+	language := Get(name)
+	if language == nil {
+		return nil, errors.New(name + " language not registered")
+	}
+	return language, nil
+}
+
+// fileLanguage returns the registered language of a file by its extension,
+// or nil. Grammars loaded by LoadGrammarDir are never detected: they have no
+// symbols, outline or refs queries.
+func fileLanguage(name string) Language {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" {
+		return nil
+	}
+	language := ByExtension(ext)
+	if _, ok := language.(*pluginLanguage); ok {
+		return nil
+	}
+	return language
+}
+
+// noLanguageError reports that a file named explicitly has no registered
+// language to auto-detect.
+func noLanguageError(file string) error {
+	ext := filepath.Ext(file)
+	if p, ok := ByExtension(strings.ToLower(ext)).(*pluginLanguage); ok {
+		return fmt.Errorf("%s: the %s grammar supports queries only", file, p.name)
+	}
+	return fmt.Errorf("%s: no registered language for %q files", file, ext)
+}
+
+// languageQueries compiles the query for the files of each language on
+// first use, for worker pools over files of several languages. It is shared
+// by all workers. A nil query means it failed to compile.
+type languageQueries struct {
+	source func(Language) string // query source for a language
+
+	mu      sync.Mutex
+	queries map[string]*query // by language name
+}
+
+func newLanguageQueries(source func(Language) string) *languageQueries {
+	return &languageQueries{source: source, queries: make(map[string]*query)}
+}
+
+// get returns the query for the files of language, or nil if it doesn't
+// compile. The first time it doesn't, a "query_error" diagnostic naming
+// file, the first file skipped, is added to diags.
+func (lq *languageQueries) get(language Language, file string, diags *diagnostics) *query {
+	lq.mu.Lock()
+	defer lq.mu.Unlock()
+	q, ok := lq.queries[language.Name()]
+	if !ok {
+		var err error
+		if q, err = newQuery(lq.source(language), language); err != nil {
+			diags.add(Diagnostic{
+				File:    file,
+				Kind:    "query_error",
+				Message: fmt.Sprintf("%s files skipped: %v", language.Name(), err),
+			})
+		}
+		lq.queries[language.Name()] = q
+	}
+	return q
+}
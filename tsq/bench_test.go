@@ -0,0 +1,123 @@
+package tsq
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Benchmarks for the hot paths over a generated corpus.
+// Run with: go test -bench . -benchmem ./tsq
+
+const benchCorpusFiles = 200
+
+// setupBenchCorpus creates a corpus in a temp dir and returns its path.
+func setupBenchCorpus(b *testing.B) string {
+	b.Helper()
+
+	tmpDir, err := os.MkdirTemp("", "tsq-bench-*")
+	require.NoError(b, err)
+	b.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	generateTestFiles(b, tmpDir, benchCorpusFiles, true)
+	return tmpDir
+}
+
+func BenchmarkScannerCollect(b *testing.B) {
+	dir := setupBenchCorpus(b)
+	sc := newScanner(scannerConfig{root: dir, language: Get("go"), maxBytes: 2 * 1024 * 1024})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		files, err := sc.collect()
+		require.NoError(b, err)
+		require.Len(b, files, benchCorpusFiles)
+	}
+}
+
+func BenchmarkParserParseFile(b *testing.B) {
+	dir := setupBenchCorpus(b)
+	p := newParser(Get("go"))
+	path := filepath.Join(dir, "file_1.go")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		_, _, err := p.parseFile(path)
+		require.NoError(b, err)
+	}
+}
+
+func BenchmarkQueryRun(b *testing.B) {
+	dir := setupBenchCorpus(b)
+	language := Get("go")
+	q, err := newQuery(language.SymbolsQuery(), language)
+	require.NoError(b, err)
+
+	tree, source, err := newParser(language).parseFile(filepath.Join(dir, "file_1.go"))
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		q.run(tree.RootNode(), source, "file_1.go")
+	}
+}
+
+func BenchmarkSymbols(b *testing.B) {
+	dir := setupBenchCorpus(b)
+
+	for _, jobs := range []int{1, 4} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				results, err := Symbols(SymbolsOptions{Path: dir, Jobs: jobs})
+				require.NoError(b, err)
+				require.Len(b, results, benchCorpusFiles)
+			}
+		})
+	}
+}
+
+func BenchmarkRefs(b *testing.B) {
+	dir := setupBenchCorpus(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		_, err := Refs(RefsOptions{Symbol: "Item1", Path: dir})
+		require.NoError(b, err)
+	}
+}
+
+// BenchmarkSchedule compares scan-order and largest-first dispatch on a
+// skewed corpus: a few files hundreds of times larger than the rest. With
+// scan order a big file picked up last leaves the other workers idle.
+func BenchmarkSchedule(b *testing.B) {
+	dir := setupBenchCorpus(b)
+	for i := range 4 {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "package testpkg\n\n")
+		for j := range 3000 {
+			fmt.Fprintf(&sb, "func Huge%d_%d(a, b int) int {\n\treturn a*%d + b\n}\n\n", i, j, j)
+		}
+		// Named to sort last so scan order dispatches them at the end.
+		path := filepath.Join(dir, fmt.Sprintf("zz_huge_%d.go", i))
+		require.NoError(b, os.WriteFile(path, []byte(sb.String()), 0644))
+	}
+
+	for _, schedule := range []string{"fifo", "size"} {
+		b.Run("schedule="+schedule, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				_, err := Symbols(SymbolsOptions{Path: dir, Jobs: 4, Schedule: schedule})
+				require.NoError(b, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package tsq
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SymbolChurn is how often a symbol's code changed in git history.
+type SymbolChurn struct {
+	Symbol      string `json:"symbol"` // Name, or Receiver.Name for methods
+	File        string `json:"file"`
+	Range       Range  `json:"range"`        // the declaration, whose lines are followed
+	CommitCount int    `json:"commit_count"` // commits that touched those lines, including the one adding them
+	LastChanged string `json:"last_changed"` // committer date of the latest of them, RFC3339 in UTC
+}
+
+// Churn counts the commits that touched each declaration of a symbol. The
+// lines of the declaration's current range are followed back through
+// history with git log -L, so edits moving them around don't break the
+// count. Ranges are those of the files on disk, and git follows those lines
+// in HEAD, so uncommitted edits above a declaration skew the result.
+// Results are ordered by file and position.
+func Churn(opts ChurnOptions) ([]SymbolChurn, error) {
+	if opts.Symbol == "" {
+		return nil, errors.New("symbol is required")
+	}
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+
+	// With IncludeSource, Range covers the whole declaration instead of
+	// its name.
+	results, err := Symbols(SymbolsOptions{
+		Language:       opts.Language,
+		Path:           opts.Path,
+		Jobs:           opts.Jobs,
+		MaxBytes:       opts.MaxBytes,
+		IncludeSource:  true,
+		MaxSourceLines: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	churn := []SymbolChurn{}
+	for _, r := range results {
+		for _, sym := range r.Symbols {
+			if sym.Name != opts.Symbol && declName(sym) != opts.Symbol {
+				continue
+			}
+			count, last, err := gitLineChurn(filepath.Join(opts.Path, filepath.FromSlash(r.File)), sym.Range.Start.Line, sym.Range.End.Line)
+			if err != nil {
+				return nil, err
+			}
+			churn = append(churn, SymbolChurn{
+				Symbol:      declName(sym),
+				File:        r.File,
+				Range:       sym.Range,
+				CommitCount: count,
+				LastChanged: last,
+			})
+		}
+	}
+
+	sort.SliceStable(churn, func(i, j int) bool {
+		return locationBefore(Location{churn[i].File, churn[i].Range.Start}, Location{churn[j].File, churn[j].Range.Start})
+	})
+	return churn, nil
+}
+
+// gitLineChurn returns the number of commits that touched lines start..end
+// of file and the committer date of the latest one.
+func gitLineChurn(file string, start, end int) (int, string, error) {
+	// Each commit is printed as a NUL-prefixed line; -s drops the patches.
+	cmd := exec.Command("git", "log", "-s", "--format=%x00%ct",
+		fmt.Sprintf("-L%d,%d:%s", start, end, filepath.Base(file)))
+	cmd.Dir = filepath.Dir(file)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return 0, "", fmt.Errorf("git log -L %s: %s", file, msg)
+	}
+
+	count, last := 0, ""
+	for _, line := range strings.Split(string(out), "\n") {
+		unix, ok := strings.CutPrefix(line, "\x00")
+		if !ok {
+			continue
+		}
+		if count == 0 {
+			sec, err := strconv.ParseInt(unix, 10, 64)
+			if err != nil {
+				return 0, "", fmt.Errorf("git log -L %s: invalid commit time %q", file, unix)
+			}
+			last = time.Unix(sec, 0).UTC().Format(time.RFC3339)
+		}
+		count++
+	}
+	return count, last, nil
+}
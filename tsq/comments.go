@@ -0,0 +1,284 @@
+package tsq
+
+import (
+	"errors"
+	"runtime"
+	"slices"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Comment is a source comment.
+type Comment struct {
+	File   string `json:"file"`
+	Kind   string `json:"kind"` // line, block, doc
+	Text   string `json:"text"`
+	Range  Range  `json:"range"`
+	Symbol string `json:"symbol,omitempty"` // documented declaration (doc) or enclosing declaration
+}
+
+// CommentsResult is the output format for comment extraction.
+type CommentsResult struct {
+	File     string    `json:"file"`
+	Comments []Comment `json:"comments"`
+}
+
+// commentsQuery extends a language's symbols query with a comment pattern so
+// comments and the declarations they document come from a single pass.
+func commentsQuery(language Language) string {
+	return language.SymbolsQuery() + "\n(comment) @comment\n"
+}
+
+// Comments lists the comments in code files. Comments that sit on their own
+// lines directly above a declaration are reported with kind "doc" and the
+// declaration's name; other comments carry the name of the declaration that
+// encloses them, if any.
+func Comments(opts CommentsOptions) ([]CommentsResult, error) {
+	language, files, q, err := prepareComments(&opts)
+	if err != nil || len(files) == 0 {
+		return []CommentsResult{}, err
+	}
+
+	cfg := workerConfig{jobs: opts.Jobs}
+	return runWorkers(language, q, files, cfg, func(job FileJob, matches []QueryMatch, source []byte) []CommentsResult {
+		comments, _ := classifyComments(matches, source)
+		if len(comments) == 0 {
+			return nil
+		}
+		return []CommentsResult{{File: job.DisplayPath, Comments: comments}}
+	}), nil
+}
+
+// Undocumented lists exported declarations that have no doc comment.
+// Methods on unexported types are not considered exported.
+func Undocumented(opts CommentsOptions) ([]SymbolsResult, error) {
+	language, files, q, err := prepareComments(&opts)
+	if err != nil || len(files) == 0 {
+		return []SymbolsResult{}, err
+	}
+
+	cfg := workerConfig{jobs: opts.Jobs}
+	return runWorkers(language, q, files, cfg, func(job FileJob, matches []QueryMatch, source []byte) []SymbolsResult {
+		_, docs := classifyComments(matches, source)
+
+		var symbols []Symbol
+		for _, match := range matches {
+			sym := parseSymbolFromMatch(match, sourceOptions{})
+			if sym == nil || sym.Kind == "closure" || sym.Visibility != "public" {
+				continue
+			}
+			if sym.Receiver != "" && getVisibility(sym.Receiver) != "public" {
+				continue
+			}
+			decl, _ := declCapture(match)
+			if docs[decl.Range.Start.Line] != "" {
+				continue
+			}
+			symbols = append(symbols, *sym)
+		}
+		if len(symbols) == 0 {
+			return nil
+		}
+		return []SymbolsResult{{File: job.DisplayPath, Symbols: symbols}}
+	}), nil
+}
+
+// prepareComments applies defaults and collects the files and query shared by
+// Comments and Undocumented.
+func prepareComments(opts *CommentsOptions) (Language, []FileJob, *query, error) {
+	if opts.Language == "" {
+		opts.Language = "go"
+	}
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.Jobs == 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+
+	language := Get(opts.Language)
+	if language == nil {
+		return nil, nil, nil, errors.New(opts.Language + " language not registered")
+	}
+
+	q, err := newQuery(commentsQuery(language), language)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var files []FileJob
+	if opts.File != "" {
+		sc := newScanner(scannerConfig{language: language})
+		job, err := sc.collectSingle(opts.File)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		files = []FileJob{job}
+	} else {
+		sc := newScanner(scannerConfig{
+			root:     opts.Path,
+			language: language,
+			maxBytes: opts.MaxBytes,
+		})
+		files, err = sc.collect()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return language, files, q, nil
+}
+
+// classifyComments turns the comment matches of a file into Comments and
+// returns the doc comments by the start line of the declaration they
+// document, as the comments' text (see commentText) joined by newlines.
+//
+// A run of comments on consecutive lines, each on a line of its own, is a doc
+// comment when the line after the run starts a declaration.
+func classifyComments(matches []QueryMatch, source []byte) ([]Comment, map[int]string) {
+	decls := make(map[int]string) // start line -> declaration name
+	for _, match := range matches {
+		if decl, ok := declCapture(match); ok && decl.Name != "closure" {
+			if sym := parseSymbolFromMatch(match, sourceOptions{}); sym != nil {
+				if _, seen := decls[decl.Range.Start.Line]; !seen {
+					decls[decl.Range.Start.Line] = declName(*sym)
+				}
+			}
+		}
+	}
+
+	lines := strings.Split(string(source), "\n")
+	ownLine := func(r Range) bool {
+		idx := r.Start.Line - 1
+		if idx < 0 || idx >= len(lines) {
+			return false
+		}
+		col := min(r.Start.Column-1, len(lines[idx]))
+		return strings.TrimSpace(lines[idx][:col]) == ""
+	}
+
+	var comments []Comment
+	for _, match := range matches {
+		c, ok := findCapture(match, "comment")
+		if !ok {
+			continue
+		}
+		kind := "line"
+		if strings.HasPrefix(c.Text, "/*") {
+			kind = "block"
+		}
+		comments = append(comments, Comment{
+			File:   match.File,
+			Kind:   kind,
+			Text:   c.Text,
+			Range:  c.Range,
+			Symbol: enclosingDecl(matches, c.Range.Start),
+		})
+	}
+
+	docs := make(map[int]string)
+	for start := 0; start < len(comments); {
+		// Find the run of own-line comments on consecutive lines
+		end := start
+		for end+1 < len(comments) &&
+			comments[end+1].Range.Start.Line == comments[end].Range.End.Line+1 &&
+			ownLine(comments[end+1].Range) {
+			end++
+		}
+
+		next := comments[end].Range.End.Line + 1
+		if name, ok := decls[next]; ok && ownLine(comments[start].Range) {
+			var doc []string
+			for i := start; i <= end; i++ {
+				comments[i].Kind = "doc"
+				comments[i].Symbol = name
+				doc = append(doc, commentText(comments[i].Text))
+			}
+			docs[next] = strings.Join(doc, "\n")
+		}
+		start = end + 1
+	}
+
+	return comments, docs
+}
+
+// commentText returns the text of a comment without its markers: the
+// slashes of a line comment and a space after them, or the delimiters of a
+// block comment, the leading space and asterisk of its lines, and the blank
+// lines they leave at its ends.
+func commentText(comment string) string {
+	if !strings.HasPrefix(comment, "/*") {
+		text := strings.TrimLeft(strings.TrimPrefix(comment, "//"), "/!")
+		return strings.TrimPrefix(text, " ")
+	}
+
+	body := strings.TrimSuffix(strings.TrimLeft(comment[2:], "*!"), "*/")
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(line, "*") {
+			line = strings.TrimPrefix(line[1:], " ")
+		}
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// symbolDirectives returns the //go: directive lines of the comments on the
+// lines directly above the declaration of a symbols match, in source order.
+// Specs without directives of their own get those of their const or var
+// declaration.
+func symbolDirectives(match QueryMatch, source []byte) []string {
+	decl, ok := declCapture(match)
+	if !ok || decl.node == nil {
+		return nil
+	}
+	directives := precedingDirectives(decl.node, source)
+	if block, ok := findCapture(match, "decl_block"); ok && directives == nil && block.node != nil {
+		directives = precedingDirectives(block.node, source)
+	}
+	return directives
+}
+
+// precedingDirectives returns the //go: directives among the comments that
+// precede n on consecutive lines.
+func precedingDirectives(n *sitter.Node, source []byte) []string {
+	var directives []string
+	row := n.StartPoint().Row
+	for prev := n.PrevSibling(); prev != nil && prev.Type() == "comment" && prev.EndPoint().Row+1 == row; prev = prev.PrevSibling() {
+		if text := prev.Content(source); strings.HasPrefix(text, "//go:") {
+			directives = append(directives, text)
+		}
+		row = prev.StartPoint().Row
+	}
+	slices.Reverse(directives)
+	return directives
+}
+
+// declCapture returns the capture spanning a whole declaration in a symbols
+// query match.
+// const and var are checked first because their matches also have a @type
+// capture for the type annotation.
+func declCapture(match QueryMatch) (CaptureResult, bool) {
+	for _, name := range []string{"const", "var", "function", "method", "closure", "type", "class", "enum", "field"} {
+		if c, ok := findCapture(match, name); ok {
+			return c, true
+		}
+	}
+	return CaptureResult{}, false
+}
+
+// declName returns Name, or Receiver.Name for methods.
+func declName(sym Symbol) string {
+	if sym.Receiver != "" {
+		return sym.Receiver + "." + sym.Name
+	}
+	return sym.Name
+}
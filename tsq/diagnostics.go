@@ -0,0 +1,36 @@
+package tsq
+
+import (
+	"sort"
+	"sync"
+)
+
+// Diagnostic describes a non-fatal condition encountered while processing a file.
+type Diagnostic struct {
+	File    string `json:"file"`
+	Kind    string `json:"kind"` // capped, parse_error, max_files, ignored, query_error
+	Message string `json:"message,omitempty"`
+}
+
+// diagnostics collects Diagnostics from concurrent workers.
+type diagnostics struct {
+	mu    sync.Mutex
+	items []Diagnostic
+}
+
+func (d *diagnostics) add(diag Diagnostic) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.items = append(d.items, diag)
+}
+
+// flush appends the collected diagnostics, sorted by file, to dst if it is non-nil.
+func (d *diagnostics) flush(dst *[]Diagnostic) {
+	if dst == nil {
+		return
+	}
+	sort.SliceStable(d.items, func(i, j int) bool {
+		return d.items[i].File < d.items[j].File
+	})
+	*dst = append(*dst, d.items...)
+}
@@ -0,0 +1,197 @@
+package tsq
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LineRange is an inclusive range of 1-based line numbers.
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ParseUnifiedDiff returns the lines each file in a unified diff (as written
+// by git diff or diff -u) changes, as ranges of lines in the new version of
+// the file. Lines removed without replacement count as a change to the line
+// that follows them. Paths are taken from the +++ headers with git's b/
+// prefix removed; deleted files are left out.
+func ParseUnifiedDiff(r io.Reader) (map[string][]LineRange, error) {
+	changed := make(map[string][]LineRange)
+	var file string
+	line := 0                // next line of the new file
+	oldLeft, newLeft := 0, 0 // lines of the current hunk still to come
+
+	add := func(n int) {
+		if file == "" {
+			return
+		}
+		n = max(n, 1)
+		ranges := changed[file]
+		if last := len(ranges) - 1; last >= 0 && n <= ranges[last].End+1 {
+			ranges[last].End = max(ranges[last].End, n)
+			return
+		}
+		changed[file] = append(ranges, LineRange{Start: n, End: n})
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		text := sc.Text()
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(text, "+"):
+				add(line)
+				line++
+				newLeft--
+			case strings.HasPrefix(text, "-"):
+				add(line)
+				oldLeft--
+			case strings.HasPrefix(text, `\`):
+				// "\ No newline at end of file"
+			default:
+				// Context, possibly with its leading space stripped.
+				line++
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			file = diffPath(strings.TrimPrefix(text, "+++ "))
+		case strings.HasPrefix(text, "@@ "):
+			var err error
+			if oldLeft, line, newLeft, err = parseHunkHeader(text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read diff: %w", err)
+	}
+	return changed, nil
+}
+
+// diffPath extracts the file path from a +++ header, or "" for /dev/null.
+func diffPath(header string) string {
+	// diff -u appends a tab and the modification time.
+	header, _, _ = strings.Cut(header, "\t")
+	if header == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(header, "b/")
+}
+
+// parseHunkHeader returns the old line count and the new start line and
+// line count of a hunk header such as "@@ -12,7 +12,9 @@ func main() {".
+func parseHunkHeader(header string) (oldCount, newStart, newCount int, err error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+	_, oldCount, err = parseHunkRange(fields[1][1:])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+	newStart, newCount, err = parseHunkRange(fields[2][1:])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+	return oldCount, newStart, newCount, nil
+}
+
+// parseHunkRange parses "start,count" or "start", where the count is 1.
+func parseHunkRange(s string) (start, count int, err error) {
+	startText, countText, hasCount := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startText); err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(countText); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
+
+// changedLines maps FileJob.AbsPath to the lines changed in that file.
+type changedLines map[string][]LineRange
+
+// resolveChangedLines re-keys lines, given relative to the git worktree root
+// containing root (or root itself outside a repository), by the paths files
+// are scanned under. A nil map means no filtering.
+func resolveChangedLines(lines map[string][]LineRange, root string, fsys fs.FS) changedLines {
+	if lines == nil {
+		return nil
+	}
+	resolved := make(changedLines, len(lines))
+	if fsys != nil {
+		for name, ranges := range lines {
+			resolved[path.Clean(name)] = ranges
+		}
+		return resolved
+	}
+
+	base, err := filepath.Abs(root)
+	if err != nil {
+		base = root
+	}
+	if gitRoot, ok := findGitRoot(base); ok {
+		base = gitRoot
+	}
+	for name, ranges := range lines {
+		resolved[filepath.Join(base, filepath.FromSlash(name))] = ranges
+	}
+	return resolved
+}
+
+// filterFiles drops the files with no changed lines.
+func (c changedLines) filterFiles(files []FileJob) []FileJob {
+	if c == nil {
+		return files
+	}
+	var kept []FileJob
+	for _, f := range files {
+		if _, ok := c[f.AbsPath]; ok {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// touches reports whether any changed line of file falls in start..end.
+func (c changedLines) touches(file string, start, end int) bool {
+	for _, r := range c[file] {
+		if r.Start <= end && start <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// filterSymbols keeps the symbols of file whose declaration contains a
+// changed line.
+func (c changedLines) filterSymbols(file string, symbols []Symbol, matches []QueryMatch) []Symbol {
+	if c == nil {
+		return symbols
+	}
+	decls := declsByName(matches)
+	var kept []Symbol
+	for _, sym := range symbols {
+		r := declRange(sym, decls)
+		if c.touches(file, r.Start.Line, r.End.Line) {
+			kept = append(kept, sym)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,105 @@
+package tsq
+
+import (
+	"cmp"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ContainmentEdge records that one symbol contains another, e.g. a type and
+// one of its methods or a function and a closure inside it. Symbols are
+// identified by SymbolID.
+type ContainmentEdge struct {
+	ParentID string `json:"parent_id"`
+	ChildID  string `json:"child_id"`
+}
+
+// SymbolID returns a stable identifier for sym in file: the file, "#", and
+// the symbol's name, with methods named Receiver.Name and closures by their
+// func@line:col name. IDs don't change when unrelated code is edited, except
+// for closures, which move with their position.
+func SymbolID(file string, sym Symbol) string {
+	name := sym.Name
+	if sym.Receiver != "" {
+		name = sym.Receiver + "." + name
+	}
+	return file + "#" + name
+}
+
+// ContainmentEdges derives containment edges from symbols results:
+//   - a type contains its methods, matched by receiver type name among the
+//     types of the method's directory, so methods in other files of the
+//     package are linked too;
+//   - a closure is contained by the innermost closure whose range holds it,
+//     or else by its Enclosing declaration.
+//
+// Closures are only present if the results were produced with
+// IncludeAnonymous. Edges are sorted by parent, then child.
+func ContainmentEdges(results []SymbolsResult) []ContainmentEdge {
+	// types maps a directory and type name to the type's ID.
+	types := make(map[[2]string]string)
+	for _, r := range results {
+		dir := path.Dir(r.File)
+		for _, sym := range r.Symbols {
+			switch sym.Kind {
+			case "type", "struct", "interface", "class":
+				types[[2]string{dir, sym.Name}] = SymbolID(r.File, sym)
+			}
+		}
+	}
+
+	edges := []ContainmentEdge{}
+	for _, r := range results {
+		dir := path.Dir(r.File)
+		for _, sym := range r.Symbols {
+			child := SymbolID(r.File, sym)
+			switch sym.Kind {
+			case "method":
+				if parent, ok := types[[2]string{dir, receiverTypeName(sym.Receiver)}]; ok {
+					edges = append(edges, ContainmentEdge{ParentID: parent, ChildID: child})
+				}
+			case "closure":
+				if parent, ok := closureParent(r, sym); ok {
+					edges = append(edges, ContainmentEdge{ParentID: parent, ChildID: child})
+				}
+			}
+		}
+	}
+
+	slices.SortFunc(edges, func(a, b ContainmentEdge) int {
+		return cmp.Or(cmp.Compare(a.ParentID, b.ParentID), cmp.Compare(a.ChildID, b.ChildID))
+	})
+	return edges
+}
+
+// closureParent returns the ID of the symbol directly containing closure
+// within r's file.
+func closureParent(r SymbolsResult, closure Symbol) (string, bool) {
+	var inner *Symbol
+	for i, sym := range r.Symbols {
+		if sym.Kind != "closure" || sym.Range == closure.Range || !rangeContains(sym.Range, closure.Range.Start) {
+			continue
+		}
+		if inner == nil || positionBefore(inner.Range.Start, sym.Range.Start) {
+			inner = &r.Symbols[i]
+		}
+	}
+	if inner != nil {
+		return SymbolID(r.File, *inner), true
+	}
+	if closure.Enclosing == "" {
+		return "", false
+	}
+	return r.File + "#" + closure.Enclosing, true
+}
+
+// receiverTypeName strips type arguments, and the trait of Rust trait impls,
+// from a receiver type, so methods on Set[K] or Set<K> for Trait are matched
+// to the type Set.
+func receiverTypeName(receiver string) string {
+	if i := strings.IndexAny(receiver, "[< "); i >= 0 {
+		return receiver[:i]
+	}
+	return receiver
+}
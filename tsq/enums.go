@@ -0,0 +1,130 @@
+package tsq
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// predeclaredTypes are Go's predeclared types, which don't make a const
+// block enum-like.
+var predeclaredTypes = map[string]bool{
+	"bool": true, "byte": true, "rune": true, "string": true, "error": true, "any": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"float32": true, "float64": true, "complex64": true, "complex128": true,
+}
+
+// groupEnums replaces the constants of each enum-like const block (see
+// constEnum) with a single "enum" symbol named after their type, whose
+// Members are the constants with their values. Blank constants are left
+// out. The returned blocks stay parallel to the returned symbols, with no
+// block for the enums.
+func groupEnums(symbols []Symbol, blocks []CaptureResult, src sourceOptions) ([]Symbol, []CaptureResult) {
+	var grouped []Symbol
+	var groupedBlocks []CaptureResult
+	for i := 0; i < len(symbols); {
+		block := blocks[i]
+		typeName, values := "", map[Position]string(nil)
+		if block.Name != "" && isParenthesizedBlock(block.Text) {
+			typeName, values = constEnum(block)
+		}
+		if typeName == "" {
+			grouped = append(grouped, symbols[i])
+			groupedBlocks = append(groupedBlocks, block)
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(blocks) && blocks[j].Name != "" && blocks[j].Range == block.Range {
+			j++
+		}
+
+		sym := Symbol{
+			Name:       typeName,
+			Kind:       "enum",
+			Visibility: getVisibility(typeName),
+			File:       symbols[i].File,
+			Range:      block.Range,
+		}
+		for _, m := range symbols[i:j] {
+			if m.Name == "_" {
+				continue
+			}
+			m.Value = values[m.Range.Start]
+			sym.Members = append(sym.Members, m)
+		}
+		if src.include {
+			sym.Source = src.snippet(block)
+		}
+		grouped = append(grouped, sym)
+		groupedBlocks = append(groupedBlocks, CaptureResult{})
+		i = j
+	}
+	return grouped, groupedBlocks
+}
+
+// constEnum reports whether a const block is enum-like: all of its
+// non-blank constants have the same named, non-predeclared type, such as
+// Color in const ( Red Color = iota; Green ). Specs without a value inherit
+// the type of the spec they repeat, as in Go. If so, it returns the type
+// name and each constant's value, keyed by the position of its name: the
+// iota value where it can be computed (see resolveIota), else its value
+// expression; otherwise it returns "". block is the const_declaration
+// capture.
+func constEnum(block CaptureResult) (string, map[Position]string) {
+	if block.node == nil || block.node.Type() != "const_declaration" {
+		return "", nil
+	}
+	iotaValues := resolveIota(block)
+
+	typeName := ""
+	values := make(map[Position]string)
+	specType := ""
+	for i := 0; i < int(block.node.NamedChildCount()); i++ {
+		spec := block.node.NamedChild(i)
+		if spec.Type() != "const_spec" {
+			continue
+		}
+		value := spec.ChildByFieldName("value")
+		switch typ := spec.ChildByFieldName("type"); {
+		case typ != nil && typ.Type() == "type_identifier":
+			specType = captureNodeText(block, typ)
+		case typ != nil || value != nil:
+			specType = ""
+		}
+
+		n := 0
+		for j := 0; j < int(spec.NamedChildCount()); j++ {
+			name := spec.NamedChild(j)
+			if name.Type() != "identifier" {
+				continue
+			}
+			if captureNodeText(block, name) != "_" {
+				if specType == "" || (typeName != "" && specType != typeName) {
+					return "", nil
+				}
+				typeName = specType
+				pos := nodeRange(name).Start
+				if v, ok := iotaValues[pos]; ok {
+					values[pos] = v
+				} else if expr := nthNamedChild(value, n); expr != nil {
+					values[pos] = captureNodeText(block, expr)
+				}
+			}
+			n++
+		}
+	}
+	if predeclaredTypes[typeName] {
+		return "", nil
+	}
+	return typeName, values
+}
+
+// nthNamedChild returns the i-th named child of n, or nil if n is nil or
+// has fewer children.
+func nthNamedChild(n *sitter.Node, i int) *sitter.Node {
+	if n == nil || i >= int(n.NamedChildCount()) {
+		return nil
+	}
+	return n.NamedChild(i)
+}
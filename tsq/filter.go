@@ -0,0 +1,292 @@
+package tsq
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a compiled result filter expression such as
+//
+//	kind == "function" && visibility == "public"
+//
+// The grammar is deliberately small: comparisons of a field against a
+// literal, joined by && and ||, with && binding tighter. Fields are the JSON
+// names of a result's fields, with dots for nested objects (range.start.line).
+// Operators are ==, !=, <, <=, > and >=; literals are double-quoted strings,
+// numbers, true, false and null. Fields left out of the JSON because they are
+// empty compare as the zero value of the literal's type, and equal to null.
+type Filter struct {
+	or [][]comparison // disjunction of conjunctions
+}
+
+type comparison struct {
+	field []string
+	op    string
+	value any // string, float64, bool or nil
+}
+
+// ParseFilter compiles a filter expression.
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("filter: empty expression")
+	}
+
+	f := &Filter{}
+	var and []comparison
+	for len(tokens) > 0 {
+		if len(tokens) < 3 {
+			return nil, fmt.Errorf("filter: incomplete comparison near %q", strings.Join(tokens, " "))
+		}
+		c, err := parseComparison(tokens[0], tokens[1], tokens[2])
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, c)
+		tokens = tokens[3:]
+
+		if len(tokens) == 0 {
+			break
+		}
+		switch tokens[0] {
+		case "&&":
+		case "||":
+			f.or = append(f.or, and)
+			and = nil
+		default:
+			return nil, fmt.Errorf("filter: expected && or ||, got %q", tokens[0])
+		}
+		tokens = tokens[1:]
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("filter: expression ends with an operator")
+		}
+	}
+	f.or = append(f.or, and)
+	return f, nil
+}
+
+func parseComparison(field, op, literal string) (comparison, error) {
+	if !isFilterIdent(field) {
+		return comparison{}, fmt.Errorf("filter: expected field name, got %q", field)
+	}
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return comparison{}, fmt.Errorf("filter: expected comparison operator, got %q", op)
+	}
+
+	c := comparison{field: strings.Split(field, "."), op: op}
+	switch {
+	case strings.HasPrefix(literal, `"`):
+		s, err := strconv.Unquote(literal)
+		if err != nil {
+			return comparison{}, fmt.Errorf("filter: bad string %s", literal)
+		}
+		c.value = s
+	case literal == "true", literal == "false":
+		c.value = literal == "true"
+	case literal == "null":
+		c.value = nil
+	default:
+		n, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return comparison{}, fmt.Errorf("filter: expected literal, got %q", literal)
+		}
+		c.value = n
+	}
+	return c, nil
+}
+
+// tokenizeFilter splits an expression into field names, operators and
+// literals.
+func tokenizeFilter(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		ch := expr[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			i++
+		case ch == '"':
+			j := i + 1
+			for j < len(expr) && expr[j] != '"' {
+				if expr[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(expr) {
+				return nil, fmt.Errorf("filter: unterminated string")
+			}
+			tokens = append(tokens, expr[i:j+1])
+			i = j + 1
+		case strings.HasPrefix(expr[i:], "&&"), strings.HasPrefix(expr[i:], "||"),
+			strings.HasPrefix(expr[i:], "=="), strings.HasPrefix(expr[i:], "!="),
+			strings.HasPrefix(expr[i:], "<="), strings.HasPrefix(expr[i:], ">="):
+			tokens = append(tokens, expr[i:i+2])
+			i += 2
+		case ch == '<' || ch == '>':
+			tokens = append(tokens, expr[i:i+1])
+			i++
+		default:
+			j := i
+			for j < len(expr) && (isFilterIdentByte(expr[j]) || expr[j] == '-' || expr[j] == '+') {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("filter: unexpected %q", expr[i:i+1])
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func isFilterIdentByte(b byte) bool {
+	return b == '_' || b == '.' || b < 0x80 && (unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b)))
+}
+
+func isFilterIdent(s string) bool {
+	if s == "" || s == "true" || s == "false" || s == "null" {
+		return false
+	}
+	first := rune(s[0])
+	return first == '_' || unicode.IsLetter(first)
+}
+
+// Match reports whether v, viewed through its JSON encoding, satisfies the
+// filter.
+func (f *Filter) Match(v any) (bool, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return false, fmt.Errorf("filter: result is not an object")
+	}
+
+	for _, and := range f.or {
+		ok := true
+		for _, c := range and {
+			if !c.match(obj) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (c comparison) match(obj map[string]any) bool {
+	var value any = obj
+	for _, key := range c.field {
+		m, ok := value.(map[string]any)
+		if !ok {
+			value = nil
+			break
+		}
+		value = m[key]
+	}
+
+	if value == nil {
+		switch c.value.(type) {
+		case string:
+			value = ""
+		case float64:
+			value = float64(0)
+		case bool:
+			value = false
+		}
+	}
+
+	switch want := c.value.(type) {
+	case string:
+		got, ok := value.(string)
+		if !ok {
+			return c.op == "!="
+		}
+		return compareOrdered(strings.Compare(got, want), c.op)
+	case float64:
+		got, ok := value.(float64)
+		if !ok {
+			return c.op == "!="
+		}
+		switch {
+		case got < want:
+			return compareOrdered(-1, c.op)
+		case got > want:
+			return compareOrdered(1, c.op)
+		default:
+			return compareOrdered(0, c.op)
+		}
+	default: // bool or nil
+		equal := value == c.value
+		switch c.op {
+		case "==":
+			return equal
+		case "!=":
+			return !equal
+		}
+		return false
+	}
+}
+
+// compareOrdered applies op to the result of a three-way comparison.
+func compareOrdered(cmp int, op string) bool {
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// FilterSlice returns the items that match f, preserving order.
+func FilterSlice[T any](items []T, f *Filter) ([]T, error) {
+	kept := make([]T, 0, len(items))
+	for _, item := range items {
+		ok, err := f.Match(item)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			kept = append(kept, item)
+		}
+	}
+	return kept, nil
+}
+
+// FilterSymbols applies f to each symbol in results, dropping files left
+// without symbols.
+func FilterSymbols(results []SymbolsResult, f *Filter) ([]SymbolsResult, error) {
+	kept := make([]SymbolsResult, 0, len(results))
+	for _, r := range results {
+		symbols, err := FilterSlice(r.Symbols, f)
+		if err != nil {
+			return nil, err
+		}
+		if len(symbols) > 0 {
+			kept = append(kept, SymbolsResult{File: r.File, Symbols: symbols})
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,52 @@
+package tsq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	symbols := []Symbol{
+		{Name: "Run", Kind: "function", Visibility: "public", Range: Range{Start: Position{Line: 3}}},
+		{Name: "helper", Kind: "function", Visibility: "private", Range: Range{Start: Position{Line: 7}}},
+		{Name: "Server", Kind: "struct", Visibility: "public", Range: Range{Start: Position{Line: 12}}},
+		{Name: "Start", Kind: "method", Visibility: "public", Receiver: "Server", Range: Range{Start: Position{Line: 20}}},
+	}
+
+	names := func(expr string) []string {
+		t.Helper()
+		f, err := ParseFilter(expr)
+		require.NoError(t, err)
+		kept, err := FilterSlice(symbols, f)
+		require.NoError(t, err)
+		out := []string{}
+		for _, sym := range kept {
+			out = append(out, sym.Name)
+		}
+		return out
+	}
+
+	require.Equal(t, []string{"Run"}, names(`kind == "function" && visibility == "public"`))
+	require.Equal(t, []string{"Run", "helper", "Server"}, names(`kind == "function" || kind == "struct"`))
+	require.Equal(t, []string{"Run", "Start"}, names(`kind == "struct" && name == "x" || visibility == "public" && kind != "struct"`))
+	require.Equal(t, []string{"Server", "Start"}, names(`range.start.line >= 10`))
+	require.Equal(t, []string{"Start"}, names(`receiver != ""`))
+	require.Equal(t, []string{"Run", "helper", "Server"}, names(`receiver == null`))
+	require.Equal(t, []string{}, names(`name == "it's \"quoted\""`))
+
+	for _, bad := range []string{
+		``,
+		`kind`,
+		`kind == `,
+		`kind = "function"`,
+		`kind == "function" &&`,
+		`kind == "function" and visibility == "public"`,
+		`"kind" == "function"`,
+		`kind == "unterminated`,
+		`kind == function`,
+	} {
+		_, err := ParseFilter(bad)
+		require.Error(t, err, bad)
+	}
+}
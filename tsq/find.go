@@ -0,0 +1,94 @@
+package tsq
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// findQueries are the queries generated for QueryOptions.Find, by target.
+// Each captures the declaration as @<target> and its name as @name; methods
+// also capture their receiver as @receiver. const and var names are matched
+// as direct identifier children, not by the name field, so that specs
+// declaring several names match once per name; values are nested in an
+// expression_list and types are type identifiers, so neither matches.
+var findQueries = map[string]string{
+	"function":  `(function_declaration name: (identifier) @name) @function`,
+	"method":    `(method_declaration receiver: (parameter_list) @receiver name: (field_identifier) @name) @method`,
+	"struct":    `(type_spec name: (type_identifier) @name type: (struct_type)) @struct`,
+	"interface": `(type_spec name: (type_identifier) @name type: (interface_type)) @interface`,
+	"type":      `(type_spec name: (type_identifier) @name) @type`,
+	"const":     `(const_spec (identifier) @name) @const`,
+	"var":       `(var_spec (identifier) @name) @var`,
+}
+
+// FindTargets returns the declaration kinds accepted by QueryOptions.Find,
+// sorted.
+func FindTargets() []string {
+	targets := make([]string, 0, len(findQueries))
+	for target := range findQueries {
+		targets = append(targets, target)
+	}
+	slices.Sort(targets)
+	return targets
+}
+
+// findQuery returns the query generated for opts.Find, after checking the
+// options that go with it.
+func findQuery(opts QueryOptions) (string, error) {
+	if opts.Query != "" {
+		return "", errors.New("query and find are mutually exclusive")
+	}
+	if opts.Language != "go" {
+		return "", fmt.Errorf("find is not supported for %s", opts.Language)
+	}
+	q, ok := findQueries[opts.Find]
+	if !ok {
+		return "", fmt.Errorf("invalid find target %q: want one of %s", opts.Find, strings.Join(FindTargets(), ", "))
+	}
+	if opts.Receiver != "" && opts.Find != "method" {
+		return "", errors.New("receiver is only supported when finding methods")
+	}
+	for _, glob := range []string{opts.Name, opts.Receiver} {
+		if _, err := path.Match(glob, ""); err != nil {
+			return "", fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+	}
+	return q, nil
+}
+
+// nameFilter selects the matches of a generated find query by name and
+// receiver type name globs.
+type nameFilter struct {
+	name     string
+	receiver string
+}
+
+func (f nameFilter) active() bool {
+	return f.name != "" || f.receiver != ""
+}
+
+func (f nameFilter) keep(m QueryMatch) bool {
+	if f.name != "" {
+		name, ok := findCapture(m, "name")
+		if !ok || !globMatch(f.name, name.Text) {
+			return false
+		}
+	}
+	if f.receiver != "" {
+		recv, ok := findCapture(m, "receiver")
+		if !ok || !globMatch(f.receiver, receiverTypeName(extractReceiverType(recv.Text))) {
+			return false
+		}
+	}
+	return true
+}
+
+// globMatch reports whether s matches a path.Match glob that has already
+// been validated.
+func globMatch(glob, s string) bool {
+	ok, _ := path.Match(glob, s)
+	return ok
+}
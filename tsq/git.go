@@ -0,0 +1,141 @@
+package tsq
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// findGitRoot returns the root of the git worktree containing dir, found by
+// walking up until a directory holding .git (a directory, or a file for
+// linked worktrees and submodules). It returns false if dir is not in one.
+func findGitRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// checkGitRef rejects a revision git would take for an option, such as
+// "--output=file", before it is put on a git command line.
+func checkGitRef(ref string) error {
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid git revision %q", ref)
+	}
+	return nil
+}
+
+// gitChangedFiles lists files added, modified or renamed on HEAD relative to
+// the merge base with base. Paths are relative to dir. Renamed files are
+// reported under their new name.
+func gitChangedFiles(dir, base string) ([]string, error) {
+	if err := checkGitRef(base); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("git", "diff", "--name-only", "--relative", "--diff-filter=AMR", base+"...HEAD")
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("git diff %s...HEAD: %s", base, msg)
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths, nil
+}
+
+// gitExtractTree writes the files under dir as of revision rev into dest,
+// which must exist. dir must be inside a git worktree.
+func gitExtractTree(dir, rev, dest string) error {
+	if err := checkGitRef(rev); err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	root, ok := findGitRoot(abs)
+	if !ok {
+		return fmt.Errorf("%s is not in a git repository", dir)
+	}
+	prefix, err := filepath.Rel(root, abs)
+	if err != nil {
+		return err
+	}
+	treeish := rev + ":"
+	if prefix != "." {
+		treeish += filepath.ToSlash(prefix)
+	}
+
+	cmd := exec.Command("git", "archive", "--format=tar", treeish)
+	cmd.Dir = root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("git archive %s: %s", treeish, msg)
+	}
+	return extractTar(bytes.NewReader(out), dest)
+}
+
+// extractTar writes the regular files of a tar archive into dest. Entries
+// with paths outside dest are rejected.
+func extractTar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !filepath.IsLocal(hdr.Name) {
+			return fmt.Errorf("archive entry %q outside destination", hdr.Name)
+		}
+		name := filepath.Join(dest, filepath.FromSlash(hdr.Name))
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			return err
+		}
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
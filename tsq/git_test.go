@@ -0,0 +1,312 @@
+package tsq
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSymbolsChangedSince checks that only files changed on a feature branch
+// relative to the base branch are analyzed.
+func TestSymbolsChangedSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "tsq-git-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpDir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(name, content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644))
+	}
+
+	git("init", "-q", "-b", "main")
+	write("a.go", "package p\n\nfunc A() {}\n")
+	write("b.go", "package p\n\nfunc B() {}\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "base")
+
+	git("checkout", "-q", "-b", "feature")
+	write("b.go", "package p\n\nfunc B() {}\n\nfunc C() {}\n")
+	write("notes.txt", "not go\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "feature")
+
+	results, err := Symbols(SymbolsOptions{
+		Language:     "go",
+		Path:         tmpDir,
+		ChangedSince: "main",
+		Jobs:         1,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "b.go", results[0].File)
+
+	var names []string
+	for _, sym := range results[0].Symbols {
+		names = append(names, sym.Name)
+	}
+	require.Equal(t, []string{"B", "C"}, names)
+
+	_, err = Symbols(SymbolsOptions{Path: tmpDir, ChangedSince: "no-such-branch"})
+	require.Error(t, err)
+
+	out := filepath.Join(tmpDir, "out.txt")
+	_, err = Symbols(SymbolsOptions{Path: tmpDir, ChangedSince: "--output=" + out})
+	require.ErrorContains(t, err, "invalid git revision")
+	require.NoFileExists(t, out, "revisions are never passed to git as options")
+
+	// Changed files go through the filters of a directory scan.
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "gen"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, ".tools"), 0755))
+	write("c.go", "package p\n\nfunc D() {}\n")
+	write("gen/gen.go", "package gen\n\nfunc G() {}\n")
+	write(".tools/tool.go", "package tools\n\nfunc T() {}\n")
+	write(".hidden.go", "package p\n\nfunc H() {}\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "more")
+	write(".gitignore", "gen/\n")
+
+	files := func(opts SymbolsOptions) []string {
+		t.Helper()
+		opts.Path, opts.ChangedSince, opts.Jobs = tmpDir, "main", 1
+		results, err := Symbols(opts)
+		require.NoError(t, err)
+		var files []string
+		for _, r := range results {
+			files = append(files, r.File)
+		}
+		return files
+	}
+	require.Equal(t, []string{"b.go", "c.go", "gen/gen.go"}, files(SymbolsOptions{}))
+	require.Equal(t, []string{"b.go", "c.go"}, files(SymbolsOptions{RespectGitignore: true}))
+	require.Equal(t, []string{".hidden.go", ".tools/tool.go", "b.go", "c.go", "gen/gen.go"}, files(SymbolsOptions{Hidden: true}))
+	require.Equal(t, []string{"b.go"}, files(SymbolsOptions{MaxFiles: 1}))
+}
+
+// TestPathRelativeToGit checks that scanning a subdirectory of a repository
+// can report paths relative to the repository root.
+func TestPathRelativeToGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "tsq-git-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	cmd := exec.Command("git", "init", "-q")
+	cmd.Dir = tmpDir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	sub := filepath.Join(tmpDir, "internal", "svc")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sub, "svc.go"), []byte("package svc\n\nfunc Serve() {}\n"), 0644))
+
+	files := func(opts SymbolsOptions) []string {
+		t.Helper()
+		opts.Jobs = 1
+		results, err := Symbols(opts)
+		require.NoError(t, err)
+		var names []string
+		for _, r := range results {
+			names = append(names, r.File)
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"svc.go"}, files(SymbolsOptions{Path: sub}))
+	require.Equal(t, []string{"internal/svc/svc.go"}, files(SymbolsOptions{Path: sub, PathRelativeTo: "git"}))
+	require.Equal(t, []string{"internal/svc/svc.go"}, files(SymbolsOptions{File: filepath.Join(sub, "svc.go"), PathRelativeTo: "git"}))
+
+	// Outside a repository paths stay relative to the scan root
+	outside, err := os.MkdirTemp("", "tsq-nogit-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(outside)
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "x.go"), []byte("package x\n\nfunc X() {}\n"), 0644))
+	if _, inRepo := findGitRoot(outside); !inRepo {
+		require.Equal(t, []string{"x.go"}, files(SymbolsOptions{Path: outside, PathRelativeTo: "git"}))
+	}
+
+	_, err = Symbols(SymbolsOptions{Path: sub, PathRelativeTo: "module"})
+	require.ErrorContains(t, err, "invalid path-relative-to")
+}
+
+// TestChurn checks that only the commits touching a function's lines are
+// counted for it, following the lines as code above them moves.
+func TestChurn(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "tsq-git-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	commit := func(content, date string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "parse.go"), []byte(content), 0644))
+		for _, args := range [][]string{{"add", "."}, {"commit", "-q", "-m", "change"}} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = tmpDir
+			cmd.Env = append(os.Environ(),
+				"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+				"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+				"GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date,
+			)
+			out, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(out))
+		}
+	}
+
+	cmd := exec.Command("git", "init", "-q")
+	cmd.Dir = tmpDir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	commit("package p\n\nfunc Parse() {\n\treturn\n}\n\nfunc Other() {}\n", "2024-01-01T10:00:00Z")
+	commit("package p\n\nfunc Parse() {\n\tprintln()\n}\n\nfunc Other() {}\n", "2024-02-01T10:00:00Z")
+	commit("package p\n\nimport \"fmt\"\n\nfunc Parse() {\n\tprintln()\n}\n\nfunc Other() { fmt.Println() }\n", "2024-03-01T10:00:00Z")
+
+	churn, err := Churn(ChurnOptions{Symbol: "Parse", Path: tmpDir, Jobs: 1})
+	require.NoError(t, err)
+	require.Len(t, churn, 1)
+	require.Equal(t, "Parse", churn[0].Symbol)
+	require.Equal(t, "parse.go", churn[0].File)
+	require.Equal(t, 2, churn[0].CommitCount, "added, then changed once; the import moved it without touching it")
+	require.Equal(t, "2024-02-01T10:00:00Z", churn[0].LastChanged)
+
+	churn, err = Churn(ChurnOptions{Symbol: "Other", Path: tmpDir, Jobs: 1})
+	require.NoError(t, err)
+	require.Len(t, churn, 1)
+	require.Equal(t, 2, churn[0].CommitCount)
+	require.Equal(t, "2024-03-01T10:00:00Z", churn[0].LastChanged)
+
+	churn, err = Churn(ChurnOptions{Symbol: "Missing", Path: tmpDir, Jobs: 1})
+	require.NoError(t, err)
+	require.Empty(t, churn)
+}
+
+// TestAPIDiff checks that public API changes between revisions are
+// classified by semver impact.
+func TestAPIDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "tsq-git-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpDir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	sub := filepath.Join(tmpDir, "lib")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+	write := func(content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(sub, "lib.go"), []byte(content), 0644))
+	}
+
+	git("init", "-q", "-b", "main")
+	write("package lib\n\nfunc Parse(s string) error { return nil }\n\nfunc Format() string { return \"\" }\n\nfunc helper() {}\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "v1")
+	git("tag", "v1.0.0")
+
+	// Adding an exported function is a minor change.
+	write("package lib\n\nfunc Parse(s string) error { return nil }\n\nfunc Format() string { return \"\" }\n\nfunc Validate() bool { return true }\n\nfunc helper(n int) {}\n")
+	git("commit", "-q", "-am", "add Validate")
+	git("tag", "v1.1.0")
+
+	diff, err := APIDiff(APIDiffOptions{Path: sub, OldRev: "v1.0.0", NewRev: "v1.1.0", Jobs: 1})
+	require.NoError(t, err)
+	require.Equal(t, ImpactMinor, diff.Bump)
+	require.Equal(t, []APIChange{
+		{Kind: "added", Impact: ImpactMinor, Dir: ".", Name: "lib.Validate", SymbolKind: "function", New: "func Validate() bool"},
+	}, diff.Changes)
+
+	// Removing one, in the working tree, is a major change.
+	write("package lib\n\nfunc Parse(s string) error { return nil }\n\nfunc Validate() bool { return true }\n")
+	diff, err = APIDiff(APIDiffOptions{Path: sub, OldRev: "v1.1.0", Jobs: 1})
+	require.NoError(t, err)
+	require.Equal(t, ImpactMajor, diff.Bump)
+	require.Equal(t, []APIChange{
+		{Kind: "removed", Impact: ImpactMajor, Dir: ".", Name: "lib.Format", SymbolKind: "function", Old: "func Format() string"},
+	}, diff.Changes)
+
+	// So is changing a signature.
+	write("package lib\n\nfunc Parse(s string, strict bool) error { return nil }\n\nfunc Format() string { return \"\" }\n\nfunc Validate() bool { return true }\n")
+	diff, err = APIDiff(APIDiffOptions{Path: sub, OldRev: "v1.1.0", Jobs: 1})
+	require.NoError(t, err)
+	require.Equal(t, ImpactMajor, diff.Bump)
+	require.Equal(t, []APIChange{
+		{Kind: "changed", Impact: ImpactMajor, Dir: ".", Name: "lib.Parse", SymbolKind: "function", Old: "func Parse(s string) error", New: "func Parse(s string, strict bool) error"},
+	}, diff.Changes)
+
+	// Reformatting a signature, as gofmt does when a parameter list is
+	// split over lines, changes it as written.
+	write("package lib\n\nfunc Parse(\n\ts string,\n) error {\n\treturn nil\n}\n\nfunc Format() string { return \"\" }\n\nfunc Validate() bool { return true }\n")
+	diff, err = APIDiff(APIDiffOptions{Path: sub, OldRev: "v1.1.0", Jobs: 1})
+	require.NoError(t, err)
+	require.Equal(t, ImpactMajor, diff.Bump)
+	require.Len(t, diff.Changes, 1)
+	require.Equal(t, "changed", diff.Changes[0].Kind)
+
+	// But is no change when formatting is ignored.
+	diff, err = APIDiff(APIDiffOptions{Path: sub, OldRev: "v1.1.0", Jobs: 1, IgnoreFormatting: true})
+	require.NoError(t, err)
+	require.Equal(t, ImpactPatch, diff.Bump)
+	require.Empty(t, diff.Changes)
+
+	// Unexported changes are a patch.
+	write("package lib\n\nfunc Parse(s string) error { return nil }\n\nfunc Format() string { return \"\" }\n\nfunc Validate() bool { return false }\n\nfunc helper() {}\n")
+	diff, err = APIDiff(APIDiffOptions{Path: tmpDir, OldRev: "v1.1.0", Jobs: 1})
+	require.NoError(t, err)
+	require.Equal(t, ImpactPatch, diff.Bump)
+	require.Empty(t, diff.Changes)
+
+	_, err = APIDiff(APIDiffOptions{Path: sub, OldRev: "no-such-rev"})
+	require.ErrorContains(t, err, "git archive")
+
+	out := filepath.Join(tmpDir, "out.tar")
+	_, err = APIDiff(APIDiffOptions{Path: sub, OldRev: "--output=" + out})
+	require.ErrorContains(t, err, "invalid git revision")
+	require.NoFileExists(t, out)
+}
+
+func TestNormalizeSignature(t *testing.T) {
+	for _, sig := range []string{
+		"func Parse(a string,b int) (int,error)",
+		"func  Parse( a string, b int ) (int, error)",
+		"func Parse(\n\ta string,\n\tb int,\n) (int, error)",
+	} {
+		require.Equal(t, "func Parse(a string, b int) (int, error)", normalizeSignature(sig), "%q", sig)
+	}
+	require.NotEqual(t, normalizeSignature("func Parse(a string)"), normalizeSignature("func Parse(a []string)"))
+}
@@ -0,0 +1,171 @@
+package tsq
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// gitignoreRule is one pattern line of a .gitignore file.
+type gitignoreRule struct {
+	base    string // slash-separated directory of the .gitignore, relative to the top of the rules ("" for the top)
+	pattern string // glob matched against paths relative to base
+	negate  bool   // "!pattern": re-includes what earlier rules excluded
+	dirOnly bool   // "pattern/": matches directories only
+}
+
+// gitignore holds the rules of the .gitignore files met during a walk, in
+// the order git applies them: a directory's rules after its parents', so
+// the last matching rule decides. The top of the rules is the scan root,
+// or the root of the git worktree containing it once loadParents ran.
+type gitignore struct {
+	prefix string // slash-separated path of the scan root below the top, "" if it is the top
+	rules  []gitignoreRule
+	loaded map[string]bool // directories below the scan root whose rules ignoredFile loaded
+}
+
+// load adds the rules of the .gitignore file in dir, if there is one. dir
+// is the slash-separated path of the directory relative to the scan root,
+// "" for the root itself; read reads a file by that kind of path.
+func (g *gitignore) load(dir string, read func(name string) ([]byte, error)) {
+	data, err := read(path.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	g.rules = append(g.rules, parseGitignore(path.Join(g.prefix, dir), data)...)
+}
+
+// loadParents adds the rules of the .gitignore files in the directories
+// from the root of the git worktree containing the OS directory absRoot
+// down to absRoot's parent, as git applies them to a scan of a
+// subdirectory too. It must run before the rules of absRoot are loaded.
+func (g *gitignore) loadParents(absRoot string) {
+	top, ok := findGitRoot(absRoot)
+	if !ok || top == absRoot {
+		return
+	}
+	g.prefix = relSlash(top, absRoot)
+	if g.prefix == ".." || strings.HasPrefix(g.prefix, "../") {
+		g.prefix = ""
+		return
+	}
+
+	dir := ""
+	for _, name := range strings.Split(g.prefix, "/") {
+		data, err := readFile(nil, filepath.Join(top, filepath.FromSlash(dir), ".gitignore"))
+		if err == nil {
+			g.rules = append(g.rules, parseGitignore(dir, data)...)
+		}
+		dir = path.Join(dir, name)
+	}
+}
+
+// parseGitignore parses the contents of the .gitignore file in dir.
+// Blank lines and comments are skipped; a leading backslash escapes "#" and
+// "!", and trailing spaces are ignored unless escaped.
+func parseGitignore(dir string, data []byte) []gitignoreRule {
+	var rules []gitignoreRule
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := gitignoreRule{base: dir}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// A pattern with a slash other than at the end is relative to the
+		// .gitignore's directory; one without matches at any depth below it.
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		if !doublestar.ValidatePattern(line) {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignored reports whether the slash-separated path rel, relative to the
+// scan root, is excluded by the rules. Paths under an excluded directory
+// needn't be asked about: the walk skips the directory, and as in git its
+// contents can't be re-included.
+func (g *gitignore) ignored(rel string, isDir bool) bool {
+	rel = path.Join(g.prefix, rel)
+	ignored := false
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			sub = rel[len(rule.base)+1:]
+		}
+		if doublestar.MatchUnvalidated(rule.pattern, sub) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// ignoredFile is ignored for a file met outside a walk: it reports whether
+// the slash-separated path rel, relative to the scan root, or a directory
+// on its path is excluded, loading the rules of those directories first as
+// a walk would. The rules of the root must already be loaded.
+func (g *gitignore) ignoredFile(rel string, read func(name string) ([]byte, error)) bool {
+	if g.loaded == nil {
+		g.loaded = map[string]bool{}
+	}
+	dirs := strings.Split(rel, "/")
+	dir := ""
+	for _, name := range dirs[:len(dirs)-1] {
+		dir = path.Join(dir, name)
+		if g.ignored(dir, true) {
+			return true
+		}
+		if !g.loaded[dir] {
+			g.loaded[dir] = true
+			g.load(dir, read)
+		}
+	}
+	return g.ignored(rel, false)
+}
+
+// gitignoreReader returns the function gitignore.load reads files with,
+// for a walk of root in fsys, or of the OS directory root if fsys is nil.
+func gitignoreReader(fsys fs.FS, root string) func(name string) ([]byte, error) {
+	return func(name string) ([]byte, error) {
+		if fsys != nil {
+			return readFile(fsys, path.Join(root, name))
+		}
+		return readFile(nil, filepath.Join(root, filepath.FromSlash(name)))
+	}
+}
@@ -16,6 +16,9 @@ var goOutlineQuery string
 //go:embed queries/go/refs.scm
 var goRefsQuery string
 
+//go:embed queries/go/injections.scm
+var goInjectionsQuery string
+
 // Go implements the Language interface for Go source code.
 type Go struct{}
 
@@ -46,3 +49,7 @@ func (g *Go) OutlineQuery() string {
 func (g *Go) RefsQuery() string {
 	return goRefsQuery
 }
+
+func (g *Go) InjectionsQuery() string {
+	return goInjectionsQuery
+}
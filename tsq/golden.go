@@ -0,0 +1,39 @@
+package tsq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GoldenMatches renders query matches as stable text for golden files, one
+// line per capture,
+//
+//	@name: text (path:line:column)
+//
+// followed by an indented, quoted context line if the capture has context.
+// It is the format tsq's own data-driven tests use, and "(no matches)" if
+// there are none. No trailing newline is written.
+func GoldenMatches(matches []QueryMatch) string {
+	if len(matches) == 0 {
+		return "(no matches)"
+	}
+
+	var lines []string
+	for _, match := range matches {
+		for _, c := range match.Captures {
+			line := fmt.Sprintf("@%s: %s (%s:%d:%d)",
+				c.Name,
+				c.Text,
+				match.File,
+				c.Range.Start.Line,
+				c.Range.Start.Column,
+			)
+			if c.Context != "" {
+				line += fmt.Sprintf("\n  context: %q", c.Context)
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
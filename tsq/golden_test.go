@@ -0,0 +1,35 @@
+package tsq
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestGoldenMatches checks the golden text of a query against the layout the
+// data-driven tests in testdata/query.txt expect.
+func TestGoldenMatches(t *testing.T) {
+	dir := t.TempDir()
+	src := "package main\n\nfunc Hello() {}\n\nfunc World() {}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0o644))
+
+	matches, err := Query(QueryOptions{
+		Query: `(function_declaration name: (identifier) @name)`,
+		Path:  dir,
+		Jobs:  1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "@name: Hello (main.go:3:6)\n@name: World (main.go:5:6)", GoldenMatches(matches))
+
+	matches, err = Query(QueryOptions{
+		Query:        `(function_declaration name: (identifier) @name)`,
+		File:         filepath.Join(dir, "main.go"),
+		ContextBytes: 5,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "@name: Hello (main.go:3:6)\n  context: \"func Hello() {}\"\n@name: World (main.go:5:6)\n  context: \"func World() {}\"", GoldenMatches(matches))
+
+	require.Equal(t, "(no matches)", GoldenMatches(nil))
+}
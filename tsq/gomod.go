@@ -0,0 +1,140 @@
+package tsq
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"golang.org/x/mod/modfile"
+)
+
+// findModulePath walks up from dir looking for a go.mod file and returns the
+// module path it declares, or "" if none is found.
+func findModulePath(dir string) string {
+	path, _ := findModule(dir)
+	return path
+}
+
+// findModule walks up from dir looking for a go.mod file and returns the
+// module path it declares along with the directory containing it.
+func findModule(dir string) (modulePath, moduleDir string) {
+	for {
+		if path, ok := readModulePath(filepath.Join(dir, "go.mod")); ok {
+			return path, dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// packageImportPath returns the import path of the package in dir, given the
+// enclosing module. It returns "" if dir is outside the module.
+func packageImportPath(dir, modulePath, moduleDir string) string {
+	if modulePath == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(moduleDir, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	if rel == "." {
+		return modulePath
+	}
+	return modulePath + "/" + filepath.ToSlash(rel)
+}
+
+// readModulePath returns the module path declared in a go.mod file, or ""
+// if it declares none. It reports false if the file can't be read.
+func readModulePath(gomod string) (string, bool) {
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		return "", false
+	}
+	return modfile.ModulePath(data), true
+}
+
+// classifyImport returns the group of an import path: "local" for packages
+// within modulePath, "std" for paths whose first element has no dot, and
+// "third_party" otherwise.
+func classifyImport(path, modulePath string) string {
+	if modulePath != "" && (path == modulePath || strings.HasPrefix(path, modulePath+"/")) {
+		return "local"
+	}
+	first, _, _ := strings.Cut(path, "/")
+	if !strings.Contains(first, ".") {
+		return "std"
+	}
+	return "third_party"
+}
+
+// importName returns the name an import is referenced by in code: its alias
+// if set, otherwise the last element of the path with any major version
+// suffix (/v2) or gopkg.in style version (yaml.v3) removed. This matches the
+// package name for conventionally named packages.
+func importName(imp ImportInfo) string {
+	if imp.Alias != "" {
+		return imp.Alias
+	}
+	elems := strings.Split(imp.Path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+	if base, version, ok := strings.Cut(name, ".v"); ok && isDigits(version) {
+		name = base
+	}
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+func isMajorVersion(s string) bool {
+	return len(s) > 1 && s[0] == 'v' && isDigits(s[1:])
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// countImportUsage sets UsageCount on each import to the number of selector
+// expressions and qualified types under root whose package operand is the
+// import's name. Blank and dot imports are left without a count.
+func countImportUsage(imports []ImportInfo, root *sitter.Node, source []byte) {
+	counts := make(map[string]int)
+	var walk func(n *sitter.Node)
+	walk = func(n *sitter.Node) {
+		var operand *sitter.Node
+		switch n.Type() {
+		case "selector_expression":
+			operand = n.ChildByFieldName("operand")
+		case "qualified_type":
+			operand = n.ChildByFieldName("package")
+		}
+		if operand != nil && (operand.Type() == "identifier" || operand.Type() == "package_identifier") {
+			counts[operand.Content(source)]++
+		}
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			walk(n.NamedChild(i))
+		}
+	}
+	walk(root)
+
+	for i := range imports {
+		name := importName(imports[i])
+		if name == "_" || name == "." {
+			continue
+		}
+		n := counts[name]
+		imports[i].UsageCount = &n
+	}
+}
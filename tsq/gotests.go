@@ -0,0 +1,115 @@
+package tsq
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TestFunc is a Go test, benchmark, example or fuzz target declared in a
+// _test.go file.
+type TestFunc struct {
+	Name     string   `json:"test_name"`
+	Kind     string   `json:"kind"`                      // test, benchmark, example, fuzz
+	Target   string   `json:"inferred_target,omitempty"` // e.g. Parse for TestParse, Server.Start for TestServer_Start
+	File     string   `json:"file"`
+	Position Position `json:"position"`
+}
+
+// testPrefixes maps the name prefixes recognized by go test to their kind.
+var testPrefixes = []struct {
+	prefix, kind string
+}{
+	{"Test", "test"},
+	{"Benchmark", "benchmark"},
+	{"Example", "example"},
+	{"Fuzz", "fuzz"},
+}
+
+// Tests lists the test functions declared in Go _test.go files, with the
+// function or method each one most likely covers, inferred from its name.
+func Tests(opts TestsOptions) ([]TestFunc, error) {
+	results, err := Symbols(SymbolsOptions{
+		Language: "go",
+		Path:     opts.Path,
+		File:     opts.File,
+		Jobs:     opts.Jobs,
+		MaxBytes: opts.MaxBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tests := []TestFunc{}
+	for _, r := range results {
+		if !strings.HasSuffix(r.File, "_test.go") {
+			continue
+		}
+		for _, sym := range r.Symbols {
+			if sym.Kind != "function" {
+				continue
+			}
+			kind, target, ok := classifyTestName(sym.Name)
+			if !ok {
+				continue
+			}
+			tests = append(tests, TestFunc{
+				Name:     sym.Name,
+				Kind:     kind,
+				Target:   target,
+				File:     r.File,
+				Position: sym.Range.Start,
+			})
+		}
+	}
+
+	sort.Slice(tests, func(i, j int) bool {
+		if tests[i].File != tests[j].File {
+			return tests[i].File < tests[j].File
+		}
+		return tests[i].Position.Line < tests[j].Position.Line
+	})
+	return tests, nil
+}
+
+// classifyTestName reports the kind of a go test function name and the
+// identifier it targets. As in go test, the prefix must be followed by the
+// end of the name or a character that isn't a lowercase letter, so Testify
+// is not a test. The rest of the name follows the go doc example
+// convention: F, T_M for method M of T, and an optional _suffix starting
+// with a lowercase letter that is ignored. A leading underscore, as in
+// Test_parse, targets the unexported parse, except for examples, where
+// Example_suffix is a package example.
+func classifyTestName(name string) (kind, target string, ok bool) {
+	for _, p := range testPrefixes {
+		rest, found := strings.CutPrefix(name, p.prefix)
+		if !found {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(rest); unicode.IsLower(r) {
+			return "", "", false
+		}
+		return p.kind, inferTestTarget(p.kind, rest), true
+	}
+	return "", "", false
+}
+
+func inferTestTarget(kind, rest string) string {
+	if unexported, ok := strings.CutPrefix(rest, "_"); ok {
+		if kind == "example" {
+			return ""
+		}
+		rest, _, _ = strings.Cut(unexported, "_")
+		return rest
+	}
+
+	parts := strings.Split(rest, "_")
+	target := parts[0]
+	if len(parts) > 1 {
+		if r, _ := utf8.DecodeRuneInString(parts[1]); unicode.IsUpper(r) {
+			target += "." + parts[1]
+		}
+	}
+	return target
+}
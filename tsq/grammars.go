@@ -0,0 +1,165 @@
+package tsq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// GrammarManifest is the name of the optional file in a grammar directory
+// that maps grammar names to file extensions, e.g.
+//
+//	{"toml": {"extensions": [".toml"]}}
+//
+// Grammars it doesn't list are registered without extensions, so they are
+// only used when named explicitly as a Language.
+const GrammarManifest = "grammars.json"
+
+// grammarManifestEntry is the manifest entry for one grammar.
+type grammarManifestEntry struct {
+	Extensions []string `json:"extensions"`
+}
+
+// LoadGrammarDir loads the compiled tree-sitter grammars in dir and
+// registers each as a Language, returning their names in order. Grammars
+// are shared libraries (.so, or .dylib on macOS) built from a grammar's
+// parser.c and scanner.c, e.g.
+//
+//	cc -shared -fPIC -I src -o toml.so src/parser.c src/scanner.c
+//
+// A grammar is named after its file, without the extension and any "lib"
+// and "tree-sitter-" prefixes, so libtree-sitter-toml.so is "toml", and
+// must export the tree_sitter_<name> function tree-sitter generates, with
+// dashes in the name replaced by underscores. Its extensions come from the
+// GrammarManifest, if any.
+//
+// Loaded languages support Query only: they have no symbols, outline or
+// refs queries, so they are never auto-detected (see AutoDetect). Loading
+// needs a Unix system; WebAssembly grammars are not supported. If any
+// grammar fails to load, none are registered and their libraries are
+// unloaded. A registered grammar stays loaded for the life of the process,
+// and like Register, LoadGrammarDir must not run concurrently with
+// analyses.
+func LoadGrammarDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := readGrammarManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded []*pluginLanguage
+	var unloads []func() // of the libraries loaded, until all are registered
+	defer func() {
+		for _, unload := range unloads {
+			unload()
+		}
+	}()
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(entry.Name())
+		switch ext {
+		case ".so", ".dylib":
+		case ".wasm":
+			return nil, fmt.Errorf("grammar %s: WebAssembly grammars are not supported, build it as a shared library", entry.Name())
+		default:
+			continue
+		}
+
+		name := grammarName(entry.Name())
+		if Get(name) != nil {
+			return nil, fmt.Errorf("grammar %s: %s language already registered", entry.Name(), name)
+		}
+		symbol := "tree_sitter_" + strings.ReplaceAll(name, "-", "_")
+		lang, unload, err := openGrammar(filepath.Join(dir, entry.Name()), symbol)
+		if err != nil {
+			return nil, fmt.Errorf("grammar %s: %w", entry.Name(), err)
+		}
+		unloads = append(unloads, unload)
+		language := &pluginLanguage{name: name, extensions: manifest[name].Extensions, lang: lang}
+		if err := checkGrammarABI(language); err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, language)
+	}
+
+	unloads = nil
+	names := make([]string, len(loaded))
+	for i, language := range loaded {
+		Register(language)
+		names[i] = language.name
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readGrammarManifest reads the GrammarManifest of dir, if there is one.
+func readGrammarManifest(dir string) (map[string]grammarManifestEntry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, GrammarManifest))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest map[string]grammarManifestEntry
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", GrammarManifest, err)
+	}
+	for name, entry := range manifest {
+		for _, ext := range entry.Extensions {
+			if !strings.HasPrefix(ext, ".") {
+				return nil, fmt.Errorf("%s: %s extension %q must start with a dot", GrammarManifest, name, ext)
+			}
+		}
+	}
+	return manifest, nil
+}
+
+// grammarName returns the language name of a grammar library file.
+func grammarName(file string) string {
+	name := strings.TrimSuffix(file, filepath.Ext(file))
+	name = strings.TrimPrefix(name, "lib")
+	return strings.TrimPrefix(name, "tree-sitter-")
+}
+
+// pluginLanguage is a Language whose grammar was loaded by LoadGrammarDir.
+type pluginLanguage struct {
+	name       string
+	extensions []string
+	lang       *sitter.Language
+}
+
+func (p *pluginLanguage) Name() string {
+	return p.name
+}
+
+func (p *pluginLanguage) Extensions() []string {
+	return p.extensions
+}
+
+func (p *pluginLanguage) TreeSitterLang() *sitter.Language {
+	return p.lang
+}
+
+func (p *pluginLanguage) SymbolsQuery() string {
+	return ""
+}
+
+func (p *pluginLanguage) OutlineQuery() string {
+	return ""
+}
+
+func (p *pluginLanguage) RefsQuery() string {
+	return ""
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package tsq
+
+import (
+	"errors"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// openGrammar reports that grammar libraries can't be loaded on this system.
+func openGrammar(path, symbol string) (*sitter.Language, func(), error) {
+	return nil, nil, errors.New("loading grammars is only supported on Unix systems")
+}
@@ -0,0 +1,77 @@
+package tsq
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadGrammarDir builds the test grammar in testdata/grammars (generated
+// from its grammar.js) as a shared library and queries files with it.
+func TestLoadGrammarDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("grammar loading is Unix-only")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("cc not available")
+	}
+
+	dir := t.TempDir()
+	out, err := exec.Command(cc, "-shared", "-fPIC", "-o", filepath.Join(dir, "libtree-sitter-test_grammar.so"),
+		filepath.Join("testdata", "grammars", "test_grammar", "parser.c")).CombinedOutput()
+	require.NoError(t, err, string(out))
+	write := func(name, content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write(GrammarManifest, `{"test_grammar": {"extensions": [".tg"]}}`)
+
+	// A grammar that fails to load keeps the others from being registered.
+	broken := t.TempDir()
+	lib, err := os.ReadFile(filepath.Join(dir, "libtree-sitter-test_grammar.so"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(broken, "libtree-sitter-test_grammar.so"), lib, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(broken, "zz.so"), lib, 0644))
+	_, err = LoadGrammarDir(broken)
+	require.EqualError(t, err, "grammar zz.so: no tree_sitter_zz function")
+	require.Nil(t, Get("test_grammar"))
+
+	names, err := LoadGrammarDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"test_grammar"}, names)
+	require.Equal(t, "test_grammar", ByExtension(".tg").Name())
+
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "sum.tg"), []byte("1 + 2 // total\n"), 0644))
+	matches, err := Query(QueryOptions{
+		Query:    "(sum left: (expression (number) @left) right: (expression (number) @right))",
+		Language: "test_grammar",
+		Path:     src,
+	})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	require.Equal(t, "sum.tg", matches[0].File)
+	require.Equal(t, "1", matches[0].Captures[0].Text)
+	require.Equal(t, "2", matches[0].Captures[1].Text)
+
+	// Auto-detection leaves loaded grammars out: they have no symbols,
+	// outline or refs queries.
+	results, err := Symbols(SymbolsOptions{Path: src})
+	require.NoError(t, err)
+	require.Empty(t, results)
+	_, err = Outline(OutlineOptions{File: filepath.Join(src, "sum.tg")})
+	require.EqualError(t, err, filepath.Join(src, "sum.tg")+": the test_grammar grammar supports queries only")
+
+	_, err = LoadGrammarDir(dir)
+	require.EqualError(t, err, "grammar libtree-sitter-test_grammar.so: test_grammar language already registered")
+
+	wasm := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(wasm, "toml.wasm"), nil, 0644))
+	_, err = LoadGrammarDir(wasm)
+	require.EqualError(t, err, "grammar toml.wasm: WebAssembly grammars are not supported, build it as a shared library")
+}
@@ -0,0 +1,44 @@
+//go:build unix
+
+package tsq
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdlib.h>
+
+typedef const void *(*grammar_func)(void);
+
+static const void *call_grammar(void *f) {
+	return ((grammar_func)f)();
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// openGrammar loads the shared library at path and returns the grammar its
+// symbol function returns, and a function unloading the library, which
+// must not be called once the grammar is in use.
+func openGrammar(path, symbol string) (*sitter.Language, func(), error) {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	handle := C.dlopen(cpath, C.RTLD_NOW|C.RTLD_LOCAL)
+	if handle == nil {
+		return nil, nil, fmt.Errorf("load: %s", C.GoString(C.dlerror()))
+	}
+
+	csymbol := C.CString(symbol)
+	defer C.free(unsafe.Pointer(csymbol))
+	f := C.dlsym(handle, csymbol)
+	if f == nil {
+		C.dlclose(handle)
+		return nil, nil, fmt.Errorf("no %s function", symbol)
+	}
+	return sitter.NewLanguage(unsafe.Pointer(C.call_grammar(f))), func() { C.dlclose(handle) }, nil
+}
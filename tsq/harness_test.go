@@ -1,7 +1,9 @@
 package tsq
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -14,6 +16,9 @@ import (
 
 func TestDataDriven(t *testing.T) {
 	datadriven.Walk(t, "testdata", func(t *testing.T, path string) {
+		if strings.HasPrefix(path, filepath.Join("testdata", "grammars")) {
+			t.Skip("grammar sources for TestLoadGrammarDir")
+		}
 		// Create temp dir for this test file
 		tmpDir, err := os.MkdirTemp("", "tsq-test-*")
 		require.NoError(t, err)
@@ -34,6 +39,28 @@ func TestDataDriven(t *testing.T) {
 				return handleOutline(t, d, tmpDir, files)
 			case "refs":
 				return handleRefs(t, d, tmpDir, files)
+			case "api":
+				return handleAPI(t, d, tmpDir)
+			case "imports":
+				return handleImports(t, d, tmpDir)
+			case "comments":
+				return handleComments(t, d, tmpDir, files)
+			case "hotspots":
+				return handleHotspots(t, d, tmpDir)
+			case "todos":
+				return handleTodos(t, d, tmpDir)
+			case "info":
+				return handleInfo(t, d, tmpDir)
+			case "tests":
+				return handleTests(t, d, tmpDir)
+			case "shadows":
+				return handleShadows(t, d, tmpDir)
+			case "tree":
+				return handleTree(t, d, files)
+			case "stats":
+				return handleStats(t, d, tmpDir)
+			case "module":
+				return handleModule(t, d, tmpDir)
 			default:
 				t.Fatalf("unknown command: %s", d.Cmd)
 				return ""
@@ -55,8 +82,13 @@ func handleFile(
 	err := os.MkdirAll(filepath.Dir(absPath), 0755)
 	require.NoError(t, err)
 
+	content := d.Input
+	if d.HasArg("crlf") {
+		content = strings.ReplaceAll(content, "\n", "\r\n")
+	}
+
 	// Write file content
-	err = os.WriteFile(absPath, []byte(d.Input), 0644)
+	err = os.WriteFile(absPath, []byte(content), 0644)
 	require.NoError(t, err)
 
 	files[name] = absPath
@@ -68,7 +100,9 @@ func handleQuery(
 	t *testing.T, d *datadriven.TestData, tmpDir string, files map[string]string,
 ) string {
 	var query string
-	d.ScanArgs(t, "q", &query)
+	if d.HasArg("q") {
+		d.ScanArgs(t, "q", &query)
+	}
 
 	opts := QueryOptions{
 		Query:    query,
@@ -85,12 +119,67 @@ func handleQuery(
 		opts.Path = ""
 	}
 
+	if d.HasArg("find") {
+		d.ScanArgs(t, "find", &opts.Find)
+	}
+
+	if d.HasArg("name") {
+		d.ScanArgs(t, "name", &opts.Name)
+	}
+
+	if d.HasArg("receiver") {
+		d.ScanArgs(t, "receiver", &opts.Receiver)
+	}
+
+	if d.HasArg("max-per-file") {
+		d.ScanArgs(t, "max-per-file", &opts.MaxPerFile)
+	}
+
+	if d.HasArg("context-bytes") {
+		d.ScanArgs(t, "context-bytes", &opts.ContextBytes)
+	}
+
+	if d.HasArg("pattern") {
+		var pattern int
+		d.ScanArgs(t, "pattern", &pattern)
+		opts.PatternIndex = &pattern
+	}
+
+	if d.HasArg("at-line") {
+		d.ScanArgs(t, "at-line", &opts.AtLine)
+	}
+
+	if d.HasArg("require-child") {
+		d.ScanArgs(t, "require-child", &opts.RequireChild)
+	}
+
+	if d.HasArg("forbid-child") {
+		d.ScanArgs(t, "forbid-child", &opts.ForbidChild)
+	}
+
+	var diags []Diagnostic
+	opts.Diagnostics = &diags
+
+	var legend []LegendEntry
+	if d.HasArg("legend") {
+		opts.Legend = &legend
+	}
+
+	var coverage []PatternCount
+	if d.HasArg("coverage") {
+		opts.PatternCoverage = &coverage
+	}
+
 	results, err := Query(opts)
 	if err != nil {
 		return fmt.Sprintf("error: %s", err)
 	}
 
-	return formatQueryResults(results, tmpDir)
+	if d.HasArg("format") {
+		return formatAs(t, d, func(w io.Writer) error { return WriteRgMatches(w, results) }, QuickfixMatches(results))
+	}
+
+	return formatLegend(legend) + formatCoverage(coverage) + GoldenMatches(results) + formatDiagnostics(diags)
 }
 
 // handleSymbols runs Symbols() and formats results
@@ -111,10 +200,28 @@ func handleSymbols(
 		opts.Path = ""
 	}
 
+	if d.HasArg("dir") {
+		var dir string
+		d.ScanArgs(t, "dir", &dir)
+		opts.Path = filepath.Join(tmpDir, dir)
+	}
+
+	if d.HasArg("language") {
+		d.ScanArgs(t, "language", &opts.Language)
+	}
+
 	if d.HasArg("visibility") {
 		d.ScanArgs(t, "visibility", &opts.Visibility)
 	}
 
+	if d.HasArg("documented") {
+		d.ScanArgs(t, "documented", &opts.Documented)
+	}
+
+	if d.HasArg("doc") {
+		opts.WithDoc = true
+	}
+
 	if d.HasArg("source") {
 		opts.IncludeSource = true
 		if d.HasArg("maxlines") {
@@ -122,14 +229,123 @@ func handleSymbols(
 		} else {
 			opts.MaxSourceLines = 10
 		}
+		opts.Dedent = d.HasArg("dedent")
+		opts.SourceWithLocation = d.HasArg("location")
+	}
+
+	if d.HasArg("arity") {
+		opts.WithArity = true
 	}
 
+	if d.HasArg("body") {
+		opts.WithBody = true
+		opts.Dedent = d.HasArg("dedent")
+	}
+
+	if d.HasArg("anonymous") {
+		opts.IncludeAnonymous = true
+	}
+
+	if d.HasArg("promote") {
+		opts.PromoteEmbedded = true
+	}
+
+	if d.HasArg("group-blocks") {
+		opts.GroupDeclBlocks = true
+	}
+
+	if d.HasArg("index") {
+		opts.QualifyNames = true
+	}
+
+	if d.HasArg("path-pattern") {
+		d.ScanArgs(t, "path-pattern", &opts.PathPattern)
+	}
+
+	if d.HasArg("top") {
+		d.ScanArgs(t, "top", &opts.Top)
+	}
+
+	if d.HasArg("by") {
+		d.ScanArgs(t, "by", &opts.By)
+	}
+
+	if d.HasArg("strict") {
+		opts.StrictParse = true
+	}
+
+	if d.HasArg("resolve-iota") {
+		opts.ResolveIota = true
+	}
+
+	if d.HasArg("detect-enums") {
+		opts.DetectEnums = true
+	}
+
+	if d.HasArg("returns-error") {
+		opts.ReturnsError = true
+	}
+
+	if d.HasArg("method-names") {
+		opts.MethodQualifiedNames = true
+	}
+
+	if d.HasArg("layout") {
+		opts.StructLayout = true
+	}
+
+	if d.HasArg("outermost") {
+		opts.OutermostOnly = true
+	}
+
+	if d.HasArg("injections") {
+		opts.Injections = true
+	}
+
+	if d.HasArg("directives") {
+		opts.WithDirectives = true
+	}
+
+	if d.HasArg("diff") {
+		opts.ChangedLines = scanDiffArg(t, d, files)
+	}
+
+	if d.HasArg("hook") {
+		var hook string
+		d.ScanArgs(t, "hook", &hook)
+		switch hook {
+		case "upper":
+			opts.SymbolHook = func(sym *Symbol) bool {
+				sym.Name = strings.ToUpper(sym.Name)
+				return true
+			}
+		case "drop-vars":
+			opts.SymbolHook = func(sym *Symbol) bool {
+				return sym.Kind != "var"
+			}
+		default:
+			t.Fatalf("unknown hook: %s", hook)
+		}
+	}
+
+	var diags []Diagnostic
+	opts.Diagnostics = &diags
+
 	results, err := Symbols(opts)
 	if err != nil {
 		return fmt.Sprintf("error: %s", err)
 	}
 
-	return formatSymbolsResults(results)
+	if d.HasArg("index") {
+		return formatSymbolIndex(SymbolIndex(results))
+	}
+	if d.HasArg("tree") {
+		return formatTree(SymbolTree(results), "")
+	}
+	if d.HasArg("edges") {
+		return formatEdges(ContainmentEdges(results))
+	}
+	return formatSymbolsResults(results, opts, d.HasArg("signature")) + formatDiagnostics(diags)
 }
 
 // handleOutline runs Outline() and formats results
@@ -144,6 +360,10 @@ func handleOutline(
 		File:     files[fileName],
 	}
 
+	if d.HasArg("language") {
+		d.ScanArgs(t, "language", &opts.Language)
+	}
+
 	if d.HasArg("source") {
 		opts.IncludeSource = true
 		if d.HasArg("maxlines") {
@@ -151,13 +371,27 @@ func handleOutline(
 		} else {
 			opts.MaxSourceLines = 5
 		}
+		opts.Dedent = d.HasArg("dedent")
+	}
+
+	if d.HasArg("classify") {
+		opts.ClassifyImports = true
 	}
 
+	if d.HasArg("count-usage") {
+		opts.CountImportUsage = true
+	}
+
+	opts.WithDoc = d.HasArg("doc")
+
 	result, err := Outline(opts)
 	if err != nil {
 		return fmt.Sprintf("error: %s", err)
 	}
 
+	if d.HasArg("index-positions") {
+		return formatPositionIndex(PositionIndex(result))
+	}
 	return formatOutlineResult(result)
 }
 
@@ -175,6 +409,12 @@ func handleRefs(
 		Jobs:     1, // single-threaded for deterministic ordering
 	}
 
+	if d.HasArg("dir") {
+		var dir string
+		d.ScanArgs(t, "dir", &dir)
+		opts.Path = filepath.Join(tmpDir, dir)
+	}
+
 	if d.HasArg("file") {
 		var fileName string
 		d.ScanArgs(t, "file", &fileName)
@@ -182,45 +422,474 @@ func handleRefs(
 		opts.Path = ""
 	}
 
+	if d.HasArg("language") {
+		d.ScanArgs(t, "language", &opts.Language)
+	}
+
 	if d.HasArg("context") {
 		opts.IncludeContext = true
 	}
 
+	if d.HasArg("preserve-eol") {
+		opts.PreserveEOL = true
+	}
+
+	if d.HasArg("offsets") {
+		opts.IncludeOffsets = true
+	}
+
+	if d.HasArg("enclosing") {
+		opts.WithEnclosing = true
+	}
+
+	if d.HasArg("by-package") {
+		opts.ByPackage = true
+	}
+
+	if d.HasArg("id") {
+		opts.WithID = true
+	}
+
+	if d.HasArg("ref-node-types") {
+		var types string
+		d.ScanArgs(t, "ref-node-types", &types)
+		opts.RefNodeTypes = strings.Split(types, ",")
+	}
+
+	if d.HasArg("path-pattern") {
+		d.ScanArgs(t, "path-pattern", &opts.PathPattern)
+	}
+
+	if d.HasArg("diff") {
+		opts.ChangedLines = scanDiffArg(t, d, files)
+	}
+
 	result, err := Refs(opts)
 	if err != nil {
 		return fmt.Sprintf("error: %s", err)
 	}
 
+	// Offsets must span exactly the symbol in the file on disk.
+	if opts.IncludeOffsets {
+		for _, ref := range result.References {
+			source, err := os.ReadFile(filepath.Join(opts.Path, ref.File))
+			if opts.File != "" {
+				source, err = os.ReadFile(opts.File)
+			}
+			require.NoError(t, err)
+			require.Equal(t, symbol, string(source[ref.Offsets.Start:ref.Offsets.End]), "%s:%d", ref.File, ref.Position.Line)
+		}
+	}
+
+	if d.HasArg("format") {
+		return formatAs(t, d, func(w io.Writer) error { return WriteRgRefs(w, result.References) }, QuickfixRefs(result.References))
+	}
+
+	if opts.ByPackage {
+		var lines []string
+		for _, p := range result.Packages {
+			lines = append(lines, fmt.Sprintf("%s: %d", p.Package, p.Count))
+		}
+		return strings.Join(lines, "\n") + "\n" + formatRefsResult(result)
+	}
 	return formatRefsResult(result)
 }
 
-// formatQueryResults formats query matches as text
-func formatQueryResults(results []QueryMatch, tmpDir string) string {
+// scanDiffArg parses the unified diff in the file named by the diff= argument.
+func scanDiffArg(t *testing.T, d *datadriven.TestData, files map[string]string) map[string][]LineRange {
+	var name string
+	d.ScanArgs(t, "diff", &name)
+	f, err := os.Open(files[name])
+	require.NoError(t, err)
+	defer f.Close()
+	changed, err := ParseUnifiedDiff(f)
+	require.NoError(t, err)
+	return changed
+}
+
+// formatAs renders results in the format named by the format= argument: rg
+// output from writeRg, or quickfix entries as one JSON object per line.
+func formatAs(t *testing.T, d *datadriven.TestData, writeRg func(io.Writer) error, quickfix []QuickfixEntry) string {
+	var format string
+	d.ScanArgs(t, "format", &format)
+
+	var sb strings.Builder
+	switch format {
+	case "rg":
+		require.NoError(t, writeRg(&sb))
+	case "quickfix":
+		for _, entry := range quickfix {
+			data, err := json.Marshal(entry)
+			require.NoError(t, err)
+			sb.Write(data)
+			sb.WriteByte('\n')
+		}
+	default:
+		t.Fatalf("unknown format: %s", format)
+	}
+	return sb.String()
+}
+
+// handleAPI runs PublicAPI() and formats results
+func handleAPI(t *testing.T, d *datadriven.TestData, tmpDir string) string {
+	opts := PublicAPIOptions{
+		Language: "go",
+		Path:     tmpDir,
+		Jobs:     1,
+	}
+
+	if d.HasArg("dir") {
+		var dir string
+		d.ScanArgs(t, "dir", &dir)
+		opts.Path = filepath.Join(tmpDir, dir)
+	}
+
+	results, err := PublicAPI(opts)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+
+	return formatAPIResults(results)
+}
+
+// handleImports runs Imports() and formats the edge list
+func handleImports(t *testing.T, d *datadriven.TestData, tmpDir string) string {
+	opts := ImportsOptions{
+		Language: "go",
+		Path:     tmpDir,
+		Jobs:     1,
+	}
+
+	if d.HasArg("local") {
+		opts.LocalOnly = true
+	}
+
+	edges, err := Imports(opts)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+	if len(edges) == 0 {
+		return "(no imports)"
+	}
+
+	var lines []string
+	for _, e := range edges {
+		lines = append(lines, fmt.Sprintf("%s -> %s [%s]", e.From, e.To, e.Group))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleComments runs Comments() or, with undocumented, Undocumented()
+func handleComments(
+	t *testing.T, d *datadriven.TestData, tmpDir string, files map[string]string,
+) string {
+	opts := CommentsOptions{
+		Language: "go",
+		Path:     tmpDir,
+		Jobs:     1,
+	}
+
+	if d.HasArg("file") {
+		var fileName string
+		d.ScanArgs(t, "file", &fileName)
+		opts.File = files[fileName]
+		opts.Path = ""
+	}
+
+	if d.HasArg("undocumented") {
+		results, err := Undocumented(opts)
+		if err != nil {
+			return fmt.Sprintf("error: %s", err)
+		}
+		return formatSymbolsResults(results, SymbolsOptions{}, false)
+	}
+
+	results, err := Comments(opts)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
 	if len(results) == 0 {
-		return "(no matches)"
+		return "(no comments)"
 	}
 
 	var lines []string
-	for _, match := range results {
-		for _, cap := range match.Captures {
-			// Make file path relative to tmpDir for cleaner output
-			relFile := strings.TrimPrefix(match.File, tmpDir+"/")
-			line := fmt.Sprintf("@%s: %s (%s:%d:%d)",
-				cap.Name,
-				cap.Text,
-				relFile,
-				cap.Range.Start.Line,
-				cap.Range.Start.Column,
-			)
+	for _, r := range results {
+		for _, c := range r.Comments {
+			line := fmt.Sprintf("%s %d:%d %s", c.Kind, c.Range.Start.Line, c.Range.Start.Column, c.Text)
+			if c.Symbol != "" {
+				line += " -> " + c.Symbol
+			}
 			lines = append(lines, line)
 		}
 	}
+	return strings.Join(lines, "\n")
+}
+
+// handleHotspots runs Hotspots() and formats the ranking
+func handleHotspots(t *testing.T, d *datadriven.TestData, tmpDir string) string {
+	opts := HotspotsOptions{
+		Language: "go",
+		Path:     tmpDir,
+		Jobs:     1,
+	}
+
+	if d.HasArg("top") {
+		d.ScanArgs(t, "top", &opts.Top)
+	}
+
+	spots, err := Hotspots(opts)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+	if len(spots) == 0 {
+		return "(no symbols)"
+	}
+
+	var lines []string
+	for _, s := range spots {
+		line := fmt.Sprintf("%d %s %s (%s)", s.References, s.Kind, s.Name, s.File)
+		if s.Definitions > 1 {
+			line += fmt.Sprintf(" defined %d times", s.Definitions)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleTodos runs Todos() and formats one annotation per line
+func handleTodos(t *testing.T, d *datadriven.TestData, tmpDir string) string {
+	opts := TodosOptions{
+		Language: "go",
+		Path:     tmpDir,
+		Jobs:     1,
+	}
+
+	if d.HasArg("owner") {
+		d.ScanArgs(t, "owner", &opts.Owner)
+	}
+
+	todos, err := Todos(opts)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+	if len(todos) == 0 {
+		return "(no todos)"
+	}
+
+	var lines []string
+	for _, todo := range todos {
+		line := fmt.Sprintf("%s:%d %s", todo.File, todo.Line, todo.Marker)
+		if todo.Owner != "" {
+			line += "(" + todo.Owner + ")"
+		}
+		line += " " + todo.Text
+		if todo.Symbol != "" {
+			line += " in " + todo.Symbol
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleTests runs Tests() and formats results
+func handleTests(t *testing.T, d *datadriven.TestData, tmpDir string) string {
+	tests, err := Tests(TestsOptions{Path: tmpDir, Jobs: 1})
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+	if len(tests) == 0 {
+		return "(no tests)"
+	}
+
+	var lines []string
+	for _, test := range tests {
+		line := fmt.Sprintf("%s:%d:%d %s %s", test.File, test.Position.Line, test.Position.Column, test.Kind, test.Name)
+		if test.Target != "" {
+			line += " -> " + test.Target
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleShadows runs Shadows() and formats each shadow as
+// location name -> definition
+func handleShadows(t *testing.T, d *datadriven.TestData, tmpDir string) string {
+	path := tmpDir
+	if d.HasArg("dir") {
+		var dir string
+		d.ScanArgs(t, "dir", &dir)
+		path = filepath.Join(tmpDir, dir)
+	}
+
+	shadows, err := Shadows(ShadowsOptions{Path: path, Jobs: 1})
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+	if len(shadows) == 0 {
+		return "(no shadows)"
+	}
+
+	var lines []string
+	for _, s := range shadows {
+		lines = append(lines, fmt.Sprintf("%s:%d:%d %s -> %s:%d:%d", s.Location.File, s.Location.Position.Line, s.Location.Position.Column,
+			s.Name, s.Definition.File, s.Definition.Position.Line, s.Definition.Position.Column))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleStats runs Stats() and formats one line per language:
+// language files=N lines=N kind=N...
+func handleStats(t *testing.T, d *datadriven.TestData, tmpDir string) string {
+	opts := StatsOptions{Path: tmpDir, Jobs: 1, AllLanguages: d.HasArg("all-languages")}
+	if d.HasArg("language") {
+		d.ScanArgs(t, "language", &opts.Language)
+	}
 
+	stats, err := Stats(opts)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+	if len(stats) == 0 {
+		return "(no files)"
+	}
+
+	var lines []string
+	for _, s := range stats {
+		line := fmt.Sprintf("%s files=%d lines=%d", s.Language, s.Files, s.Lines)
+		kinds := make([]string, 0, len(s.Symbols))
+		for kind := range s.Symbols {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			line += fmt.Sprintf(" %s=%d", kind, s.Symbols[kind])
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleModule runs Module() on dir= (relative to the temp directory) and
+// formats the result like a go.mod file, with paths relative to the temp
+// directory
+func handleModule(t *testing.T, d *datadriven.TestData, tmpDir string) string {
+	path := tmpDir
+	if d.HasArg("dir") {
+		var dir string
+		d.ScanArgs(t, "dir", &dir)
+		path = filepath.Join(tmpDir, dir)
+	}
+
+	info, err := Module(ModuleOptions{Path: path})
+	if err != nil {
+		return "error: " + strings.ReplaceAll(err.Error(), tmpDir, "$TMP")
+	}
+
+	gomod, err := filepath.Rel(tmpDir, info.GoMod)
+	require.NoError(t, err)
+	lines := []string{"file " + filepath.ToSlash(gomod), "module " + info.Path}
+	if info.GoVersion != "" {
+		lines = append(lines, "go "+info.GoVersion)
+	}
+	if info.Toolchain != "" {
+		lines = append(lines, "toolchain "+info.Toolchain)
+	}
+	for _, req := range info.Requires {
+		line := "require " + req.Path + " " + req.Version
+		if req.Replace != nil {
+			line = strings.TrimSpace(line + " => " + req.Replace.Path + " " + req.Replace.Version)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleTree runs SyntaxTree() and formats the tree as an s-expression, or
+// as JSON with format=json
+func handleTree(t *testing.T, d *datadriven.TestData, files map[string]string) string {
+	var fileName string
+	d.ScanArgs(t, "file", &fileName)
+
+	opts := SyntaxTreeOptions{File: files[fileName], NamedOnly: d.HasArg("named-only")}
+	if d.HasArg("max-depth") {
+		d.ScanArgs(t, "max-depth", &opts.MaxDepth)
+	}
+
+	root, err := SyntaxTree(opts)
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+
+	if d.HasArg("format") {
+		data, err := json.MarshalIndent(root, "", "  ")
+		require.NoError(t, err)
+		return string(data)
+	}
+	return root.Sexp()
+}
+
+// formatCoverage formats pattern coverage as a header line, or "" if there
+// is none.
+func formatCoverage(coverage []PatternCount) string {
+	if coverage == nil {
+		return ""
+	}
+	parts := make([]string, len(coverage))
+	for i, c := range coverage {
+		parts[i] = fmt.Sprintf("#%d=%d", c.Pattern, c.Matches)
+	}
+	return "coverage: " + strings.Join(parts, " ") + "\n"
+}
+
+// formatLegend formats a capture legend as a header line, or "" if there is
+// none
+func formatLegend(legend []LegendEntry) string {
+	if legend == nil {
+		return ""
+	}
+	parts := make([]string, len(legend))
+	for i, e := range legend {
+		parts[i] = fmt.Sprintf("@%s=%d", e.Name, e.Captures)
+	}
+	return "legend: " + strings.Join(parts, " ") + "\n"
+}
+
+// handleInfo runs Info() and formats definitions followed by references
+func handleInfo(t *testing.T, d *datadriven.TestData, tmpDir string) string {
+	var symbol string
+	d.ScanArgs(t, "symbol", &symbol)
+
+	info, err := Info(InfoOptions{
+		Symbol:   symbol,
+		Language: "go",
+		Path:     tmpDir,
+		Jobs:     1,
+	})
+	if err != nil {
+		return fmt.Sprintf("error: %s", err)
+	}
+
+	var lines []string
+	for _, def := range info.Definitions {
+		line := fmt.Sprintf("definition %s %s:%d", def.Kind, def.File, def.Range.Start.Line)
+		if def.Signature != "" {
+			line += " | " + def.Signature
+		}
+		if def.Doc != "" {
+			line += "\n" + indentLines(def.Doc, "  ")
+		}
+		lines = append(lines, line)
+	}
+	if len(info.Definitions) == 0 {
+		lines = append(lines, "(no definitions)")
+	}
+	lines = append(lines, formatRefsResult(&RefsResult{Symbol: info.Symbol, References: info.References}))
 	return strings.Join(lines, "\n")
 }
 
 // formatSymbolsResults formats symbols as text
-func formatSymbolsResults(results []SymbolsResult) string {
+func formatSymbolsResults(results []SymbolsResult, opts SymbolsOptions, signatures bool) string {
 	if len(results) == 0 {
 		return "(no symbols)"
 	}
@@ -233,7 +902,7 @@ func formatSymbolsResults(results []SymbolsResult) string {
 				// Method with receiver
 				line = fmt.Sprintf("%s (%s) %s %s",
 					sym.Kind,
-					sym.Receiver,
+					receiverString(sym),
 					sym.Name,
 					sym.Visibility,
 				)
@@ -246,11 +915,155 @@ func formatSymbolsResults(results []SymbolsResult) string {
 				)
 			}
 
+			if sym.TypeParams != "" {
+				line += " " + sym.TypeParams
+			}
+
+			if opts.WithArity && (sym.Kind == "function" || sym.Kind == "method" || sym.Kind == "closure") {
+				line += fmt.Sprintf(" params=%d results=%d", *sym.NumParams, *sym.NumResults)
+				if sym.ReturnsError {
+					line += " returns-error"
+				}
+			}
+
+			if sym.Enclosing != "" {
+				line += " in " + sym.Enclosing
+			}
+
+			if sym.Value != "" {
+				line += " = " + sym.Value
+			}
+
+			if len(sym.Embeds) > 0 {
+				line += " embeds " + strings.Join(sym.Embeds, ",")
+			}
+
+			if sym.LayoutHint != "" {
+				line += " layout: " + sym.LayoutHint
+			}
+
+			if sym.Promoted {
+				line += " promoted from " + sym.PromotedFrom
+			}
+
+			if signatures && sym.Signature != "" {
+				line += " sig: " + sym.Signature
+			}
+
+			if sym.Doc != "" {
+				line += " doc: " + strings.ReplaceAll(sym.Doc, "\n", " | ")
+			}
+
+			if len(sym.Directives) > 0 {
+				line += " directives: " + strings.Join(sym.Directives, ", ")
+			}
+
+			if sym.Language != "" {
+				line += fmt.Sprintf(" (%s at %d:%d)", sym.Language, sym.Range.Start.Line, sym.Range.Start.Column)
+			}
+
+			for _, m := range sym.Members {
+				line += fmt.Sprintf("\n  member %s %s %s", m.Kind, m.Name, m.Visibility)
+				if m.Value != "" {
+					line += " = " + m.Value
+				}
+			}
+
 			if sym.Source != "" {
 				// Include source on separate lines, indented
 				line += "\n" + indentLines(sym.Source, "  ")
 			}
 
+			if sym.Body != "" {
+				line += "\n  body:\n" + indentLines(sym.Body, "    ")
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// formatEdges formats containment edges as parent -> child lines.
+func formatEdges(edges []ContainmentEdge) string {
+	if len(edges) == 0 {
+		return "(no edges)"
+	}
+	lines := make([]string, len(edges))
+	for i, e := range edges {
+		lines[i] = e.ParentID + " -> " + e.ChildID
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatTree formats a directory tree as indented text, sorted by name.
+// Directories end in a slash and files list their symbol names.
+func formatTree(tree map[string]any, indent string) string {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		switch v := tree[name].(type) {
+		case map[string]any:
+			lines = append(lines, indent+name+"/", formatTree(v, indent+"  "))
+		case []Symbol:
+			symNames := make([]string, len(v))
+			for i, sym := range v {
+				symNames[i] = sym.Name
+			}
+			lines = append(lines, fmt.Sprintf("%s%s: %s", indent, name, strings.Join(symNames, ", ")))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatSymbolIndex formats a symbol index as text, sorted by key
+func formatSymbolIndex(index map[string][]IndexEntry) string {
+	if len(index) == 0 {
+		return "(no symbols)"
+	}
+
+	keys := make([]string, 0, len(index))
+	for k := range index {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, k := range keys {
+		for _, e := range index[k] {
+			line := fmt.Sprintf("%s: %s %s:%d", k, e.Kind, e.File, e.Range.Start.Line)
+			if e.Signature != "" {
+				line += " | " + e.Signature
+			}
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatAPIResults formats package APIs as text
+func formatAPIResults(results []PackageAPI) string {
+	if len(results) == 0 {
+		return "(no exported symbols)"
+	}
+
+	var lines []string
+	for _, pkg := range results {
+		lines = append(lines, fmt.Sprintf("package %s (%s)", pkg.Package, pkg.Dir))
+		for _, sym := range pkg.Symbols {
+			line := fmt.Sprintf("  %s %s", sym.Kind, sym.QualifiedName)
+			if sym.Signature != "" {
+				line += " | " + sym.Signature
+			}
+			if sym.Doc != "" {
+				line += " doc: " + strings.ReplaceAll(sym.Doc, "\n", " | ")
+			}
 			lines = append(lines, line)
 		}
 	}
@@ -269,11 +1082,31 @@ func formatOutlineResult(outline FileOutline) string {
 	if len(outline.Imports) > 0 {
 		lines = append(lines, "imports:")
 		for _, imp := range outline.Imports {
+			line := "  " + imp.Path
 			if imp.Alias != "" {
-				lines = append(lines, fmt.Sprintf("  %s (alias: %s)", imp.Path, imp.Alias))
-			} else {
-				lines = append(lines, fmt.Sprintf("  %s", imp.Path))
+				line += fmt.Sprintf(" (alias: %s)", imp.Alias)
 			}
+			if imp.Group != "" {
+				line += fmt.Sprintf(" [%s]", imp.Group)
+			}
+			if imp.UsageCount != nil {
+				line += fmt.Sprintf(" uses=%d", *imp.UsageCount)
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if len(outline.Errors) > 0 {
+		lines = append(lines, "errors:")
+		for _, r := range outline.Errors {
+			lines = append(lines, fmt.Sprintf("  %d:%d-%d:%d", r.Start.Line, r.Start.Column, r.End.Line, r.End.Column))
+		}
+	}
+
+	if len(outline.Embeds) > 0 {
+		lines = append(lines, "embeds:")
+		for _, pattern := range outline.Embeds {
+			lines = append(lines, "  "+pattern)
 		}
 	}
 
@@ -284,7 +1117,7 @@ func formatOutlineResult(outline FileOutline) string {
 			if sym.Receiver != "" {
 				symLine = fmt.Sprintf("  %s (%s) %s %s",
 					sym.Kind,
-					sym.Receiver,
+					receiverString(sym),
 					sym.Name,
 					sym.Visibility,
 				)
@@ -296,6 +1129,10 @@ func formatOutlineResult(outline FileOutline) string {
 				)
 			}
 
+			if sym.Doc != "" {
+				symLine += " doc: " + strings.ReplaceAll(sym.Doc, "\n", " | ")
+			}
+
 			if sym.Source != "" {
 				symLine += "\n" + indentLines(sym.Source, "    ")
 			}
@@ -339,6 +1176,22 @@ func formatRefsResult(result *RefsResult) string {
 			ref.Position.Column,
 		)
 
+		if ref.Offsets != nil {
+			line += fmt.Sprintf(" bytes=%d-%d", ref.Offsets.Start, ref.Offsets.End)
+		}
+
+		if ref.EnclosingSymbol != "" {
+			line += " in " + ref.EnclosingSymbol
+		}
+
+		if ref.Package != "" {
+			line += " package " + ref.Package
+		}
+
+		if ref.ID != "" {
+			line += " id=" + ref.ID
+		}
+
 		if ref.Context != "" {
 			line += fmt.Sprintf(" | %s", ref.Context)
 		}
@@ -349,6 +1202,44 @@ func formatRefsResult(result *RefsResult) string {
 	return strings.Join(lines, "\n")
 }
 
+// formatPositionIndex formats position index entries as
+// "start-end kind name", one per line
+func formatPositionIndex(entries []PositionEntry) string {
+	if len(entries) == 0 {
+		return "(no symbols)"
+	}
+	var lines []string
+	for _, e := range entries {
+		name := e.Name
+		if e.Receiver != "" {
+			name = e.Receiver + "." + name
+		}
+		lines = append(lines, fmt.Sprintf("%d:%d-%d:%d %s %s",
+			e.Start.Line, e.Start.Column, e.End.Line, e.End.Column, e.Kind, name))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatDiagnostics formats diagnostics as trailing lines
+func formatDiagnostics(diags []Diagnostic) string {
+	var sb strings.Builder
+	for _, diag := range diags {
+		fmt.Fprintf(&sb, "\ndiagnostic: %s %s", diag.Kind, diag.File)
+		if diag.Message != "" {
+			fmt.Fprintf(&sb, " (%s)", diag.Message)
+		}
+	}
+	return sb.String()
+}
+
+// receiverString formats a method receiver, marking pointer receivers with *
+func receiverString(sym Symbol) string {
+	if sym.ReceiverPointer {
+		return "*" + sym.Receiver
+	}
+	return sym.Receiver
+}
+
 // indentLines adds indent prefix to each line
 func indentLines(text, indent string) string {
 	lines := strings.Split(text, "\n")
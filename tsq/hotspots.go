@@ -0,0 +1,160 @@
+package tsq
+
+import (
+	"errors"
+	"runtime"
+	"sort"
+)
+
+// Hotspot is a symbol name ranked by how often it is referenced.
+type Hotspot struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	File        string `json:"file"`        // file of the first definition
+	References  int    `json:"references"`  // references excluding definitions
+	Definitions int    `json:"definitions"` // >1 when several symbols share the name
+}
+
+// Hotspots ranks the top-level symbols declared under a path by reference count,
+// most referenced first. References are matched by name in a single refs
+// pass over all files, so symbols sharing a name share a count.
+func Hotspots(opts HotspotsOptions) ([]Hotspot, error) {
+	if opts.Language == "" {
+		opts.Language = "go"
+	}
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.Jobs == 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+
+	language := Get(opts.Language)
+	if language == nil {
+		return nil, errors.New(opts.Language + " language not registered")
+	}
+
+	symbolsQuery, err := newQuery(language.SymbolsQuery(), language)
+	if err != nil {
+		return nil, err
+	}
+	refsQuery, err := newQuery(language.RefsQuery(), language)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := newScanner(scannerConfig{
+		root:     opts.Path,
+		language: language,
+		maxBytes: opts.MaxBytes,
+	})
+	files, err := sc.collect()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return []Hotspot{}, nil
+	}
+
+	type location struct {
+		file         string
+		line, column int
+	}
+	type definition struct {
+		sym Symbol
+		loc location
+	}
+	cfg := workerConfig{jobs: opts.Jobs}
+
+	// Pass 1: declarations and the positions of their names
+	defs := runWorkers(language, symbolsQuery, files, cfg, func(job FileJob, matches []QueryMatch, _ []byte) []definition {
+		var out []definition
+		for _, match := range matches {
+			sym := parseSymbolFromMatch(match, sourceOptions{})
+			if sym == nil || sym.Kind == "closure" {
+				continue
+			}
+			name, _ := findCapture(match, "name")
+			if (sym.Kind == "var" || sym.Kind == "const") && insideFunction(matches, name.Range.Start) {
+				continue
+			}
+			out = append(out, definition{
+				sym: *sym,
+				loc: location{job.DisplayPath, name.Range.Start.Line, name.Range.Start.Column},
+			})
+		}
+		return out
+	})
+
+	spots := make(map[string]*Hotspot)
+	declared := make(map[location]bool)
+	sort.Slice(defs, func(i, j int) bool {
+		a, b := defs[i].loc, defs[j].loc
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		return a.line < b.line || (a.line == b.line && a.column < b.column)
+	})
+	for _, d := range defs {
+		declared[d.loc] = true
+		if spot, ok := spots[d.sym.Name]; ok {
+			spot.Definitions++
+			continue
+		}
+		spots[d.sym.Name] = &Hotspot{Name: d.sym.Name, Kind: d.sym.Kind, File: d.sym.File, Definitions: 1}
+	}
+
+	// Pass 2: one refs pass, bucketing captures by name. A position is
+	// counted once even when several refs patterns capture it.
+	refs := runWorkers(language, refsQuery, files, cfg, func(job FileJob, matches []QueryMatch, _ []byte) []string {
+		seen := make(map[location]bool)
+		var names []string
+		for _, match := range matches {
+			for _, c := range match.Captures {
+				if _, ok := spots[c.Text]; !ok {
+					continue
+				}
+				loc := location{job.DisplayPath, c.Range.Start.Line, c.Range.Start.Column}
+				if seen[loc] || declared[loc] {
+					continue
+				}
+				seen[loc] = true
+				names = append(names, c.Text)
+			}
+		}
+		return names
+	})
+	for _, name := range refs {
+		spots[name].References++
+	}
+
+	ranked := make([]Hotspot, 0, len(spots))
+	for _, spot := range spots {
+		ranked = append(ranked, *spot)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].References != ranked[j].References {
+			return ranked[i].References > ranked[j].References
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+
+	if opts.Top > 0 && len(ranked) > opts.Top {
+		ranked = ranked[:opts.Top]
+	}
+	return ranked, nil
+}
+
+// insideFunction reports whether pos lies within a function, method or
+// closure body.
+func insideFunction(matches []QueryMatch, pos Position) bool {
+	for _, match := range matches {
+		if decl, ok := declCapture(match); ok && rangeContains(decl.Range, pos) {
+			switch decl.Name {
+			case "function", "method", "closure":
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package tsq
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// ImportEdge is a dependency from one package to a package it imports.
+type ImportEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Group string `json:"group"` // std, third_party, local
+}
+
+// Imports returns the package import graph under a path as a deduplicated,
+// sorted edge list. Packages are identified by import path when a go.mod is
+// found above Path, and by directory otherwise. Test files are skipped.
+func Imports(opts ImportsOptions) ([]ImportEdge, error) {
+	if opts.Language == "" {
+		opts.Language = "go"
+	}
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.Jobs == 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+
+	language := Get(opts.Language)
+	if language == nil {
+		return nil, errors.New(opts.Language + " language not registered")
+	}
+
+	query, err := newQuery(language.OutlineQuery(), language)
+	if err != nil {
+		return nil, err
+	}
+
+	absRoot, err := filepath.Abs(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	modulePath, moduleDir := findModule(absRoot)
+
+	sc := newScanner(scannerConfig{
+		root:     opts.Path,
+		language: language,
+		maxBytes: opts.MaxBytes,
+	})
+	files, err := sc.collect()
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []FileJob
+	for _, f := range files {
+		if !strings.HasSuffix(f.DisplayPath, "_test.go") {
+			sources = append(sources, f)
+		}
+	}
+	if len(sources) == 0 {
+		return []ImportEdge{}, nil
+	}
+
+	cfg := workerConfig{jobs: opts.Jobs}
+	edges := runWorkers(language, query, sources, cfg, func(job FileJob, matches []QueryMatch, _ []byte) []ImportEdge {
+		from := packageImportPath(filepath.Dir(job.AbsPath), modulePath, moduleDir)
+		if from == "" {
+			from = path.Dir(job.DisplayPath)
+		}
+
+		var out []ImportEdge
+		for _, match := range matches {
+			imp, ok := findCapture(match, "path")
+			if !ok {
+				continue
+			}
+			to := strings.Trim(imp.Text, `"`)
+			group := classifyImport(to, modulePath)
+			if opts.LocalOnly && group != "local" {
+				continue
+			}
+			out = append(out, ImportEdge{From: from, To: to, Group: group})
+		}
+		return out
+	})
+
+	return dedupeEdges(edges), nil
+}
+
+// dedupeEdges sorts edges by From then To and removes duplicates.
+func dedupeEdges(edges []ImportEdge) []ImportEdge {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+
+	unique := []ImportEdge{}
+	for i, e := range edges {
+		if i > 0 && e == edges[i-1] {
+			continue
+		}
+		unique = append(unique, e)
+	}
+	return unique
+}
@@ -0,0 +1,121 @@
+package tsq
+
+import "slices"
+
+// IndexEntry is the definition location of a symbol in a SymbolIndex.
+type IndexEntry struct {
+	File      string `json:"file"`
+	Range     Range  `json:"range"`
+	Kind      string `json:"kind"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// SymbolIndex flattens per-file symbols into a single table keyed by
+// qualified name, for building jump-to-definition indexes. Each key maps to
+// every definition with that name, in result order, so collisions (e.g. the
+// same function in files with different build tags) are kept. Symbols
+// without a QualifiedName (see SymbolsOptions.QualifyNames) are keyed by
+// Name, and members of grouped const/var blocks are indexed individually.
+func SymbolIndex(results []SymbolsResult) map[string][]IndexEntry {
+	index := make(map[string][]IndexEntry)
+
+	var add func(file string, symbols []Symbol)
+	add = func(file string, symbols []Symbol) {
+		for _, sym := range symbols {
+			if len(sym.Members) > 0 {
+				add(file, sym.Members)
+				continue
+			}
+			key := sym.QualifiedName
+			if key == "" {
+				key = sym.Name
+			}
+			index[key] = append(index[key], IndexEntry{
+				File:      file,
+				Range:     sym.Range,
+				Kind:      sym.Kind,
+				Signature: sym.Signature,
+			})
+		}
+	}
+
+	for _, r := range results {
+		add(r.File, r.Symbols)
+	}
+	return index
+}
+
+// PositionEntry is a declaration in a PositionIndex. Start is its first
+// character and End its last, so a position p is inside the declaration when
+// Start <= p <= End.
+type PositionEntry struct {
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"`
+	Receiver string   `json:"receiver,omitempty"`
+	Start    Position `json:"start"`
+	End      Position `json:"end"`
+}
+
+// PositionIndex lists the declarations of an outline sorted by start
+// position, for binary searching the symbol at a line. The sort is stable,
+// so declarations starting at the same position keep their outline order.
+// Nested declarations, such as the members of a grouped block, follow the
+// one containing them.
+func PositionIndex(outline FileOutline) []PositionEntry {
+	entries := []PositionEntry{}
+	var add func(symbols []Symbol)
+	add = func(symbols []Symbol) {
+		for _, sym := range symbols {
+			end := sym.Range.End
+			if end.Column > 1 {
+				end.Column-- // Range.End is just past the last character
+			}
+			entries = append(entries, PositionEntry{
+				Name:     sym.Name,
+				Kind:     sym.Kind,
+				Receiver: sym.Receiver,
+				Start:    sym.Range.Start,
+				End:      end,
+			})
+			add(sym.Members)
+		}
+	}
+	add(outline.Symbols)
+
+	slices.SortStableFunc(entries, func(a, b PositionEntry) int {
+		return comparePositions(a.Start, b.Start)
+	})
+	return entries
+}
+
+// methodQualifyNames renames methods in results to Receiver.Method, with
+// type arguments dropped from the receiver (Set[K].Add becomes Set.Add).
+// Functions and other symbols keep their bare names.
+func methodQualifyNames(results []SymbolsResult) {
+	for _, r := range results {
+		for i := range r.Symbols {
+			if sym := &r.Symbols[i]; sym.Kind == "method" && sym.Receiver != "" {
+				sym.Name = receiverTypeName(sym.Receiver) + "." + sym.Name
+			}
+		}
+	}
+}
+
+// qualifySymbols sets QualifiedName on symbols (and grouped members) using
+// the package clause found in matches.
+func qualifySymbols(matches []QueryMatch, symbols []Symbol) {
+	var pkg string
+	for _, match := range matches {
+		if c, ok := findCapture(match, "package"); ok {
+			pkg = c.Text
+			break
+		}
+	}
+
+	for i := range symbols {
+		symbols[i].QualifiedName = qualifiedName(pkg, symbols[i])
+		for j := range symbols[i].Members {
+			symbols[i].Members[j].QualifiedName = qualifiedName(pkg, symbols[i].Members[j])
+		}
+	}
+}
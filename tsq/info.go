@@ -0,0 +1,101 @@
+package tsq
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// SymbolInfo gathers everything known about a symbol name: where it is
+// defined, its doc comments and where it is referenced.
+type SymbolInfo struct {
+	Symbol      string      `json:"symbol"`
+	Definitions []Symbol    `json:"definitions"`
+	References  []Reference `json:"references"`
+}
+
+// Info combines Symbols, Comments and Refs for a single symbol name.
+// Definitions carry their signature and, when present, their doc comment.
+// Both lists are ordered by file and position.
+func Info(opts InfoOptions) (*SymbolInfo, error) {
+	if opts.Symbol == "" {
+		return nil, errors.New("symbol is required")
+	}
+	if opts.Language == "" {
+		opts.Language = "go" // as Comments, which doesn't auto-detect
+	}
+
+	results, err := Symbols(SymbolsOptions{
+		Language: opts.Language,
+		Path:     opts.Path,
+		Jobs:     opts.Jobs,
+		MaxBytes: opts.MaxBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := Comments(CommentsOptions{
+		Language: opts.Language,
+		Path:     opts.Path,
+		Jobs:     opts.Jobs,
+		MaxBytes: opts.MaxBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := Refs(RefsOptions{
+		Symbol:         opts.Symbol,
+		Language:       opts.Language,
+		Path:           opts.Path,
+		IncludeContext: opts.IncludeContext,
+		Jobs:           opts.Jobs,
+		MaxBytes:       opts.MaxBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Doc comment lines per file and declaration name
+	docs := make(map[string][]string)
+	for _, r := range comments {
+		for _, c := range r.Comments {
+			if c.Kind == "doc" {
+				key := r.File + "\x00" + c.Symbol
+				docs[key] = append(docs[key], commentText(c.Text))
+			}
+		}
+	}
+
+	info := &SymbolInfo{
+		Symbol:      opts.Symbol,
+		Definitions: []Symbol{},
+		References:  refs.References,
+	}
+	for _, r := range results {
+		for _, sym := range r.Symbols {
+			if sym.Name != opts.Symbol {
+				continue
+			}
+			sym.Doc = strings.Join(docs[r.File+"\x00"+declName(sym)], "\n")
+			info.Definitions = append(info.Definitions, sym)
+		}
+	}
+
+	sort.SliceStable(info.Definitions, func(i, j int) bool {
+		a, b := info.Definitions[i], info.Definitions[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return positionBefore(a.Range.Start, b.Range.Start)
+	})
+	sort.SliceStable(info.References, func(i, j int) bool {
+		a, b := info.References[i], info.References[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return positionBefore(a.Position, b.Position)
+	})
+	return info, nil
+}
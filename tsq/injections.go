@@ -0,0 +1,139 @@
+package tsq
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// injectionsQuery returns the injections query of language, or "" if it
+// doesn't implement InjectionsProvider.
+func injectionsQuery(language Language) string {
+	if p, ok := language.(InjectionsProvider); ok {
+		return p.InjectionsQuery()
+	}
+	return ""
+}
+
+// injectedQueries caches the compiled symbols queries of injected languages
+// by language name, shared by all workers. A nil query means it failed to
+// compile.
+var injectedQueries = struct {
+	mu      sync.Mutex
+	queries map[string]*query
+}{queries: make(map[string]*query)}
+
+func injectedSymbolsQuery(language Language) *query {
+	injectedQueries.mu.Lock()
+	defer injectedQueries.mu.Unlock()
+	q, ok := injectedQueries.queries[language.Name()]
+	if !ok {
+		q, _ = newQuery(language.SymbolsQuery(), language)
+		injectedQueries.queries[language.Name()] = q
+	}
+	return q
+}
+
+// injectedSymbols returns the symbols of the code marked by the injection
+// matches among matches, each parsed with the language it is tagged with.
+// The code is parsed in place in source, restricted to its range, so
+// positions are those of the host file.
+func injectedSymbols(matches []QueryMatch, source []byte, opts SymbolsOptions) []Symbol {
+	opts.Injections = false
+	var symbols []Symbol
+	seen := make(map[int]bool)          // content start byte
+	parsers := make(map[string]*parser) // by language name
+	defer func() {
+		for _, p := range parsers {
+			p.parser.Close()
+		}
+	}()
+	for _, match := range matches {
+		tag, ok := findCapture(match, "injection.language")
+		if !ok {
+			continue
+		}
+		content, ok := findCapture(match, "injection.content")
+		if !ok || seen[content.startByte] {
+			continue
+		}
+		language := injectionLanguage(tag.Text)
+		if language == nil {
+			continue
+		}
+		r, ok := injectionRange(content)
+		if !ok {
+			continue
+		}
+		query := injectedSymbolsQuery(language)
+		p, ok := parsers[language.Name()]
+		if !ok {
+			p = newParser(language)
+			parsers[language.Name()] = p
+		}
+		if query == nil || p.err != nil {
+			continue
+		}
+		seen[content.startByte] = true
+		opts.Language = language.Name()
+
+		p.parser.SetIncludedRanges([]sitter.Range{r})
+		tree := p.parse(source)
+		for _, sym := range fileSymbols(query.run(tree.RootNode(), source, match.File), source, opts) {
+			sym.Language = language.Name()
+			symbols = append(symbols, sym)
+		}
+		tree.Close()
+	}
+	return symbols
+}
+
+// injectionMarker matches a comment that is nothing but an injection
+// marker, as in /* sql */ or // language=sql.
+var injectionMarker = regexp.MustCompile(`^(?://|/\*)\s*(language=)?([\w+#-]+)\s*(?:\*/)?$`)
+
+// bareInjectionLanguages are the languages a marker may name without the
+// "language=" prefix. Other names, like the go of a "// go" comment, are
+// more likely prose than markers.
+var bareInjectionLanguages = map[string]bool{"sql": true}
+
+// injectionLanguage returns the registered language named by the comment of
+// an @injection.language capture, or nil if it is not a marker: its whole
+// text must be the name of a language, prefixed with "language=" unless
+// the language is in bareInjectionLanguages.
+func injectionLanguage(text string) Language {
+	m := injectionMarker.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return nil
+	}
+	name := strings.ToLower(m[2])
+	if m[1] == "" && !bareInjectionLanguages[name] {
+		return nil
+	}
+	return Get(name)
+}
+
+// injectionRange returns the range of the code in an @injection.content
+// capture. The quotes of string literals are left out; they are one byte
+// wide in every supported language. It reports false if the code is empty.
+func injectionRange(c CaptureResult) (sitter.Range, bool) {
+	start, end := c.Range.Start, c.Range.End
+	startByte, endByte := c.startByte, c.endByte
+	if strings.HasSuffix(c.NodeType, "string_literal") && endByte-startByte >= 2 {
+		startByte++
+		endByte--
+		start.Column++
+		end.Column--
+	}
+	if startByte >= endByte {
+		return sitter.Range{}, false
+	}
+	return sitter.Range{
+		StartPoint: sitter.Point{Row: uint32(start.Line - 1), Column: uint32(start.Column - 1)},
+		EndPoint:   sitter.Point{Row: uint32(end.Line - 1), Column: uint32(end.Column - 1)},
+		StartByte:  uint32(startByte),
+		EndByte:    uint32(endByte),
+	}, true
+}
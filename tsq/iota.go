@@ -0,0 +1,199 @@
+package tsq
+
+import (
+	"math/big"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// maxIotaShift bounds the shift counts resolveIota evaluates, so a
+// pathological constant can't allocate an enormous integer.
+const maxIotaShift = 1024
+
+// resolveIota computes the integer value of each constant in a const block
+// that uses iota, keyed by the position of the constant's name. Specs without
+// a value repeat the previous spec's expressions with the next iota, as in
+// Go. Values are only reported for expressions built from integer literals,
+// iota, earlier constants of the block, conversions and the integer
+// operators; anything else, such as floats or calls, leaves the constant
+// out. block is the const_declaration capture.
+func resolveIota(block CaptureResult) map[Position]string {
+	if block.node == nil || block.node.Type() != "const_declaration" {
+		return nil
+	}
+	e := &iotaEval{block: block, consts: make(map[string]*big.Int)}
+
+	var specs []*sitter.Node
+	for i := 0; i < int(block.node.NamedChildCount()); i++ {
+		if child := block.node.NamedChild(i); child.Type() == "const_spec" {
+			specs = append(specs, child)
+		}
+	}
+
+	usesIota := false
+	for _, spec := range specs {
+		if value := spec.ChildByFieldName("value"); value != nil && e.mentionsIota(value) {
+			usesIota = true
+		}
+	}
+	if !usesIota {
+		return nil
+	}
+
+	values := make(map[Position]string)
+	var exprs []*sitter.Node
+	for i, spec := range specs {
+		if value := spec.ChildByFieldName("value"); value != nil {
+			exprs = nil
+			for j := 0; j < int(value.NamedChildCount()); j++ {
+				exprs = append(exprs, value.NamedChild(j))
+			}
+		}
+		e.iota = big.NewInt(int64(i))
+
+		// The names are the spec's direct identifier children; types are
+		// type identifiers and values are wrapped in an expression_list.
+		n := 0
+		for j := 0; j < int(spec.NamedChildCount()); j++ {
+			name := spec.NamedChild(j)
+			if name.Type() != "identifier" {
+				continue
+			}
+			if n < len(exprs) {
+				if v := e.eval(exprs[n]); v != nil {
+					values[nodeRange(name).Start] = v.String()
+					e.consts[e.text(name)] = v
+				}
+			}
+			n++
+		}
+	}
+	return values
+}
+
+// iotaEval evaluates constant expressions inside a const block.
+type iotaEval struct {
+	block  CaptureResult
+	iota   *big.Int
+	consts map[string]*big.Int
+}
+
+// text returns the source of n, sliced from the block's captured text.
+func (e *iotaEval) text(n *sitter.Node) string {
+	return captureNodeText(e.block, n)
+}
+
+func (e *iotaEval) mentionsIota(n *sitter.Node) bool {
+	if n.Type() == "iota" || (n.Type() == "identifier" && e.text(n) == "iota") {
+		return true
+	}
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		if e.mentionsIota(n.NamedChild(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// eval returns the value of n, or nil if it isn't an integer constant
+// expression eval understands.
+func (e *iotaEval) eval(n *sitter.Node) *big.Int {
+	switch n.Type() {
+	case "iota":
+		return new(big.Int).Set(e.iota)
+	case "identifier":
+		if name := e.text(n); name == "iota" {
+			return new(big.Int).Set(e.iota)
+		} else if v, ok := e.consts[name]; ok {
+			return new(big.Int).Set(v)
+		}
+		return nil
+	case "int_literal":
+		v, ok := new(big.Int).SetString(e.text(n), 0)
+		if !ok {
+			return nil
+		}
+		return v
+	case "parenthesized_expression":
+		if n.NamedChildCount() != 1 {
+			return nil
+		}
+		return e.eval(n.NamedChild(0))
+	case "call_expression":
+		// A conversion such as Weekday(iota) keeps the value.
+		args := n.ChildByFieldName("arguments")
+		if args == nil || args.NamedChildCount() != 1 {
+			return nil
+		}
+		return e.eval(args.NamedChild(0))
+	case "unary_expression":
+		operand := n.ChildByFieldName("operand")
+		op := n.ChildByFieldName("operator")
+		if operand == nil || op == nil {
+			return nil
+		}
+		v := e.eval(operand)
+		if v == nil {
+			return nil
+		}
+		switch op.Type() {
+		case "+":
+			return v
+		case "-":
+			return v.Neg(v)
+		case "^":
+			return v.Not(v)
+		}
+		return nil
+	case "binary_expression":
+		left, right, op := n.ChildByFieldName("left"), n.ChildByFieldName("right"), n.ChildByFieldName("operator")
+		if left == nil || right == nil || op == nil {
+			return nil
+		}
+		a, b := e.eval(left), e.eval(right)
+		if a == nil || b == nil {
+			return nil
+		}
+		return binaryOp(op.Type(), a, b)
+	}
+	return nil
+}
+
+// binaryOp applies a Go integer operator with Go's truncated division.
+func binaryOp(op string, a, b *big.Int) *big.Int {
+	switch op {
+	case "+":
+		return a.Add(a, b)
+	case "-":
+		return a.Sub(a, b)
+	case "*":
+		return a.Mul(a, b)
+	case "/":
+		if b.Sign() == 0 {
+			return nil
+		}
+		return a.Quo(a, b)
+	case "%":
+		if b.Sign() == 0 {
+			return nil
+		}
+		return a.Rem(a, b)
+	case "&":
+		return a.And(a, b)
+	case "|":
+		return a.Or(a, b)
+	case "^":
+		return a.Xor(a, b)
+	case "&^":
+		return a.AndNot(a, b)
+	case "<<", ">>":
+		if b.Sign() < 0 || !b.IsInt64() || b.Int64() > maxIotaShift {
+			return nil
+		}
+		if op == "<<" {
+			return a.Lsh(a, uint(b.Int64()))
+		}
+		return a.Rsh(a, uint(b.Int64()))
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package tsq
+
+import (
+	_ "embed"
+	"slices"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/java"
+)
+
+//go:embed queries/java/symbols.scm
+var javaSymbolsQuery string
+
+//go:embed queries/java/outline.scm
+var javaOutlineQuery string
+
+//go:embed queries/java/refs.scm
+var javaRefsQuery string
+
+// Java implements the Language interface for Java. Its symbols are classes,
+// interfaces, enums, and the methods, constructors and fields declared in
+// them, with the enclosing type as Receiver. Visibility follows the access
+// modifiers (see Visibility).
+type Java struct{}
+
+func init() {
+	Register(&Java{})
+}
+
+func (j *Java) Name() string {
+	return "java"
+}
+
+func (j *Java) Extensions() []string {
+	return []string{".java"}
+}
+
+func (j *Java) TreeSitterLang() *sitter.Language {
+	return java.GetLanguage()
+}
+
+func (j *Java) SymbolsQuery() string {
+	return javaSymbolsQuery
+}
+
+func (j *Java) OutlineQuery() string {
+	return javaOutlineQuery
+}
+
+func (j *Java) RefsQuery() string {
+	return javaRefsQuery
+}
+
+// Visibility makes a symbol public if it is declared public, or is a member
+// of an interface not declared private, as interface members are implicitly
+// public. protected and package-private symbols are private. Symbols are
+// matched to declarations by position, as their range starts at their name
+// or their declaration; the enclosing type captured with a member is left
+// out, so a public method doesn't make its class public.
+func (j *Java) Visibility(matches []QueryMatch) func(Symbol) string {
+	public := make(map[Position]bool)
+	for _, match := range matches {
+		if !javaPublic(match) {
+			continue
+		}
+		for _, c := range match.Captures {
+			if c.Name != "class_name" && c.Name != "receiver_type" {
+				public[c.Range.Start] = true
+			}
+		}
+	}
+	return func(sym Symbol) string {
+		if public[sym.Range.Start] {
+			return "public"
+		}
+		return "private"
+	}
+}
+
+// javaPublic reports whether the declaration matched by match is public.
+func javaPublic(match QueryMatch) bool {
+	var modifiers []string
+	if c, ok := findCapture(match, "modifiers"); ok {
+		modifiers = strings.Fields(c.Text)
+	}
+	if slices.Contains(modifiers, "public") {
+		return true
+	}
+	_, member := findCapture(match, "interface_body")
+	return member && !slices.Contains(modifiers, "private")
+}
+
+// isJavaParams reports whether params are the parameters of a Java method
+// or constructor, whose signatures are written in Java's own order.
+func isJavaParams(params CaptureResult) bool {
+	if params.NodeType != "formal_parameters" || params.node == nil || params.node.Parent() == nil {
+		return false
+	}
+	switch params.node.Parent().Type() {
+	case "method_declaration", "constructor_declaration":
+		return true
+	}
+	return false
+}
+
+// javaSignature returns the signature of a Java method, such as
+// "<T> List<T> copy(List<T> xs)", or of a constructor, such as
+// "Point(int x, int y)". Modifiers are left out.
+func javaSignature(captures map[string]CaptureResult) string {
+	var sb strings.Builder
+	if typeParams, ok := captures["type_params"]; ok {
+		sb.WriteString(typeParams.Text + " ")
+	}
+	if result, ok := captures["result"]; ok {
+		sb.WriteString(result.Text + " ")
+	}
+	sb.WriteString(captures["name"].Text)
+	sb.WriteString(captures["params"].Text)
+	return sb.String()
+}
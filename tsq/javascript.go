@@ -0,0 +1,105 @@
+package tsq
+
+import (
+	_ "embed"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/javascript"
+)
+
+//go:embed queries/javascript/symbols.scm
+var jsSymbolsQuery string
+
+//go:embed queries/javascript/outline.scm
+var jsOutlineQuery string
+
+//go:embed queries/javascript/refs.scm
+var jsRefsQuery string
+
+//go:embed queries/javascript/exports.scm
+var jsExportsQuery string
+
+// JavaScript implements the Language interface for JavaScript. Its symbols
+// are function declarations, functions and arrow functions bound to
+// variables, classes and class methods. A symbol is public if the module
+// exports it (see Visibility).
+type JavaScript struct{}
+
+func init() {
+	Register(&JavaScript{})
+}
+
+func (j *JavaScript) Name() string {
+	return "javascript"
+}
+
+func (j *JavaScript) Extensions() []string {
+	return []string{".js", ".mjs", ".cjs"}
+}
+
+func (j *JavaScript) TreeSitterLang() *sitter.Language {
+	return javascript.GetLanguage()
+}
+
+func (j *JavaScript) SymbolsQuery() string {
+	return jsSymbolsQuery + jsExportsQuery
+}
+
+func (j *JavaScript) OutlineQuery() string {
+	return jsOutlineQuery + jsExportsQuery
+}
+
+func (j *JavaScript) RefsQuery() string {
+	return jsRefsQuery
+}
+
+// Visibility makes a symbol public if its name is exported, by an export
+// statement or by assignment to module.exports or exports, and private
+// otherwise. Methods are reachable through their class, so they follow it,
+// except for #private ones.
+func (j *JavaScript) Visibility(matches []QueryMatch) func(Symbol) string {
+	return exportVisibility(matches)
+}
+
+// exportVisibility implements JavaScript.Visibility, given the matches of
+// an exports query such as queries/javascript/exports.scm.
+func exportVisibility(matches []QueryMatch) func(Symbol) string {
+	exported := make(map[string]bool)
+	for _, match := range matches {
+		if name, ok := jsExportedName(match); ok {
+			exported[name] = true
+		}
+	}
+	return func(sym Symbol) string {
+		name := sym.Name
+		if strings.HasPrefix(name, "#") {
+			return "private"
+		}
+		if sym.Receiver != "" {
+			name = sym.Receiver
+		}
+		if exported[name] {
+			return "public"
+		}
+		return "private"
+	}
+}
+
+// jsExportedName returns the local name exported by a match of an exports
+// query. Assignments only export through module.exports or exports, which
+// the patterns capture as @export.module and @export.exports since queries
+// aren't filtered by predicates.
+func jsExportedName(match QueryMatch) (string, bool) {
+	name, ok := findCapture(match, "export.name")
+	if !ok {
+		return "", false
+	}
+	if c, ok := findCapture(match, "export.module"); ok && c.Text != "module" {
+		return "", false
+	}
+	if c, ok := findCapture(match, "export.exports"); ok && c.Text != "exports" {
+		return "", false
+	}
+	return name.Text, true
+}
@@ -1,6 +1,12 @@
 package tsq
 
-import sitter "github.com/smacker/go-tree-sitter"
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
 
 // Language defines the interface for a supported programming language.
 type Language interface {
@@ -23,6 +29,112 @@ type Language interface {
 	RefsQuery() string
 }
 
+// defaultMaxBytes is the file size limit used when neither the caller nor
+// the language specifies one.
+const defaultMaxBytes = 2 * 1024 * 1024
+
+// MaxBytesProvider is optionally implemented by a Language whose files
+// routinely need a different size limit than the 2MB default.
+type MaxBytesProvider interface {
+	// DefaultMaxBytes returns the size above which files are skipped.
+	DefaultMaxBytes() int64
+}
+
+// LanguageMaxBytes returns the default file size limit for lang, falling back
+// to 2MB for languages that don't implement MaxBytesProvider.
+func LanguageMaxBytes(lang Language) int64 {
+	if p, ok := lang.(MaxBytesProvider); ok {
+		if n := p.DefaultMaxBytes(); n != 0 {
+			return n
+		}
+	}
+	return defaultMaxBytes
+}
+
+// InjectionsProvider is optionally implemented by a Language whose files
+// embed code in other languages, such as SQL in Go string literals.
+type InjectionsProvider interface {
+	// InjectionsQuery returns a tree-sitter query marking embedded code.
+	// Each match captures the code as @injection.content and a node naming
+	// its language as @injection.language; see injectionLanguage for how
+	// the name is read.
+	InjectionsQuery() string
+}
+
+// LineCommentProvider is optionally implemented by a Language whose line
+// comments don't start with "//".
+type LineCommentProvider interface {
+	// LineComment returns the marker starting a line comment, e.g. "--".
+	LineComment() string
+}
+
+// lineComment returns the line-comment marker of language, "//" unless it
+// implements LineCommentProvider.
+func lineComment(language Language) string {
+	if p, ok := language.(LineCommentProvider); ok {
+		return p.LineComment()
+	}
+	return "//"
+}
+
+// VisibilityProvider is optionally implemented by a Language whose symbols
+// aren't made public by Go's rule of an upper-case first letter.
+type VisibilityProvider interface {
+	// Visibility returns a function giving the visibility ("public" or
+	// "private") of a symbol of the file with the given symbols or outline
+	// query matches.
+	Visibility(matches []QueryMatch) func(Symbol) string
+}
+
+// visibilityFunc returns language's visibility rule for a file with the
+// given matches, or nil if it keeps the Go rule set by parseSymbolFromMatch.
+func visibilityFunc(language Language, matches []QueryMatch) func(Symbol) string {
+	if p, ok := language.(VisibilityProvider); ok {
+		return p.Visibility(matches)
+	}
+	return nil
+}
+
+// ErrUnsupportedGrammarABI is returned when a language's grammar was
+// generated for a tree-sitter ABI version the binding can't load.
+var ErrUnsupportedGrammarABI = errors.New("unsupported grammar ABI version")
+
+// The range of grammar ABI versions the tree-sitter runtime accepts
+// (TREE_SITTER_MIN_COMPATIBLE_LANGUAGE_VERSION and
+// TREE_SITTER_LANGUAGE_VERSION in the binding's api.h).
+const (
+	minGrammarABI = 13
+	maxGrammarABI = 14
+)
+
+// checkGrammarABI reports an ErrUnsupportedGrammarABI error if lang has no
+// grammar or one the runtime would reject. Without the check, a parser with
+// an incompatible grammar silently has no language and queries fail with
+// obscure errors or crash.
+func checkGrammarABI(lang Language) error {
+	version := grammarABIVersion(lang.TreeSitterLang())
+	if version < minGrammarABI || version > maxGrammarABI {
+		return fmt.Errorf("%w: %s grammar has version %d, want %d to %d",
+			ErrUnsupportedGrammarABI, lang.Name(), version, minGrammarABI, maxGrammarABI)
+	}
+	return nil
+}
+
+// grammarABIVersion returns the ABI version of a grammar, or 0 if there is
+// none. The binding doesn't wrap ts_language_version, but sitter.Language
+// only holds the TSLanguage pointer, and version is the first field of
+// every TSLanguage.
+func grammarABIVersion(lang *sitter.Language) uint32 {
+	if lang == nil {
+		return 0
+	}
+	ptr := *(*unsafe.Pointer)(unsafe.Pointer(lang))
+	if ptr == nil {
+		return 0
+	}
+	return *(*uint32)(ptr)
+}
+
 // registry holds all registered languages.
 var registry = make(map[string]Language)
 
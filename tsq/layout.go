@@ -0,0 +1,162 @@
+package tsq
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// wordSize is the size and alignment of pointers and of named types whose
+// layout isn't known. Layouts assume a 64-bit platform.
+const wordSize = 8
+
+// basicLayouts holds the size and alignment of predeclared types and a few
+// common standard library ones.
+var basicLayouts = map[string][2]int{
+	"bool": {1, 1}, "int8": {1, 1}, "uint8": {1, 1}, "byte": {1, 1},
+	"int16": {2, 2}, "uint16": {2, 2},
+	"int32": {4, 4}, "uint32": {4, 4}, "rune": {4, 4}, "float32": {4, 4},
+	"int64": {8, 8}, "uint64": {8, 8}, "float64": {8, 8}, "complex64": {8, 4},
+	"int": {8, 8}, "uint": {8, 8}, "uintptr": {8, 8},
+	"complex128": {16, 8}, "string": {16, 8}, "error": {16, 8}, "any": {16, 8},
+	"unsafe.Pointer": {8, 8}, "time.Duration": {8, 8}, "time.Time": {24, 8},
+	"sync.Mutex": {8, 4}, "sync.RWMutex": {24, 8},
+}
+
+// fieldLayout is a struct field's name, size and alignment in bytes.
+type fieldLayout struct {
+	name        string
+	size, align int
+}
+
+// structLayout describes the estimated memory layout of a struct_type
+// capture: each field with its offset and the struct's size, then the
+// padding holes between fields, and the smaller size and field order that
+// sorting by alignment gives if it saves space. For example:
+//
+//	a@0 flag@8 b@16 = 24 bytes, padding: 7 after flag
+//	ok@0 n@8 small@16 p@24 = 32 bytes, padding: 7 after ok, 6 after small, 24 reordered (n, p, small, ok)
+//
+// Sizes are a heuristic read from type names, not type-checked: named types
+// other than the predeclared ones are taken to be pointer-sized.
+func structLayout(def CaptureResult) string {
+	if def.node == nil || def.node.Type() != "struct_type" {
+		return ""
+	}
+	text := func(n *sitter.Node) string { return captureNodeText(def, n) }
+
+	fields := structFields(def.node, text)
+	if len(fields) == 0 {
+		return "0 bytes"
+	}
+	size, _ := layoutSize(fields)
+
+	var sb strings.Builder
+	var holes []string
+	offset := 0
+	for i, f := range fields {
+		if aligned := alignUp(offset, f.align); aligned > offset {
+			holes = append(holes, fmt.Sprintf("%d after %s", aligned-offset, fields[i-1].name))
+			offset = aligned
+		}
+		fmt.Fprintf(&sb, "%s@%d ", f.name, offset)
+		offset += f.size
+	}
+	fmt.Fprintf(&sb, "= %d bytes", size)
+	if len(holes) > 0 {
+		fmt.Fprintf(&sb, ", padding: %s", strings.Join(holes, ", "))
+	}
+
+	sorted := append([]fieldLayout(nil), fields...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].align > sorted[j].align })
+	if best, _ := layoutSize(sorted); best < size {
+		names := make([]string, len(sorted))
+		for i, f := range sorted {
+			names[i] = f.name
+		}
+		fmt.Fprintf(&sb, ", %d reordered (%s)", best, strings.Join(names, ", "))
+	}
+	return sb.String()
+}
+
+// structFields lists the fields of a struct_type node in declaration order.
+// Embedded fields are named by their type.
+func structFields(n *sitter.Node, text func(*sitter.Node) string) []fieldLayout {
+	var fields []fieldLayout
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		list := n.NamedChild(i)
+		if list.Type() != "field_declaration_list" {
+			continue
+		}
+		for j := 0; j < int(list.NamedChildCount()); j++ {
+			decl := list.NamedChild(j)
+			typ := decl.ChildByFieldName("type")
+			if decl.Type() != "field_declaration" || typ == nil {
+				continue
+			}
+			size, align := typeLayout(typ, text)
+
+			named := false
+			for k := 0; k < int(decl.NamedChildCount()); k++ {
+				if name := decl.NamedChild(k); name.Type() == "field_identifier" {
+					fields = append(fields, fieldLayout{text(name), size, align})
+					named = true
+				}
+			}
+			if !named {
+				name := strings.TrimPrefix(text(typ), "*")
+				if i := strings.LastIndex(name, "."); i >= 0 {
+					name = name[i+1:]
+				}
+				fields = append(fields, fieldLayout{name, size, align})
+			}
+		}
+	}
+	return fields
+}
+
+// typeLayout estimates the size and alignment of a type node.
+func typeLayout(n *sitter.Node, text func(*sitter.Node) string) (size, align int) {
+	switch n.Type() {
+	case "type_identifier", "qualified_type":
+		if l, ok := basicLayouts[text(n)]; ok {
+			return l[0], l[1]
+		}
+	case "slice_type":
+		return 3 * wordSize, wordSize
+	case "interface_type":
+		return 2 * wordSize, wordSize
+	case "array_type":
+		length, err := strconv.Atoi(text(n.ChildByFieldName("length")))
+		if elem := n.ChildByFieldName("element"); err == nil && elem != nil {
+			size, align := typeLayout(elem, text)
+			return length * size, align
+		}
+	case "struct_type":
+		return layoutSize(structFields(n, text))
+	case "parenthesized_type":
+		if n.NamedChildCount() == 1 {
+			return typeLayout(n.NamedChild(0), text)
+		}
+	}
+	// Pointers, maps, channels, funcs and unknown named types.
+	return wordSize, wordSize
+}
+
+// layoutSize returns the size and alignment of a struct with fields laid out
+// in order.
+func layoutSize(fields []fieldLayout) (size, align int) {
+	align = 1
+	for _, f := range fields {
+		size = alignUp(size, f.align) + f.size
+		align = max(align, f.align)
+	}
+	return alignUp(size, align), align
+}
+
+func alignUp(n, align int) int {
+	return (n + align - 1) / align * align
+}
@@ -0,0 +1,145 @@
+package tsq
+
+import (
+	"path"
+	"strings"
+)
+
+// collectEmbeds maps struct names to the types they embed, in declaration
+// order, from the embedded-field matches of the symbols query. Types
+// embedded as pointers keep their "*", as in "*pkg.T".
+func collectEmbeds(matches []QueryMatch) map[string][]string {
+	embeds := make(map[string][]string)
+	for _, match := range matches {
+		name, ok := findCapture(match, "struct_name")
+		if !ok {
+			continue
+		}
+		if embedded, ok := findCapture(match, "embedded"); ok {
+			typ := embedded.Text
+			if embedded.node != nil {
+				if prev := embedded.node.PrevSibling(); prev != nil && prev.Type() == "*" {
+					typ = "*" + typ
+				}
+			}
+			embeds[name.Text] = append(embeds[name.Text], typ)
+		}
+	}
+	return embeds
+}
+
+// promoteEmbedded adds to each struct the methods of the types it embeds,
+// marked as Promoted. Embedded types are resolved by name: first within the
+// struct's own directory, then anywhere in the results (for qualified names
+// like pkg.T only the type name is used). Methods the struct declares itself
+// shadow promoted ones. A promoted method has ReceiverPointer set if only
+// the struct's pointer type has it: if it has a pointer receiver and no type
+// on the way to it is embedded as a pointer. This is an approximation
+// without type-checking.
+func promoteEmbedded(results []SymbolsResult) {
+	type key struct{ dir, typ string }
+	methods := make(map[key][]Symbol)
+	byName := make(map[string][]Symbol)
+	for _, r := range results {
+		for _, sym := range r.Symbols {
+			if sym.Kind == "method" && !sym.Promoted {
+				k := key{path.Dir(r.File), sym.Receiver}
+				methods[k] = append(methods[k], sym)
+				byName[sym.Receiver] = append(byName[sym.Receiver], sym)
+			}
+		}
+	}
+
+	lookup := func(dir, embedded string) []Symbol {
+		typ := embedded
+		if i := strings.LastIndex(typ, "."); i >= 0 {
+			typ = typ[i+1:]
+		}
+		if ms, ok := methods[key{dir, typ}]; ok {
+			return ms
+		}
+		return byName[typ]
+	}
+
+	for i := range results {
+		dir := path.Dir(results[i].File)
+		var promoted []Symbol
+		for _, sym := range results[i].Symbols {
+			if sym.Kind != "struct" || len(sym.Embeds) == 0 {
+				continue
+			}
+
+			seen := make(map[string]bool)
+			for _, m := range methods[key{dir, sym.Name}] {
+				seen[m.Name] = true
+			}
+			visited := map[string]bool{sym.Name: true}
+
+			// embedding is an embedded type and whether it, or a type on
+			// the way to it, is embedded as a pointer.
+			type embedding struct {
+				typ        string
+				viaPointer bool
+			}
+			var walk func(embeds []embedding)
+			walk = func(embeds []embedding) {
+				var next []embedding
+				for _, e := range embeds {
+					embedded := strings.TrimPrefix(e.typ, "*")
+					viaPointer := e.viaPointer || embedded != e.typ
+					typ := embedded[strings.LastIndex(embedded, ".")+1:]
+					if visited[typ] {
+						continue
+					}
+					visited[typ] = true
+					for _, m := range lookup(dir, embedded) {
+						if seen[m.Name] {
+							continue
+						}
+						seen[m.Name] = true
+						m.Receiver = sym.Name
+						m.ReceiverPointer = m.ReceiverPointer && !viaPointer
+						m.Promoted = true
+						m.PromotedFrom = typ
+						if m.QualifiedName != "" {
+							m.QualifiedName = strings.TrimSuffix(sym.QualifiedName, sym.Name) + sym.Name + "." + m.Name
+						}
+						promoted = append(promoted, m)
+					}
+					for _, inner := range embedsOf(results, dir, typ) {
+						next = append(next, embedding{inner, viaPointer})
+					}
+				}
+				if len(next) > 0 {
+					walk(next)
+				}
+			}
+			var embeds []embedding
+			for _, typ := range sym.Embeds {
+				embeds = append(embeds, embedding{typ: typ})
+			}
+			walk(embeds)
+		}
+		results[i].Symbols = append(results[i].Symbols, promoted...)
+	}
+}
+
+// embedsOf returns the embedded types of the named struct, preferring one
+// declared in dir.
+func embedsOf(results []SymbolsResult, dir, name string) []string {
+	var fallback []string
+	for _, r := range results {
+		for _, sym := range r.Symbols {
+			if sym.Kind != "struct" || sym.Name != name {
+				continue
+			}
+			if path.Dir(r.File) == dir {
+				return sym.Embeds
+			}
+			if fallback == nil {
+				fallback = sym.Embeds
+			}
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,106 @@
+package tsq
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// ModuleInfo describes the Go module containing a directory, as declared by
+// its go.mod file.
+type ModuleInfo struct {
+	Path      string          `json:"path"`
+	GoVersion string          `json:"go_version,omitempty"`
+	Toolchain string          `json:"toolchain,omitempty"`
+	GoMod     string          `json:"go_mod"`   // the go.mod file
+	Requires  []ModuleRequire `json:"requires"` // direct dependencies
+}
+
+// ModuleRequire is a requirement of a go.mod file.
+type ModuleRequire struct {
+	Path    string         `json:"path"`
+	Version string         `json:"version,omitempty"`
+	Replace *ModuleRequire `json:"replace,omitempty"` // the module it is replaced with, if any
+}
+
+// Module finds the go.mod file of the module containing opts.Path, walking
+// up through its parents, and reports the module path, Go version and
+// direct dependencies, with their replace directives. Requirements marked
+// // indirect are left out. The go.mod file is parsed as the go command
+// does, with golang.org/x/mod/modfile.
+func Module(opts ModuleOptions) (*ModuleInfo, error) {
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	dir, err := filepath.Abs(opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("resolve path: %w", err)
+	}
+
+	_, moduleDir := findModule(dir)
+	if moduleDir == "" {
+		return nil, fmt.Errorf("no go.mod found in %s or its parents", opts.Path)
+	}
+	gomod := filepath.Join(moduleDir, "go.mod")
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		return nil, fmt.Errorf("read go.mod: %w", err)
+	}
+
+	info, err := parseGoMod(gomod, data)
+	if err != nil {
+		return nil, err
+	}
+	info.GoMod = gomod
+	return info, nil
+}
+
+// parseGoMod reads the module, go and toolchain directives and the direct
+// requirements of the go.mod file named file, with their replacements.
+func parseGoMod(file string, data []byte) (*ModuleInfo, error) {
+	f, err := modfile.Parse(file, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.Module == nil {
+		return nil, fmt.Errorf("%s: no module directive", file)
+	}
+
+	info := &ModuleInfo{Path: f.Module.Mod.Path, Requires: []ModuleRequire{}}
+	if f.Go != nil {
+		info.GoVersion = f.Go.Version
+	}
+	if f.Toolchain != nil {
+		info.Toolchain = f.Toolchain.Name
+	}
+	for _, req := range f.Require {
+		if req.Indirect {
+			continue
+		}
+		r := ModuleRequire{Path: req.Mod.Path, Version: req.Mod.Version}
+		if rep := moduleReplacement(f.Replace, req.Mod); rep != nil {
+			r.Replace = &ModuleRequire{Path: rep.New.Path, Version: rep.New.Version}
+		}
+		info.Requires = append(info.Requires, r)
+	}
+	return info, nil
+}
+
+// moduleReplacement returns the replace directive that applies to mod: one
+// for its version, or else one for all its versions.
+func moduleReplacement(replaces []*modfile.Replace, mod module.Version) *modfile.Replace {
+	var allVersions *modfile.Replace
+	for _, rep := range replaces {
+		switch {
+		case rep.Old.Path != mod.Path:
+		case rep.Old.Version == mod.Version:
+			return rep
+		case rep.Old.Version == "":
+			allVersions = rep
+		}
+	}
+	return allVersions
+}
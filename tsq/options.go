@@ -1,33 +1,184 @@
 package tsq
 
+import "io/fs"
+
 // QueryOptions configures the Query function.
 type QueryOptions struct {
 	// Query is the tree-sitter query string to execute.
-	Query string
+	Query string `json:"query"`
+
+	// Find, instead of Query, generates the query for one kind of Go
+	// declaration: function, method, struct, interface, type, const or var
+	// (see FindTargets). Matches capture the declaration as @<kind>, its
+	// name as @name and, for methods, the receiver as @receiver.
+	Find string `json:"find,omitempty"`
+
+	// Name keeps only Find matches whose name matches this glob
+	// (path.Match syntax, e.g. "Test*").
+	Name string `json:"name,omitempty"`
+
+	// Receiver keeps only Find method matches whose receiver type name,
+	// without pointer or type arguments, matches this glob.
+	Receiver string `json:"receiver,omitempty"`
 
 	// Language specifies which language to use (e.g., "go").
-	Language string
+	Language string `json:"language"`
 
 	// Path is the root directory to scan for files.
 	// If empty, current directory is used.
-	Path string
+	Path string `json:"path"`
 
 	// File is a single file to query.
 	// If set, Path is ignored.
-	File string
+	File string `json:"file,omitempty"`
+
+	// FS, if set, is the filesystem files are read from instead of the OS
+	// filesystem, e.g. an embed.FS or a zip opened with zip.NewReader. Path
+	// and File are then slash-separated fs.FS paths ("." is the root) and
+	// FileOverrides are keyed the same way. It can't be combined with
+	// PathRelativeTo "git".
+	FS fs.FS `json:"-"`
+
+	// PathRelativeTo selects what file paths in results are relative to:
+	// "root" (the default) for Path, or "git" for the root of the enclosing
+	// git worktree, falling back to Path outside a repository.
+	PathRelativeTo string `json:"path_relative_to,omitempty"`
+
+	// BaseDir, if set, is the directory file paths in results are relative
+	// to, whatever Path or File is scanned, e.g. the repository root when
+	// tsq runs elsewhere. Files outside it get "../" paths. It can't be
+	// combined with FS or PathRelativeTo "git".
+	BaseDir string `json:"base_dir,omitempty"`
 
 	// Jobs is the number of parallel workers.
 	// If 0, defaults to number of CPUs.
-	Jobs int
+	Jobs int `json:"jobs"`
+
+	// Schedule controls the order files are handed to workers: "fifo"
+	// (the default) in scan order, or "size" for largest files first, which
+	// shortens the tail when file sizes vary widely.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Sample, if positive, analyzes only a random sample of this many of
+	// the scanned files, for quick estimates on large trees. The sample is
+	// reproducible: the same Seed over the same files picks the same sample.
+	// It is ignored when File is set.
+	Sample int `json:"sample,omitempty"`
+
+	// Seed seeds the random choice of Sample files.
+	Seed int64 `json:"seed,omitempty"`
+
+	// MaxFiles, if positive, stops the scan once this many files have been
+	// collected, as a safety cap on unexpectedly large trees. Hitting the
+	// cap is reported as a "max_files" diagnostic. It is ignored when File
+	// is set.
+	MaxFiles int `json:"max_files,omitempty"`
+
+	// Hidden scans dot-prefixed files and directories, which are skipped by
+	// default. Directories such as .git and .venv stay ignored either way.
+	Hidden bool `json:"hidden,omitempty"`
+
+	// IgnoreDirs names directories to skip besides the default ones, such
+	// as vendor and node_modules, wherever they appear in the tree.
+	IgnoreDirs []string `json:"ignore_dirs,omitempty"`
+
+	// RespectGitignore skips files and directories excluded by the
+	// .gitignore files of Path and its subdirectories, and of its parents
+	// up to the root of the git worktree it is in. Patterns use the
+	// gitignore syntax, including "!" negation and "dir/" directory-only
+	// patterns. It is ignored when File is set.
+	RespectGitignore bool `json:"respect_gitignore,omitempty"`
+
+	// RespectIgnoreSingle applies the ignore rules of directory scans
+	// (ignored directories such as vendor, and hidden files unless Hidden
+	// is set) to File too. An ignored File yields no results and an
+	// "ignored" diagnostic.
+	RespectIgnoreSingle bool `json:"respect_ignore_single,omitempty"`
 
 	// MaxBytes skips files larger than this size.
-	// If 0, no size limit is enforced.
-	MaxBytes int64
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+
+	// StrictParse skips files whose syntax tree contains errors instead of
+	// querying the partial tree, reporting each one as a "parse_error"
+	// diagnostic.
+	StrictParse bool `json:"strict_parse,omitempty"`
+
+	// SkipMinified skips files that look minified (very long lines, or
+	// few newlines) or binary (NUL bytes), judged from their first
+	// kilobyte.
+	SkipMinified bool `json:"skip_minified,omitempty"`
+
+	// FileOverrides maps file paths to contents that are parsed instead of
+	// the files on disk, e.g. unsaved editor buffers. Paths are resolved
+	// against the working directory. Overrides only replace the contents of
+	// files that are scanned (or named by File); they don't add new files.
+	FileOverrides map[string][]byte `json:"-"`
+
+	// PreserveEOL disables normalizing "\r\n" line endings to "\n" before parsing.
+	PreserveEOL bool `json:"preserve_eol,omitempty"`
+
+	// PreserveInvalidUTF8 keeps capture text byte-for-byte. By default,
+	// invalid UTF-8 sequences are replaced with U+FFFD and the capture is
+	// marked Sanitized, since JSON consumers require valid UTF-8.
+	PreserveInvalidUTF8 bool `json:"preserve_invalid_utf8,omitempty"`
+
+	// MaxPerFile caps the number of matches contributed by a single file.
+	// If 0, no cap is applied.
+	MaxPerFile int `json:"max_per_file,omitempty"`
+
+	// ContextBytes, if positive, sets CaptureResult.Context to the capture
+	// plus up to this many bytes of surrounding source on each side.
+	// The window is clamped to the file and never splits a UTF-8 rune.
+	ContextBytes int `json:"context_bytes,omitempty"`
+
+	// AtLine, if positive, runs the query only on the smallest named node
+	// that spans the whole of this 1-based line, such as the function whose
+	// signature is on it, instead of the whole file. Files shorter than
+	// AtLine produce no matches.
+	AtLine int `json:"at_line,omitempty"`
+
+	// PatternIndex, if non-nil, keeps only matches produced by the query
+	// pattern with this (0-based) index, as reported in QueryMatch.Pattern.
+	// If nil, matches from every pattern are kept.
+	PatternIndex *int `json:"pattern_index,omitempty"`
+
+	// RequireChild keeps only matches where a captured node has a direct
+	// named child of this node type, e.g. "parameter_declaration" on a
+	// captured parameter_list keeps functions that take parameters.
+	RequireChild string `json:"require_child,omitempty"`
+
+	// ForbidChild drops matches where a captured node has a direct named
+	// child of this node type.
+	ForbidChild string `json:"forbid_child,omitempty"`
+
+	// Diagnostics, if non-nil, receives non-fatal conditions such as
+	// files whose matches were capped by MaxPerFile or skipped by
+	// StrictParse.
+	Diagnostics *[]Diagnostic `json:"-"`
+
+	// Legend, if non-nil, receives every capture name defined in the query,
+	// including ones that matched nothing, with its number of captures.
+	Legend *[]LegendEntry `json:"-"`
+
+	// PatternCoverage, if non-nil, receives one PatternCount per pattern of
+	// the query, in pattern order, so patterns that matched nothing show up
+	// with 0 matches.
+	PatternCoverage *[]PatternCount `json:"-"`
+
+	// Resolved, if non-nil, receives these options as the query ran with
+	// them: defaults filled in (Language, Path, Jobs, Schedule, MaxBytes),
+	// Query generated from Find, and the output fields above cleared. It
+	// marshals to JSON with the inputs that can't be, FS and
+	// FileOverrides, left out. To run it again after a Find, clear Query.
+	Resolved *QueryOptions `json:"-"`
 }
 
 // SymbolsOptions configures the Symbols function.
 type SymbolsOptions struct {
-	// Language specifies which language to use (e.g., "go").
+	// Language specifies which language to use (e.g., "go"). Empty or
+	// AutoDetect picks each file's language by its extension.
 	Language string
 
 	// Path is the root directory to scan for files.
@@ -38,38 +189,306 @@ type SymbolsOptions struct {
 	// If set, Path is ignored.
 	File string
 
+	// ChangedSince restricts the scan to files under Path that were added,
+	// modified or renamed relative to this git ref (e.g. "main"). The
+	// changed files are filtered like those of a directory scan.
+	// Requires Path to be inside a git repository.
+	ChangedSince string
+
+	// ChangedLines, if non-nil, restricts results to symbols whose declaration spans changed lines,
+	// e.g. from ParseUnifiedDiff. Keys are slash-separated paths relative
+	// to the git worktree root containing Path (or File), falling back to
+	// Path outside a repository; with FS they are paths in FS.
+	ChangedLines map[string][]LineRange
+
 	// Visibility filters symbols: "all", "public", or "private".
 	// Defaults to "all".
 	Visibility string
 
+	// Documented filters symbols by whether they have a doc comment (see
+	// Comments): "all", "documented" or "undocumented". Defaults to "all".
+	Documented string
+
+	// WithDoc populates Doc with each symbol's doc comment.
+	WithDoc bool
+
 	// IncludeSource includes source code snippets in results.
 	IncludeSource bool
 
 	// MaxSourceLines limits the number of lines in source snippets.
 	MaxSourceLines int
 
+	// SourceWithLocation starts each source snippet with a
+	// "// file.go:12" header naming its file and first line, so a
+	// truncated snippet can still be found in the file. The header is a
+	// line comment of the snippet's language ("--" for SQL). It is left
+	// out of snippets stored in SnippetCache, which stay content-addressed.
+	SourceWithLocation bool
+
+	// SnippetCache, if set with IncludeSource, is a directory that source
+	// snippets are stored in instead of being inlined: each snippet is
+	// written under its SnippetRef, which replaces Source in the results.
+	// Resolve refs with ReadSnippet.
+	SnippetCache string
+
+	// Dedent removes common leading indentation from source snippets and
+	// bodies while preserving relative indentation.
+	Dedent bool
+
+	// WithBody populates Body on functions, methods and closures with the
+	// source of their body block. Unlike Source it is never truncated.
+	WithBody bool
+
+	// WithArity populates NumParams and NumResults on functions and methods.
+	WithArity bool
+
+	// WithModTime sets SymbolsResult.ModTime to each file's last
+	// modification time.
+	WithModTime bool
+
+	// WithDirectives populates Directives with the //go: directive lines
+	// (//go:noinline, //go:generate, ...) of the comments directly above
+	// each declaration, or above its const or var block. Go only.
+	WithDirectives bool
+
+	// ReturnsError keeps only functions, methods and closures whose last
+	// result is of type error (see Symbol.ReturnsError). Go only.
+	ReturnsError bool
+
+	// IncludeAnonymous reports function literals as symbols of kind
+	// "closure", named func@line:col, with Enclosing set to the declaration
+	// that contains them.
+	IncludeAnonymous bool
+
+	// PromoteEmbedded records the embedded types of each struct and adds the
+	// methods of those types to the struct as promoted methods. Embedded
+	// types are resolved by name within the scanned files.
+	PromoteEmbedded bool
+
+	// QualifyNames populates QualifiedName on every symbol as pkg.Name, or
+	// pkg.Receiver.Name for methods.
+	QualifyNames bool
+
+	// OutermostOnly drops symbols declared inside another reported
+	// symbol's declaration, such as types or closures in a function body,
+	// and duplicate entries for the same span.
+	OutermostOnly bool
+
+	// Injections also reports the symbols of code embedded in string
+	// literals, as marked by the language's InjectionsProvider query (for
+	// Go, a comment such as /* sql */ or // language=sql directly before
+	// the literal; languages other than sql need the language= form). The
+	// code is parsed with the named language, its symbols get Language
+	// set, and their positions are in the host file.
+	Injections bool
+
+	// StructLayout sets LayoutHint on structs to their estimated field
+	// offsets and size on a 64-bit platform, and, if ordering the fields by
+	// alignment would save padding, the smaller size and that order. Sizes
+	// are guessed from type names; other named types count as
+	// pointer-sized. Go only.
+	StructLayout bool
+
+	// MethodQualifiedNames renames methods to Receiver.Method, e.g.
+	// Server.Handle for func (s *Server) Handle, so they key a per-package
+	// index without colliding; functions keep their bare names. It is
+	// applied last, after QualifyNames and PromoteEmbedded.
+	MethodQualifiedNames bool
+
+	// ResolveIota sets Value on the constants of const blocks that use
+	// iota to their effective integer value, e.g. 0, 1, 2 for
+	// const ( A = iota; B; C ). Go only.
+	ResolveIota bool
+
+	// SymbolHook, if set, is called with each symbol after it is parsed and
+	// filtered by Visibility, before GroupDeclBlocks, QualifyNames and
+	// PromoteEmbedded run. Changes it makes to the symbol are kept;
+	// returning false drops the symbol. It is called from the worker
+	// goroutines, so it must be safe for concurrent use when Jobs > 1.
+	SymbolHook func(*Symbol) bool
+
+	// Top, if positive, returns only the N best symbols ranked by By, best
+	// first. Unlike a limit it keeps the best rather than the first: symbols
+	// are streamed through a bounded heap, so memory stays O(N) however many
+	// files are scanned. Consecutive symbols from the same file share a
+	// SymbolsResult. Top can't be combined with PromoteEmbedded.
+	Top int
+
+	// By is the ranking used by Top: "size" (bytes, the default), "lines",
+	// or "refs" (reference count by name across Path, as in Hotspots).
+	By string
+
+	// GroupDeclBlocks reports the specs of each parenthesized const or var
+	// block as a single "const_block" or "var_block" symbol with Members.
+	GroupDeclBlocks bool
+
+	// DetectEnums reports each parenthesized const block whose constants
+	// all have the same named type, e.g. const ( Red Color = iota; Green ),
+	// as a single "enum" symbol named after the type. Its Members are the
+	// constants, blank ones left out, with Value set to the computed iota
+	// value or else the value expression. Other blocks are left to
+	// GroupDeclBlocks. Go only.
+	DetectEnums bool
+
+	// PathPattern, if set, only scans files whose path relative to Path
+	// matches this doublestar glob (e.g. "**/handlers/*.go").
+	PathPattern string
+
+	// FS, if set, is the filesystem files are read from instead of the OS
+	// filesystem, e.g. an embed.FS or a zip opened with zip.NewReader. Path
+	// and File are then slash-separated fs.FS paths ("." is the root) and
+	// FileOverrides are keyed the same way. It can't be combined with
+	// ChangedSince or PathRelativeTo "git".
+	FS fs.FS
+
+	// PathRelativeTo selects what file paths in results are relative to:
+	// "root" (the default) for Path, or "git" for the root of the enclosing
+	// git worktree, falling back to Path outside a repository.
+	PathRelativeTo string
+
+	// BaseDir, if set, is the directory file paths in results are relative
+	// to, whatever Path or File is scanned, e.g. the repository root when
+	// tsq runs elsewhere. Files outside it get "../" paths. It can't be
+	// combined with FS or PathRelativeTo "git".
+	BaseDir string
+
 	// Jobs is the number of parallel workers.
 	// If 0, defaults to number of CPUs.
 	Jobs int
 
+	// Schedule controls the order files are handed to workers: "fifo"
+	// (the default) in scan order, or "size" for largest files first, which
+	// shortens the tail when file sizes vary widely.
+	Schedule string
+
+	// Sample, if positive, analyzes only a random sample of this many of
+	// the scanned files, for quick estimates on large trees. The sample is
+	// reproducible: the same Seed over the same files picks the same sample.
+	// It is ignored when File is set.
+	Sample int
+
+	// Seed seeds the random choice of Sample files.
+	Seed int64
+
+	// MaxFiles, if positive, stops the scan once this many files have been
+	// collected, as a safety cap on unexpectedly large trees. Hitting the
+	// cap is reported as a "max_files" diagnostic. It is ignored when File
+	// is set.
+	MaxFiles int
+
+	// Hidden scans dot-prefixed files and directories, which are skipped by
+	// default. Directories such as .git and .venv stay ignored either way.
+	Hidden bool
+
+	// IgnoreDirs names directories to skip besides the default ones, such
+	// as vendor and node_modules, wherever they appear in the tree.
+	IgnoreDirs []string
+
+	// RespectGitignore skips files and directories excluded by the
+	// .gitignore files of Path and its subdirectories, and of its parents
+	// up to the root of the git worktree it is in. Patterns use the
+	// gitignore syntax, including "!" negation and "dir/" directory-only
+	// patterns. It is ignored when File is set.
+	RespectGitignore bool
+
+	// RespectIgnoreSingle applies the ignore rules of directory scans
+	// (ignored directories such as vendor, and hidden files unless Hidden
+	// is set) to File too. An ignored File yields no results and an
+	// "ignored" diagnostic.
+	RespectIgnoreSingle bool
+
 	// MaxBytes skips files larger than this size.
-	// If 0, no size limit is enforced.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
 	MaxBytes int64
+
+	// StrictParse skips files whose syntax tree contains errors instead of
+	// querying the partial tree, reporting each one as a "parse_error"
+	// diagnostic.
+	StrictParse bool
+
+	// SkipMinified skips files that look minified (very long lines, or
+	// few newlines) or binary (NUL bytes), judged from their first
+	// kilobyte.
+	SkipMinified bool
+
+	// FileOverrides maps file paths to contents that are parsed instead of
+	// the files on disk, e.g. unsaved editor buffers. Paths are resolved
+	// against the working directory. Overrides only replace the contents of
+	// files that are scanned (or named by File); they don't add new files.
+	FileOverrides map[string][]byte
+
+	// PreserveEOL disables normalizing "\r\n" line endings to "\n" before parsing.
+	PreserveEOL bool
+
+	// Diagnostics, if non-nil, receives non-fatal conditions such as
+	// files skipped by StrictParse.
+	Diagnostics *[]Diagnostic
 }
 
 // OutlineOptions configures the Outline function.
 type OutlineOptions struct {
-	// Language specifies which language to use (e.g., "go").
+	// Language specifies which language to use (e.g., "go"). Empty or
+	// AutoDetect picks each file's language by its extension.
 	Language string
 
-	// File is the file to analyze (required).
+	// File is the file to analyze (required by Outline).
 	File string
 
+	// Path is the directory whose files StreamOutlines outlines. Outline
+	// ignores it.
+	Path string
+
+	// Jobs is the number of parallel workers of StreamOutlines.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// MaxBytes makes StreamOutlines skip files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+
 	// IncludeSource includes source code snippets in results.
 	IncludeSource bool
 
 	// MaxSourceLines limits the number of lines in source snippets.
 	MaxSourceLines int
+
+	// Dedent removes common leading indentation from source snippets while
+	// preserving relative indentation.
+	Dedent bool
+
+	// ClassifyImports sets ImportInfo.Group to "std", "third_party" or
+	// "local", using the nearest go.mod to identify local packages.
+	ClassifyImports bool
+
+	// CountImportUsage sets ImportInfo.UsageCount to the number of
+	// selector expressions (pkg.Name) and qualified types that reference each
+	// import's package name, so unused imports report 0.
+	CountImportUsage bool
+
+	// FileOverrides maps file paths to contents that are parsed instead of
+	// the files on disk, e.g. unsaved editor buffers. Paths are resolved
+	// against the working directory. Overrides only replace the contents of
+	// files that are scanned (or named by File); they don't add new files.
+	FileOverrides map[string][]byte
+
+	// PreserveEOL disables normalizing "\r\n" line endings to "\n" before parsing.
+	PreserveEOL bool
+
+	// WithModTime sets FileOutline.ModTime to the file's last modification
+	// time.
+	WithModTime bool
+
+	// WithDoc populates Doc with each symbol's doc comment: the text of the
+	// comments (line or block) on the lines directly above its declaration,
+	// without their markers.
+	WithDoc bool
+
+	// Diagnostics, if non-nil, receives the non-fatal conditions of
+	// StreamOutlines, such as files skipped because their language's
+	// outline query doesn't compile.
+	Diagnostics *[]Diagnostic
 }
 
 // RefsOptions configures the Refs function.
@@ -77,7 +496,8 @@ type RefsOptions struct {
 	// Symbol is the symbol name to find references for (required).
 	Symbol string
 
-	// Language specifies which language to use (e.g., "go").
+	// Language specifies which language to use (e.g., "go"). Empty or
+	// AutoDetect picks each file's language by its extension.
 	Language string
 
 	// Path is the root directory to scan for files.
@@ -88,14 +508,430 @@ type RefsOptions struct {
 	// If set, Path is ignored.
 	File string
 
+	// ChangedLines, if non-nil, restricts results to references on changed lines,
+	// e.g. from ParseUnifiedDiff. Keys are slash-separated paths relative
+	// to the git worktree root containing Path (or File), falling back to
+	// Path outside a repository; with FS they are paths in FS.
+	ChangedLines map[string][]LineRange
+
 	// IncludeContext includes surrounding code context in results.
 	IncludeContext bool
 
+	// IncludeOffsets sets Offsets on each reference to the byte span of the
+	// symbol, for editors applying renames. Line endings are then kept as
+	// they are (see PreserveEOL) so the offsets index the file on disk.
+	IncludeOffsets bool
+
+	// WithEnclosing sets EnclosingSymbol on each reference to the function
+	// or method it is in. Go only.
+	WithEnclosing bool
+
+	// WithID sets ID on each reference to "file#Enclosing:kind:Symbol",
+	// where Enclosing is as in WithEnclosing (empty outside functions and
+	// for languages other than Go). The Nth reference with the same ID in
+	// a file, in source order, gets ":N" appended from the second on.
+	// Positions are left out, so IDs survive reformatting and edits
+	// elsewhere in the file, for diffing reference sets across commits.
+	WithID bool
+
+	// ByPackage sets Package on each reference to the package its file
+	// declares and fills RefsResult.Packages with the number of references
+	// per package (see PackageRefs). Packages are told apart by name only, so same-named
+	// packages in different directories are counted together. Go only.
+	ByPackage bool
+
+	// RefNodeTypes, if non-empty, only counts captures of these node types
+	// as references. An entry "parent/type" also requires the node's parent
+	// to be of type parent, so "call_expression/identifier" keeps direct
+	// calls while dropping a field or variable of the same name.
+	RefNodeTypes []string
+
+	// PathPattern, if set, only scans files whose path relative to Path
+	// matches this doublestar glob (e.g. "**/handlers/*.go").
+	PathPattern string
+
+	// FS, if set, is the filesystem files are read from instead of the OS
+	// filesystem, e.g. an embed.FS or a zip opened with zip.NewReader. Path
+	// and File are then slash-separated fs.FS paths ("." is the root) and
+	// FileOverrides are keyed the same way. It can't be combined with
+	// PathRelativeTo "git".
+	FS fs.FS
+
+	// PathRelativeTo selects what file paths in results are relative to:
+	// "root" (the default) for Path, or "git" for the root of the enclosing
+	// git worktree, falling back to Path outside a repository.
+	PathRelativeTo string
+
+	// BaseDir, if set, is the directory file paths in results are relative
+	// to, whatever Path or File is scanned, e.g. the repository root when
+	// tsq runs elsewhere. Files outside it get "../" paths. It can't be
+	// combined with FS or PathRelativeTo "git".
+	BaseDir string
+
+	// Jobs is the number of parallel workers.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// Schedule controls the order files are handed to workers: "fifo"
+	// (the default) in scan order, or "size" for largest files first, which
+	// shortens the tail when file sizes vary widely.
+	Schedule string
+
+	// Sample, if positive, analyzes only a random sample of this many of
+	// the scanned files, for quick estimates on large trees. The sample is
+	// reproducible: the same Seed over the same files picks the same sample.
+	// It is ignored when File is set.
+	Sample int
+
+	// Seed seeds the random choice of Sample files.
+	Seed int64
+
+	// MaxFiles, if positive, stops the scan once this many files have been
+	// collected, as a safety cap on unexpectedly large trees. Hitting the
+	// cap is reported as a "max_files" diagnostic. It is ignored when File
+	// is set.
+	MaxFiles int
+
+	// Hidden scans dot-prefixed files and directories, which are skipped by
+	// default. Directories such as .git and .venv stay ignored either way.
+	Hidden bool
+
+	// IgnoreDirs names directories to skip besides the default ones, such
+	// as vendor and node_modules, wherever they appear in the tree.
+	IgnoreDirs []string
+
+	// RespectGitignore skips files and directories excluded by the
+	// .gitignore files of Path and its subdirectories, and of its parents
+	// up to the root of the git worktree it is in. Patterns use the
+	// gitignore syntax, including "!" negation and "dir/" directory-only
+	// patterns. It is ignored when File is set.
+	RespectGitignore bool
+
+	// RespectIgnoreSingle applies the ignore rules of directory scans
+	// (ignored directories such as vendor, and hidden files unless Hidden
+	// is set) to File too. An ignored File yields no results and an
+	// "ignored" diagnostic.
+	RespectIgnoreSingle bool
+
+	// MaxBytes skips files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+
+	// StrictParse skips files whose syntax tree contains errors instead of
+	// querying the partial tree, reporting each one as a "parse_error"
+	// diagnostic.
+	StrictParse bool
+
+	// SkipMinified skips files that look minified (very long lines, or
+	// few newlines) or binary (NUL bytes), judged from their first
+	// kilobyte.
+	SkipMinified bool
+
+	// FileOverrides maps file paths to contents that are parsed instead of
+	// the files on disk, e.g. unsaved editor buffers. Paths are resolved
+	// against the working directory. Overrides only replace the contents of
+	// files that are scanned (or named by File); they don't add new files.
+	FileOverrides map[string][]byte
+
+	// PreserveEOL disables normalizing "\r\n" line endings to "\n" before parsing.
+	PreserveEOL bool
+
+	// Diagnostics, if non-nil, receives non-fatal conditions such as
+	// files skipped by StrictParse.
+	Diagnostics *[]Diagnostic
+}
+
+// PublicAPIOptions configures the PublicAPI function.
+type PublicAPIOptions struct {
+	// Language specifies which language to use (e.g., "go").
+	Language string
+
+	// Path is the root directory to scan for files.
+	// If empty, current directory is used.
+	Path string
+
+	// Jobs is the number of parallel workers.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// MaxBytes skips files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+}
+
+// APIDiffOptions configures the APIDiff function.
+type APIDiffOptions struct {
+	// Language specifies which language to use (e.g., "go").
+	Language string
+
+	// Path is the directory whose public API is compared. It must be inside
+	// a git repository. If empty, current directory is used.
+	Path string
+
+	// OldRev is the git revision (commit, branch or tag) of the old API.
+	// Required.
+	OldRev string
+
+	// NewRev is the git revision of the new API. If empty, the files on
+	// disk are used, including uncommitted changes.
+	NewRev string
+
+	// Jobs is the number of parallel workers.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// MaxBytes skips files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+
+	// IgnoreFormatting normalizes the whitespace of signatures before
+	// comparing them, so a declaration that was only reformatted, e.g. by
+	// gofmt, is not reported as changed. By default signatures are compared
+	// as written.
+	IgnoreFormatting bool
+}
+
+// ImportsOptions configures the Imports function.
+type ImportsOptions struct {
+	// Language specifies which language to use (e.g., "go").
+	Language string
+
+	// Path is the root directory to scan for files.
+	// If empty, current directory is used.
+	Path string
+
+	// LocalOnly keeps only imports of packages in the same module.
+	LocalOnly bool
+
+	// Jobs is the number of parallel workers.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// MaxBytes skips files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+}
+
+// CommentsOptions configures the Comments and Undocumented functions.
+type CommentsOptions struct {
+	// Language specifies which language to use (e.g., "go").
+	Language string
+
+	// Path is the root directory to scan for files.
+	// If empty, current directory is used.
+	Path string
+
+	// File is a single file to analyze.
+	// If set, Path is ignored.
+	File string
+
+	// Jobs is the number of parallel workers.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// MaxBytes skips files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+}
+
+// InfoOptions configures the Info function.
+type InfoOptions struct {
+	// Symbol is the symbol name to describe (required).
+	Symbol string
+
+	// Language specifies which language to use (e.g., "go").
+	Language string
+
+	// Path is the root directory to scan for files.
+	// If empty, current directory is used.
+	Path string
+
+	// IncludeContext includes surrounding code context in references.
+	IncludeContext bool
+
 	// Jobs is the number of parallel workers.
 	// If 0, defaults to number of CPUs.
 	Jobs int
 
 	// MaxBytes skips files larger than this size.
-	// If 0, no size limit is enforced.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
 	MaxBytes int64
 }
+
+// TodosOptions configures the Todos function.
+type TodosOptions struct {
+	// Language specifies which language to use (e.g., "go").
+	Language string
+
+	// Path is the root directory to scan for files.
+	// If empty, current directory is used.
+	Path string
+
+	// File is a single file to analyze.
+	// If set, Path is ignored.
+	File string
+
+	// Owner, if set, keeps only annotations attributed to this owner, as in
+	// TODO(owner).
+	Owner string
+
+	// Jobs is the number of parallel workers.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// MaxBytes skips files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+}
+
+// TestsOptions configures the Tests function. Tests understands Go test
+// naming only, so there is no Language option.
+type TestsOptions struct {
+	// Path is the root directory to scan for files.
+	// If empty, current directory is used.
+	Path string
+
+	// File is a single _test.go file to analyze.
+	// If set, Path is ignored.
+	File string
+
+	// Jobs is the number of parallel workers.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// MaxBytes skips files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+}
+
+// HotspotsOptions configures the Hotspots function.
+type HotspotsOptions struct {
+	// Language specifies which language to use (e.g., "go").
+	Language string
+
+	// Path is the root directory to scan for files.
+	// If empty, current directory is used.
+	Path string
+
+	// Top limits the result to the N most referenced symbols.
+	// If 0, all symbols are returned.
+	Top int
+
+	// Jobs is the number of parallel workers.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// MaxBytes skips files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+}
+
+// ShadowsOptions configures the Shadows function.
+type ShadowsOptions struct {
+	// Path is the root directory to scan for files.
+	// If empty, current directory is used.
+	Path string
+
+	// Jobs is the number of parallel workers.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// MaxBytes skips files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+}
+
+// ChurnOptions configures the Churn function.
+type ChurnOptions struct {
+	// Symbol is the symbol to measure (required): a name, or Receiver.Name
+	// for a single method.
+	Symbol string
+
+	// Language specifies which language to use (e.g., "go"). Empty or
+	// AutoDetect picks each file's language by its extension.
+	Language string
+
+	// Path is the root directory to scan for files. It must be in a git
+	// repository. If empty, current directory is used.
+	Path string
+
+	// Jobs is the number of parallel workers.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// MaxBytes skips files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+}
+
+// ModuleOptions configures the Module function.
+type ModuleOptions struct {
+	// Path is a directory in the module; go.mod is looked for there and in
+	// its parents. If empty, current directory is used.
+	Path string
+}
+
+// StatsOptions configures the Stats function.
+type StatsOptions struct {
+	// Language specifies which language to count (e.g., "go").
+	// Ignored with AllLanguages.
+	Language string
+
+	// AllLanguages counts every registered language instead of Language.
+	AllLanguages bool
+
+	// Path is the root directory to scan for files.
+	// If empty, current directory is used.
+	Path string
+
+	// Hidden scans dot-prefixed files and directories, which are skipped by
+	// default.
+	Hidden bool
+
+	// IgnoreDirs names directories to skip besides the default ones, as for
+	// SymbolsOptions.
+	IgnoreDirs []string
+
+	// RespectGitignore skips files and directories excluded by .gitignore
+	// files, as for SymbolsOptions.
+	RespectGitignore bool
+
+	// Jobs is the number of parallel workers.
+	// If 0, defaults to number of CPUs.
+	Jobs int
+
+	// MaxBytes skips files larger than this size.
+	// If 0, the language's default is used (see LanguageMaxBytes).
+	// If negative, no size limit is enforced.
+	MaxBytes int64
+}
+
+// SyntaxTreeOptions configures the SyntaxTree function.
+type SyntaxTreeOptions struct {
+	// Language specifies which language to use (e.g., "go").
+	Language string
+
+	// File is the file to parse (required).
+	File string
+
+	// NamedOnly leaves out anonymous nodes such as punctuation and keywords.
+	NamedOnly bool
+
+	// MaxDepth, if positive, stops the tree at this depth; the root is at
+	// depth 1 and nodes at MaxDepth have no children.
+	MaxDepth int
+
+	// PreserveEOL disables normalizing "\r\n" line endings to "\n" before parsing.
+	PreserveEOL bool
+}
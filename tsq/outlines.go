@@ -0,0 +1,97 @@
+package tsq
+
+import (
+	"context"
+	"runtime"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// StreamOutlines outlines every file under opts.Path and hands each
+// FileOutline to emit, on the calling goroutine, as soon as it is built, so
+// memory stays bounded however many files there are. Outlines arrive in
+// completion order rather than by path. With an empty or AutoDetect
+// Language each file is outlined in the language of its extension, and
+// files of no registered language are skipped.
+//
+// If emit returns an error, no more files are outlined and that error is
+// returned; if ctx is done first, ctx.Err() is.
+func StreamOutlines(ctx context.Context, opts OutlineOptions, emit func(FileOutline) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.MaxSourceLines == 0 {
+		opts.MaxSourceLines = 5
+	}
+	if opts.Jobs == 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+
+	language, err := resolveLanguage(opts.Language)
+	if err != nil {
+		return err
+	}
+	var outlineQuery *query
+	if language != nil {
+		if outlineQuery, err = newQuery(language.OutlineQuery(), language); err != nil {
+			return err
+		}
+	}
+
+	sc := newScanner(scannerConfig{
+		root:     opts.Path,
+		language: language,
+		maxBytes: opts.MaxBytes,
+	})
+	files, err := sc.collect()
+	if err != nil {
+		return err
+	}
+
+	var diags diagnostics
+	defer diags.flush(opts.Diagnostics)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cfg := workerConfig{
+		ctx:         ctx,
+		jobs:        opts.Jobs,
+		preserveEOL: opts.PreserveEOL,
+		overrides:   absOverrides(nil, opts.FileOverrides),
+		diags:       &diags,
+	}
+	if language == nil {
+		cfg.queries = newLanguageQueries(Language.OutlineQuery)
+	}
+	var docQueries *languageQueries
+	if opts.WithDoc {
+		docQueries = newLanguageQueries(commentsQuery)
+	}
+
+	var emitErr error
+	streamTreeWorkers(language, outlineQuery, files, cfg, func(job FileJob, root *sitter.Node, matches []QueryMatch, source []byte) []FileOutline {
+		jobLanguage := language
+		if job.Language != nil {
+			jobLanguage = job.Language
+		}
+		var docQuery *query
+		if docQueries != nil {
+			docQuery = docQueries.get(jobLanguage, job.DisplayPath, &diags)
+		}
+		return []FileOutline{fileOutline(job, jobLanguage, root, source, matches, docQuery, opts)}
+	}, func(outline FileOutline) {
+		if emitErr != nil {
+			return
+		}
+		if emitErr = emit(outline); emitErr != nil {
+			cancel()
+		}
+	})
+	if emitErr != nil {
+		return emitErr
+	}
+	return ctx.Err()
+}
@@ -1,8 +1,13 @@
 package tsq
 
 import (
+	"bytes"
 	"fmt"
-	"os"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
 
 	sitter "github.com/smacker/go-tree-sitter"
 )
@@ -11,11 +16,29 @@ import (
 type parser struct {
 	parser *sitter.Parser
 	lang   Language
+
+	// preserveEOL disables CRLF normalization in parseFile.
+	preserveEOL bool
+
+	// overrides maps absolute paths to contents that parseFile uses instead
+	// of reading the file from disk.
+	overrides map[string][]byte
+
+	// fsys, if set, is the filesystem parseFile reads from; paths are then
+	// fs.FS paths rather than absolute ones.
+	fsys fs.FS
+
+	// err is set if the language's grammar can't be used; parseFile
+	// returns it.
+	err error
 }
 
 // newParser creates a new parser for the given language.
 func newParser(language Language) *parser {
 	p := sitter.NewParser()
+	if err := checkGrammarABI(language); err != nil {
+		return &parser{parser: p, lang: language, err: err}
+	}
 	p.SetLanguage(language.TreeSitterLang())
 	return &parser{
 		parser: p,
@@ -25,26 +48,161 @@ func newParser(language Language) *parser {
 
 // parse parses source code and returns the syntax tree.
 func (p *parser) parse(source []byte) *sitter.Tree {
-	return p.parser.Parse(nil, source)
+	return p.parseIncremental(nil, source)
+}
+
+// parseIncremental parses source, reusing the unchanged parts of oldTree,
+// which must already have been edited to match. A nil oldTree parses source
+// from scratch.
+func (p *parser) parseIncremental(oldTree *sitter.Tree, source []byte) *sitter.Tree {
+	return p.parser.Parse(oldTree, source)
+}
+
+// Parser is a reusable parser of one language, for editor integrations
+// that keep a file's tree between edits and reparse it incrementally. It
+// must be closed when no longer needed, and must not be used concurrently.
+type Parser struct {
+	p *parser
+}
+
+// NewParser returns a Parser for language.
+func NewParser(language Language) (*Parser, error) {
+	p := newParser(language)
+	if p.err != nil {
+		p.parser.Close()
+		return nil, p.err
+	}
+	return &Parser{p: p}, nil
+}
+
+// ParseIncremental parses source, reusing the unchanged parts of oldTree,
+// the tree of the source before an edit. oldTree must already have been
+// edited to match, with oldTree.Edit(SourceEdit(oldSource, source)); the
+// result is the same tree a fresh parse builds, at a fraction of the cost
+// for small edits. A nil oldTree parses source from scratch.
+func (p *Parser) ParseIncremental(oldTree *sitter.Tree, source []byte) *sitter.Tree {
+	return p.p.parseIncremental(oldTree, source)
+}
+
+// Close frees the parser. Trees it built stay valid.
+func (p *Parser) Close() {
+	p.p.parser.Close()
+}
+
+// SourceEdit describes the change from oldSource to newSource as a single
+// edit spanning everything between their common prefix and common suffix,
+// for sitter.Tree.Edit before ParseIncremental.
+func SourceEdit(oldSource, newSource []byte) sitter.EditInput {
+	start := 0
+	for start < len(oldSource) && start < len(newSource) && oldSource[start] == newSource[start] {
+		start++
+	}
+	oldEnd, newEnd := len(oldSource), len(newSource)
+	for oldEnd > start && newEnd > start && oldSource[oldEnd-1] == newSource[newEnd-1] {
+		oldEnd--
+		newEnd--
+	}
+
+	return sitter.EditInput{
+		StartIndex:  uint32(start),
+		OldEndIndex: uint32(oldEnd),
+		NewEndIndex: uint32(newEnd),
+		StartPoint:  pointAt(oldSource, start),
+		OldEndPoint: pointAt(oldSource, oldEnd),
+		NewEndPoint: pointAt(newSource, newEnd),
+	}
+}
+
+// pointAt returns the zero-based row and byte column of offset in source.
+func pointAt(source []byte, offset int) sitter.Point {
+	before := source[:offset]
+	row := bytes.Count(before, []byte("\n"))
+	col := offset - (bytes.LastIndexByte(before, '\n') + 1)
+	return sitter.Point{Row: uint32(row), Column: uint32(col)}
+}
+
+// lineAround returns the line of source containing the byte at offset,
+// without its line ending, which may be "\n", "\r\n" or, on the last line
+// of a file with no trailing newline, nothing at all. It returns "" for
+// offsets outside source.
+func lineAround(source []byte, offset int) string {
+	if offset < 0 || offset > len(source) {
+		return ""
+	}
+	start := bytes.LastIndexByte(source[:offset], '\n') + 1
+	end := len(source)
+	if i := bytes.IndexByte(source[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	return string(bytes.TrimSuffix(source[start:end], []byte("\r")))
 }
 
 // parseFile reads and parses a file.
+//
+// Unless preserveEOL is set, "\r\n" line endings are rewritten to "\n" before
+// parsing so captured text never carries a stray "\r". Lines and columns are
+// unaffected because "\r" only ever precedes a newline, but any byte offsets
+// refer to the normalized source rather than the file on disk.
 func (p *parser) parseFile(path string) (*sitter.Tree, []byte, error) {
-	source, err := os.ReadFile(path)
-	if err != nil {
-		return nil, nil, fmt.Errorf("read file: %w", err)
+	if p.err != nil {
+		return nil, nil, p.err
+	}
+	source, ok := p.overrides[path]
+	if !ok {
+		var err error
+		source, err = readFile(p.fsys, path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read file: %w", err)
+		}
+	}
+	if !p.preserveEOL {
+		source = normalizeEOL(source)
 	}
 	return p.parse(source), source, nil
 }
 
+// absOverrides re-keys file overrides by absolute path, or by cleaned path
+// when reading from fsys, so they can be matched against FileJob.AbsPath.
+func absOverrides(fsys fs.FS, overrides map[string][]byte) map[string][]byte {
+	if len(overrides) == 0 {
+		return nil
+	}
+	abs := make(map[string][]byte, len(overrides))
+	for name, source := range overrides {
+		if fsys != nil {
+			name = path.Clean(filepath.ToSlash(name))
+		} else if p, err := filepath.Abs(name); err == nil {
+			name = p
+		}
+		abs[name] = source
+	}
+	return abs
+}
+
+// normalizeEOL converts CRLF line endings to LF.
+func normalizeEOL(source []byte) []byte {
+	if !bytes.Contains(source, []byte("\r\n")) {
+		return source
+	}
+	return bytes.ReplaceAll(source, []byte("\r\n"), []byte("\n"))
+}
+
 // query represents a compiled tree-sitter query.
 type query struct {
 	query        *sitter.Query
 	captureNames []string
+	predicates   [][]textPredicate // by pattern index
+
+	// preserveInvalidUTF8 keeps capture text byte-for-byte instead of
+	// replacing invalid UTF-8 sequences with U+FFFD.
+	preserveInvalidUTF8 bool
 }
 
 // newQuery compiles a tree-sitter query string.
 func newQuery(queryStr string, language Language) (*query, error) {
+	if err := checkGrammarABI(language); err != nil {
+		return nil, err
+	}
 	q, err := sitter.NewQuery([]byte(queryStr), language.TreeSitterLang())
 	if err != nil {
 		return nil, fmt.Errorf("compile query: %w", err)
@@ -56,23 +214,46 @@ func newQuery(queryStr string, language Language) (*query, error) {
 		captureNames[i] = q.CaptureNameForId(uint32(i))
 	}
 
+	predicates, err := compilePredicates(q)
+	if err != nil {
+		return nil, err
+	}
+
 	return &query{
 		query:        q,
 		captureNames: captureNames,
+		predicates:   predicates,
 	}, nil
 }
 
-// run executes the query on a syntax tree and returns matches.
-func (q *query) run(tree *sitter.Tree, source []byte, displayPath string) []QueryMatch {
+// run executes the query on a syntax tree and returns the matches whose
+// #eq? and #match? predicates (and their negations) hold.
+func (q *query) run(root *sitter.Node, source []byte, displayPath string) []QueryMatch {
+	matches, _ := q.runLimited(root, source, displayPath, nil, 0)
+	return matches
+}
+
+// runLimited is run keeping only the matches keep accepts, if keep is set,
+// and, if limit is positive, stopping once limit matches are kept. capped
+// reports whether it stopped with more matches left; keep isn't called on
+// them, so they may be ones it would reject.
+func (q *query) runLimited(
+	root *sitter.Node, source []byte, displayPath string, keep func(QueryMatch) bool, limit int,
+) (matches []QueryMatch, capped bool) {
 	cursor := sitter.NewQueryCursor()
-	cursor.Exec(q.query, tree.RootNode())
+	cursor.Exec(q.query, root)
 
-	var matches []QueryMatch
 	for {
 		match, ok := cursor.NextMatch()
 		if !ok {
 			break
 		}
+		if !q.matchesPredicates(match, source) {
+			continue
+		}
+		if limit > 0 && len(matches) == limit {
+			return matches, true
+		}
 
 		result := QueryMatch{
 			File:    displayPath,
@@ -82,24 +263,120 @@ func (q *query) run(tree *sitter.Tree, source []byte, displayPath string) []Quer
 		for _, capture := range match.Captures {
 			name := q.captureName(capture.Index)
 			node := capture.Node
-			start := node.StartPoint()
-			end := node.EndPoint()
+
+			text := node.Content(source)
+			sanitized := false
+			if !q.preserveInvalidUTF8 && !utf8.ValidString(text) {
+				text = strings.ToValidUTF8(text, string(utf8.RuneError))
+				sanitized = true
+			}
 
 			result.Captures = append(result.Captures, CaptureResult{
-				Name:     name,
-				NodeType: node.Type(),
-				Text:     node.Content(source),
-				Range: Range{
-					Start: Position{Line: int(start.Row) + 1, Column: int(start.Column) + 1},
-					End:   Position{Line: int(end.Row) + 1, Column: int(end.Column) + 1},
-				},
+				Name:      name,
+				NodeType:  node.Type(),
+				Text:      text,
+				Sanitized: sanitized,
+				Range:     nodeRange(node),
+				startByte: int(node.StartByte()),
+				endByte:   int(node.EndByte()),
+				node:      node,
 			})
 		}
 
+		if keep != nil && !keep(result) {
+			continue
+		}
 		matches = append(matches, result)
 	}
 
-	return matches
+	return matches, false
+}
+
+// nodeAtLine returns the smallest named node under root that spans all of
+// the given 1-based line, ignoring leading and trailing whitespace, or nil if
+// the source has no such line.
+func nodeAtLine(root *sitter.Node, source []byte, line int) *sitter.Node {
+	start := 0
+	for i := 1; i < line; i++ {
+		next := bytes.IndexByte(source[start:], '\n')
+		if next < 0 {
+			return nil
+		}
+		start += next + 1
+	}
+	end := len(source)
+	if i := bytes.IndexByte(source[start:], '\n'); i >= 0 {
+		end = start + i
+	}
+	for start < end && isBlank(source[start]) {
+		start++
+	}
+	for end > start && isBlank(source[end-1]) {
+		end--
+	}
+
+	node := root
+	for {
+		var inner *sitter.Node
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			child := node.NamedChild(i)
+			if int(child.StartByte()) <= start && int(child.EndByte()) >= end {
+				inner = child
+				break
+			}
+		}
+		if inner == nil {
+			return node
+		}
+		node = inner
+	}
+}
+
+func isBlank(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r'
+}
+
+// errorRanges returns the ranges of the ERROR and MISSING nodes under root,
+// where the parser had to recover from a syntax error. Errors nested inside
+// an ERROR node are covered by its range and not reported separately. A
+// zero-width MISSING node at the end of the file is not an error: grammars
+// such as Go's insert one for the terminator of a last declaration ending
+// in "}" or ")" without a trailing newline.
+func errorRanges(root *sitter.Node) []Range {
+	var ranges []Range
+	var walk func(n *sitter.Node)
+	walk = func(n *sitter.Node) {
+		if n.IsMissing() && n.StartByte() == n.EndByte() && n.StartByte() >= root.EndByte() {
+			return
+		}
+		if n.IsError() || n.IsMissing() {
+			ranges = append(ranges, nodeRange(n))
+			return
+		}
+		if !n.HasError() {
+			return
+		}
+		for i := 0; i < int(n.ChildCount()); i++ {
+			walk(n.Child(i))
+		}
+	}
+	walk(root)
+	return ranges
+}
+
+// hasSyntaxErrors reports whether the tree under root has errors, as
+// reported by errorRanges.
+func hasSyntaxErrors(root *sitter.Node) bool {
+	return root.HasError() && len(errorRanges(root)) > 0
+}
+
+// nodeRange returns the 1-based range of a node.
+func nodeRange(n *sitter.Node) Range {
+	start, end := n.StartPoint(), n.EndPoint()
+	return Range{
+		Start: Position{Line: int(start.Row) + 1, Column: int(start.Column) + 1},
+		End:   Position{Line: int(end.Row) + 1, Column: int(end.Column) + 1},
+	}
 }
 
 func (q *query) captureName(index uint32) string {
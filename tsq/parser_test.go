@@ -0,0 +1,235 @@
+package tsq
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf8"
+	"unsafe"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileOverrides(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "tsq-overrides-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	edited := filepath.Join(tmpDir, "edited.go")
+	untouched := filepath.Join(tmpDir, "untouched.go")
+	require.NoError(t, os.WriteFile(edited, []byte("package p\n\nfunc OnDisk() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(untouched, []byte("package p\n\nfunc Untouched() {}\n"), 0644))
+
+	overrides := map[string][]byte{
+		edited: []byte("package p\n\nfunc Unsaved() {}\n\ntype Buffer struct{}\n"),
+	}
+
+	names := func(results []SymbolsResult) map[string][]string {
+		out := make(map[string][]string)
+		for _, r := range results {
+			for _, sym := range r.Symbols {
+				out[r.File] = append(out[r.File], sym.Name)
+			}
+		}
+		return out
+	}
+
+	onDisk, err := Symbols(SymbolsOptions{Path: tmpDir, Jobs: 1})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"edited.go":    {"OnDisk"},
+		"untouched.go": {"Untouched"},
+	}, names(onDisk))
+
+	overridden, err := Symbols(SymbolsOptions{Path: tmpDir, Jobs: 1, FileOverrides: overrides})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"edited.go":    {"Unsaved", "Buffer"},
+		"untouched.go": {"Untouched"},
+	}, names(overridden), "overrides replace contents, other files fall back to disk")
+
+	outline, err := Outline(OutlineOptions{File: edited, FileOverrides: overrides})
+	require.NoError(t, err)
+	require.Len(t, outline.Symbols, 2)
+	require.Equal(t, "Unsaved", outline.Symbols[0].Name)
+
+	// Relative override paths are resolved against the working directory
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tmpDir))
+	defer os.Chdir(wd)
+
+	refs, err := Refs(RefsOptions{
+		Symbol:        "Buffer",
+		Path:          ".",
+		Jobs:          1,
+		FileOverrides: map[string][]byte{"untouched.go": []byte("package p\n\nvar b Buffer\n")},
+	})
+	require.NoError(t, err)
+	require.Len(t, refs.References, 1)
+	require.Equal(t, "untouched.go", refs.References[0].File)
+}
+
+func TestParseIncremental(t *testing.T) {
+	p := newParser(Get("go"))
+	defer p.parser.Close()
+	incremental, err := NewParser(Get("go"))
+	require.NoError(t, err)
+	defer incremental.Close()
+
+	tests := []struct {
+		name     string
+		old, new string
+	}{
+		{
+			name: "rename on one line",
+			old:  "package p\n\nfunc Old() {}\n\nfunc Keep() {}\n",
+			new:  "package p\n\nfunc Renamed() {}\n\nfunc Keep() {}\n",
+		},
+		{
+			name: "insert lines",
+			old:  "package p\n\nfunc A() {}\n\nfunc B() {}\n",
+			new:  "package p\n\nfunc A() {\n\tx := 1\n\t_ = x\n}\n\nfunc B() {}\n",
+		},
+		{
+			name: "delete declaration",
+			old:  "package p\n\ntype T struct{}\n\nfunc B() {}\n",
+			new:  "package p\n\nfunc B() {}\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			oldTree := p.parse([]byte(tc.old))
+			oldTree.Edit(SourceEdit([]byte(tc.old), []byte(tc.new)))
+
+			reparsed := incremental.ParseIncremental(oldTree, []byte(tc.new))
+			fresh := p.parse([]byte(tc.new))
+
+			require.Equal(t, fresh.RootNode().String(), reparsed.RootNode().String())
+			require.Equal(t, nodeSpans(fresh.RootNode()), nodeSpans(reparsed.RootNode()))
+		})
+	}
+}
+
+// nodeSpans lists the type, byte range and start point of every node in the
+// tree.
+func nodeSpans(n *sitter.Node) []string {
+	spans := []string{fmt.Sprintf("%s %d-%d %v", n.Type(), n.StartByte(), n.EndByte(), n.StartPoint())}
+	for i := 0; i < int(n.ChildCount()); i++ {
+		spans = append(spans, nodeSpans(n.Child(i))...)
+	}
+	return spans
+}
+
+func TestQueryRunLimited(t *testing.T) {
+	language := Get("go")
+	q, err := newQuery("((function_declaration name: (identifier) @name))", language)
+	require.NoError(t, err)
+	source := []byte("package p\n\nfunc a() {}\nfunc b() {}\nfunc c() {}\nfunc d() {}\nfunc e() {}\n")
+	root := newParser(language).parse(source).RootNode()
+
+	seen := 0
+	keepOdd := func(m QueryMatch) bool {
+		seen++
+		return seen%2 == 1
+	}
+	matches, capped := q.runLimited(root, source, "p.go", keepOdd, 2)
+	require.True(t, capped)
+	require.Len(t, matches, 2)
+	require.Equal(t, "a", matches[0].Captures[0].Text)
+	require.Equal(t, "c", matches[1].Captures[0].Text)
+	require.Equal(t, 3, seen, "keep isn't called once the limit is reached")
+
+	matches, capped = q.runLimited(root, source, "p.go", nil, 5)
+	require.False(t, capped)
+	require.Len(t, matches, 5)
+}
+
+func TestSanitizeInvalidUTF8(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "latin1.go")
+	// "caf\xe9" is Latin-1, not valid UTF-8.
+	require.NoError(t, os.WriteFile(path, []byte("package p\n\nvar Name = \"caf\xe9\"\n"), 0644))
+
+	opts := QueryOptions{
+		Query: `(interpreted_string_literal) @str`,
+		File:  path,
+	}
+
+	matches, err := Query(opts)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	c := matches[0].Captures[0]
+	require.Equal(t, "\"caf�\"", c.Text)
+	require.True(t, c.Sanitized)
+
+	data, err := json.Marshal(matches)
+	require.NoError(t, err)
+	require.True(t, utf8.Valid(data))
+	require.Contains(t, string(data), `"sanitized":true`)
+
+	opts.PreserveInvalidUTF8 = true
+	matches, err = Query(opts)
+	require.NoError(t, err)
+	c = matches[0].Captures[0]
+	require.Equal(t, "\"caf\xe9\"", c.Text)
+	require.False(t, c.Sanitized)
+}
+
+func TestLineAround(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		offset int
+		want   string
+	}{
+		{"first line", "a := 1\nb := 2\n", 2, "a := 1"},
+		{"middle line", "a\nb := 2\nc", 4, "b := 2"},
+		{"last line without newline", "a\nlast()", 4, "last()"},
+		{"offset at end of source", "a\nlast()", 8, "last()"},
+		{"crlf", "a\r\nb()\r\nc", 4, "b()"},
+		{"crlf last line without newline", "a\r\nlast()", 5, "last()"},
+		{"empty line", "a\n\nb", 2, ""},
+		{"empty source", "", 0, ""},
+		{"out of range", "abc", 4, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, lineAround([]byte(tc.source), tc.offset))
+		})
+	}
+}
+
+// abiLanguage is the Go language with its grammar replaced.
+type abiLanguage struct {
+	*Go
+	lang *sitter.Language
+}
+
+func (l abiLanguage) TreeSitterLang() *sitter.Language { return l.lang }
+
+func TestGrammarABI(t *testing.T) {
+	require.NoError(t, checkGrammarABI(Get("go")))
+
+	// A fake TSLanguage whose version field is out of range. It is never
+	// handed to the runtime: the check rejects it first.
+	fake := make([]uint32, 64)
+	fake[0] = 99
+	lang := abiLanguage{Go: &Go{}, lang: sitter.NewLanguage(unsafe.Pointer(&fake[0]))}
+
+	_, err := newQuery(`(identifier) @id`, lang)
+	require.ErrorIs(t, err, ErrUnsupportedGrammarABI)
+	require.EqualError(t, err, "unsupported grammar ABI version: go grammar has version 99, want 13 to 14")
+
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+	_, _, err = newParser(lang).parseFile(path)
+	require.ErrorIs(t, err, ErrUnsupportedGrammarABI)
+
+	_, err = newQuery(`(identifier) @id`, abiLanguage{Go: &Go{}})
+	require.ErrorContains(t, err, "has version 0")
+}
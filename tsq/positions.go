@@ -0,0 +1,255 @@
+package tsq
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// CompactPosition is a Position that serializes as a [line, column] array.
+type CompactPosition Position
+
+func (p CompactPosition) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int{p.Line, p.Column})
+}
+
+func (p *CompactPosition) UnmarshalJSON(data []byte) error {
+	var a [2]int
+	if err := json.Unmarshal(data, &a); err != nil {
+		return fmt.Errorf("compact position: %w", err)
+	}
+	p.Line, p.Column = a[0], a[1]
+	return nil
+}
+
+// CompactRange is a Range that serializes as [[line, column], [line, column]].
+type CompactRange Range
+
+func (r CompactRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]CompactPosition{CompactPosition(r.Start), CompactPosition(r.End)})
+}
+
+func (r *CompactRange) UnmarshalJSON(data []byte) error {
+	var a [2]CompactPosition
+	if err := json.Unmarshal(data, &a); err != nil {
+		return fmt.Errorf("compact range: %w", err)
+	}
+	r.Start, r.End = Position(a[0]), Position(a[1])
+	return nil
+}
+
+// CompactPositions returns a copy of v for JSON encoding in which every
+// Position is a CompactPosition and every Range a CompactRange. Go's JSON
+// tags can't switch formats at runtime, so the copy mirrors v's structs as
+// objects with the same keys, in the same order and with the same
+// omitempty rules; values that hold no positions are kept as they are.
+func CompactPositions(v any) any {
+	return compactValue(reflect.ValueOf(v))
+}
+
+var (
+	positionType  = reflect.TypeOf(Position{})
+	rangeType     = reflect.TypeOf(Range{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textType      = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func compactValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	switch t := v.Type(); {
+	case t == positionType:
+		return CompactPosition(v.Interface().(Position))
+	case t == rangeType:
+		return CompactRange(v.Interface().(Range))
+	case !typeHoldsPositions(t):
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return compactValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		elems := make([]any, v.Len())
+		for i := range elems {
+			elems[i] = compactValue(v.Index(i))
+		}
+		return elems
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key().Interface()
+			if tm, ok := key.(encoding.TextMarshaler); ok {
+				text, err := tm.MarshalText()
+				if err != nil {
+					return v.Interface()
+				}
+				key = string(text)
+			}
+			m[fmt.Sprint(key)] = compactValue(iter.Value())
+		}
+		return m
+	case reflect.Struct:
+		var obj compactObject
+		compactFields(v, &obj, 0)
+		return obj
+	}
+	return v.Interface()
+}
+
+// positionTypes caches typeHoldsPositions by type.
+var positionTypes sync.Map
+
+// typeHoldsPositions is holdsPositions, cached.
+func typeHoldsPositions(t reflect.Type) bool {
+	if holds, ok := positionTypes.Load(t); ok {
+		return holds.(bool)
+	}
+	holds := holdsPositions(t, map[reflect.Type]bool{})
+	positionTypes.Store(t, holds)
+	return holds
+}
+
+// holdsPositions reports whether values of t can contain a Position or
+// Range that compactValue replaces. Interfaces may hold anything; types
+// that encode themselves are left alone. seen breaks cycles of recursive
+// types.
+func holdsPositions(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == positionType || t == rangeType {
+		return true
+	}
+	if seen[t] || t.Implements(marshalerType) || t.Implements(textType) {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return holdsPositions(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if holdsPositions(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compactFields adds the JSON members of struct v, embedded depth levels
+// deep, to obj as encoding/json would encode them, inlining untagged
+// embedded structs.
+func compactFields(v reflect.Value, obj *compactObject, depth int) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				ft, fv = ft.Elem(), fv.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				compactFields(fv, obj, depth+1)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyJSON(fv) {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		obj.add(compactMember{key: name, value: compactValue(fv), depth: depth})
+	}
+}
+
+// isEmptyJSON reports whether omitempty drops v.
+func isEmptyJSON(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero() && v.Kind() != reflect.Struct
+}
+
+// compactObject is a struct as encoding/json would encode it, with its
+// members in field order.
+type compactObject []compactMember
+
+type compactMember struct {
+	key   string
+	value any
+	depth int // embedding depth of the field
+}
+
+// add adds a member. As in encoding/json, of the fields with the same key
+// the least deeply embedded one wins; of equally deep ones, the first.
+func (o *compactObject) add(m compactMember) {
+	for i, prev := range *o {
+		if prev.key != m.key {
+			continue
+		}
+		if m.depth < prev.depth {
+			*o = append(slices.Delete(*o, i, i+1), m)
+		}
+		return
+	}
+	*o = append(*o, m)
+}
+
+func (o compactObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := enc.Encode(m.key); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(':')
+		if err := enc.Encode(m.value); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,98 @@
+package tsq
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactRangeRoundTrip(t *testing.T) {
+	r := Range{Start: Position{Line: 12, Column: 5}, End: Position{Line: 12, Column: 9}}
+
+	data, err := json.Marshal(CompactRange(r))
+	require.NoError(t, err)
+	require.Equal(t, `[[12,5],[12,9]]`, string(data))
+
+	var got CompactRange
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, r, Range(got))
+
+	var p CompactPosition
+	require.NoError(t, json.Unmarshal([]byte(`[3,7]`), &p))
+	require.Equal(t, Position{Line: 3, Column: 7}, Position(p))
+	require.Error(t, json.Unmarshal([]byte(`{"line":3,"column":7}`), &p))
+}
+
+func TestCompactPositions(t *testing.T) {
+	in := []any{
+		Symbol{
+			Name:  "Foo",
+			Kind:  "function",
+			File:  "a.go",
+			Range: Range{Start: Position{Line: 1, Column: 0}, End: Position{Line: 3, Column: 1}},
+		},
+		Reference{Symbol: "Foo", Kind: "call", File: "b.go", Position: Position{Line: 7, Column: 2}},
+		map[string]any{"start": "x", "end": "y"},
+	}
+	got, err := json.Marshal(CompactPositions(in))
+	require.NoError(t, err)
+	require.Equal(t,
+		`[{"name":"Foo","kind":"function","visibility":"","file":"a.go","range":[[1,0],[3,1]]},`+
+			`{"symbol":"Foo","kind":"call","file":"b.go","position":[7,2]},`+
+			`{"end":"y","start":"x"}]`,
+		string(got))
+
+	// The compact form decodes back into the wrapper types
+	var decoded []struct {
+		Range    *CompactRange    `json:"range"`
+		Position *CompactPosition `json:"position"`
+	}
+	require.NoError(t, json.Unmarshal(got, &decoded))
+	require.Equal(t, in[0].(Symbol).Range, Range(*decoded[0].Range))
+	require.Equal(t, in[1].(Reference).Position, Position(*decoded[1].Position))
+}
+
+func TestCompactPositionsEncoding(t *testing.T) {
+	type Inner struct {
+		At   Position `json:"at"`
+		Name string   `json:"name"`
+	}
+	type Outer struct {
+		Inner
+		Name    string    `json:"name"`
+		Maybe   *Position `json:"maybe,omitempty"`
+		Skipped Range     `json:"-"`
+		Tree    *TreeNode `json:"tree"`
+		HTML    string    `json:"html"`
+		hidden  Position
+	}
+	in := Outer{
+		Inner: Inner{At: Position{Line: 1, Column: 2}, Name: "inner"},
+		Name:  "outer",
+		Tree: &TreeNode{Type: "source_file", Named: true, Children: []*TreeNode{
+			{Type: "comment", Named: true, Range: Range{End: Position{Line: 1, Column: 4}}},
+		}},
+		HTML: "<a & b>",
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	require.NoError(t, enc.Encode(CompactPositions(in)))
+	require.Equal(t,
+		`{"at":[1,2],"name":"outer",`+
+			`"tree":{"type":"source_file","named":true,"range":[[0,0],[0,0]],"children":[`+
+			`{"type":"comment","named":true,"range":[[0,0],[1,4]]}]},`+
+			`"html":"<a & b>"}`+"\n",
+		buf.String())
+
+	// Values without positions encode as they are.
+	got, err := json.Marshal(CompactPositions(map[string]int{"a": 1}))
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1}`, string(got))
+	got, err = json.Marshal(CompactPositions([]Symbol(nil)))
+	require.NoError(t, err)
+	require.Equal(t, `null`, string(got))
+}
@@ -0,0 +1,101 @@
+package tsq
+
+import (
+	"fmt"
+	"regexp"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// textPredicate is a #eq?, #not-eq?, #match? or #not-match? predicate of a
+// query pattern, which tree-sitter leaves to the caller to evaluate.
+type textPredicate struct {
+	capture string         // capture whose text is tested
+	negate  bool           // #not-eq? or #not-match?
+	value   string         // #eq?: literal to compare with, unless other is set
+	other   string         // #eq?: capture to compare with
+	re      *regexp.Regexp // #match?
+}
+
+// compilePredicates returns the text predicates of each pattern of q, by
+// pattern index. Other predicates and directives (#set!, #is?, ...) are
+// ignored. tree-sitter has already checked their arguments when compiling
+// q; regular expressions are checked here.
+func compilePredicates(q *sitter.Query) ([][]textPredicate, error) {
+	var predicates [][]textPredicate
+	for i := uint32(0); i < q.PatternCount(); i++ {
+		var patternPredicates []textPredicate
+		for _, steps := range q.PredicatesForPattern(i) {
+			if len(steps) != 4 || steps[1].Type != sitter.QueryPredicateStepTypeCapture {
+				continue
+			}
+			p := textPredicate{capture: q.CaptureNameForId(steps[1].ValueId)}
+			switch operator := q.StringValueForId(steps[0].ValueId); operator {
+			case "eq?", "not-eq?":
+				p.negate = operator == "not-eq?"
+				if steps[2].Type == sitter.QueryPredicateStepTypeCapture {
+					p.other = q.CaptureNameForId(steps[2].ValueId)
+				} else {
+					p.value = q.StringValueForId(steps[2].ValueId)
+				}
+			case "match?", "not-match?":
+				p.negate = operator == "not-match?"
+				pattern := q.StringValueForId(steps[2].ValueId)
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					return nil, fmt.Errorf("compile query: invalid #%s regexp %q: %w", operator, pattern, err)
+				}
+				p.re = re
+			default:
+				continue
+			}
+			patternPredicates = append(patternPredicates, p)
+		}
+		predicates = append(predicates, patternPredicates)
+	}
+	return predicates, nil
+}
+
+// matchesPredicates reports whether the captures of match satisfy the text
+// predicates of its pattern. A predicate on a capture that is absent from
+// the match, such as an optional one, holds; one on a quantified capture
+// must hold for each of its nodes.
+func (q *query) matchesPredicates(match *sitter.QueryMatch, source []byte) bool {
+	if int(match.PatternIndex) >= len(q.predicates) {
+		return true
+	}
+	for _, p := range q.predicates[match.PatternIndex] {
+		var other *sitter.Node
+		if p.other != "" {
+			for _, c := range match.Captures {
+				if q.captureName(c.Index) == p.other {
+					other = c.Node
+					break
+				}
+			}
+			if other == nil {
+				continue
+			}
+		}
+
+		for _, c := range match.Captures {
+			if q.captureName(c.Index) != p.capture {
+				continue
+			}
+			text := c.Node.Content(source)
+			var ok bool
+			switch {
+			case p.re != nil:
+				ok = p.re.MatchString(text)
+			case other != nil:
+				ok = text == other.Content(source)
+			default:
+				ok = text == p.value
+			}
+			if ok == p.negate {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package tsq
+
+import (
+	"errors"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// PackageAPI is the exported API surface of a single package.
+type PackageAPI struct {
+	Dir     string   `json:"dir"`
+	Package string   `json:"package"`
+	Symbols []Symbol `json:"symbols"`
+}
+
+// PublicAPI returns the exported symbols under a path, grouped by package.
+// Methods are only included when their receiver type is exported too, and
+// test files are skipped. Symbols carry signatures, qualified names and
+// the text of their doc comments.
+func PublicAPI(opts PublicAPIOptions) ([]PackageAPI, error) {
+	if opts.Language == "" {
+		opts.Language = "go"
+	}
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.Jobs == 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+
+	language := Get(opts.Language)
+	if language == nil {
+		return nil, errors.New(opts.Language + " language not registered")
+	}
+
+	query, err := newQuery(commentsQuery(language), language)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := newScanner(scannerConfig{
+		root:     opts.Path,
+		language: language,
+		maxBytes: opts.MaxBytes,
+	})
+	files, err := sc.collect()
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []FileJob
+	for _, f := range files {
+		if !strings.HasSuffix(f.DisplayPath, "_test.go") {
+			sources = append(sources, f)
+		}
+	}
+	if len(sources) == 0 {
+		return []PackageAPI{}, nil
+	}
+
+	fileAPIs := runWorkers(language, query, sources, workerConfig{jobs: opts.Jobs}, func(job FileJob, matches []QueryMatch, source []byte) []fileAPI {
+		api := fileAPI{file: job.DisplayPath}
+		for _, match := range matches {
+			if pkg, ok := findCapture(match, "package"); ok {
+				api.pkg = pkg.Text
+			}
+		}
+
+		for _, sym := range extractSymbols(matches, source, SymbolsOptions{Language: opts.Language, Visibility: "public", WithDoc: true}) {
+			if sym.Receiver != "" && getVisibility(sym.Receiver) != "public" {
+				continue
+			}
+			sym.QualifiedName = qualifiedName(api.pkg, sym)
+			api.symbols = append(api.symbols, sym)
+		}
+		return []fileAPI{api}
+	})
+
+	return groupPackageAPIs(fileAPIs), nil
+}
+
+// fileAPI holds the exported symbols of a single file.
+type fileAPI struct {
+	file    string
+	pkg     string
+	symbols []Symbol
+}
+
+// groupPackageAPIs merges per-file results into one entry per package,
+// ordered by directory and then by file within each package. Packages that
+// export nothing are dropped.
+func groupPackageAPIs(fileAPIs []fileAPI) []PackageAPI {
+	sort.Slice(fileAPIs, func(i, j int) bool {
+		return fileAPIs[i].file < fileAPIs[j].file
+	})
+
+	index := make(map[string]int) // dir + package -> position in packages
+	packages := []PackageAPI{}
+	for _, api := range fileAPIs {
+		if len(api.symbols) == 0 {
+			continue
+		}
+		dir := path.Dir(api.file)
+		key := dir + "\x00" + api.pkg
+		i, ok := index[key]
+		if !ok {
+			i = len(packages)
+			index[key] = i
+			packages = append(packages, PackageAPI{Dir: dir, Package: api.pkg})
+		}
+		packages[i].Symbols = append(packages[i].Symbols, api.symbols...)
+	}
+
+	sort.SliceStable(packages, func(i, j int) bool {
+		return packages[i].Dir < packages[j].Dir
+	})
+	return packages
+}
+
+// qualifiedName returns pkg.Name, or pkg.Receiver.Name for methods.
+func qualifiedName(pkg string, sym Symbol) string {
+	name := sym.Name
+	if sym.Receiver != "" {
+		name = sym.Receiver + "." + name
+	}
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+func findCapture(match QueryMatch, name string) (CaptureResult, bool) {
+	for _, c := range match.Captures {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return CaptureResult{}, false
+}
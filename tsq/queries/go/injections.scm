@@ -0,0 +1,44 @@
+; String literals holding code in another language, marked by a comment
+; naming the language directly before them:
+;
+;   // language=sql
+;   const schema = `CREATE TABLE ...`
+;
+;   db.Query(/* sql */ `SELECT ...`)
+;   q := /* sql */ `SELECT ...`
+
+(_
+  (comment) @injection.language
+  .
+  [(raw_string_literal) (interpreted_string_literal)] @injection.content)
+
+(_
+  (comment) @injection.language
+  .
+  (expression_list
+    .
+    [(raw_string_literal) (interpreted_string_literal)] @injection.content))
+
+(_
+  (comment) @injection.language
+  .
+  [
+    (const_spec
+      value: (expression_list
+        .
+        [(raw_string_literal) (interpreted_string_literal)] @injection.content))
+    (var_spec
+      value: (expression_list
+        .
+        [(raw_string_literal) (interpreted_string_literal)] @injection.content))
+    (const_declaration
+      (const_spec
+        value: (expression_list
+          .
+          [(raw_string_literal) (interpreted_string_literal)] @injection.content)))
+    (var_declaration
+      (var_spec
+        value: (expression_list
+          .
+          [(raw_string_literal) (interpreted_string_literal)] @injection.content)))
+  ])
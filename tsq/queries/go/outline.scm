@@ -5,13 +5,13 @@
 ; Import declarations
 (import_declaration
   (import_spec
-    name: (package_identifier)? @alias
+    name: [(package_identifier) (blank_identifier) (dot)]? @alias
     path: (interpreted_string_literal) @path)) @import
 
 (import_declaration
   (import_spec_list
     (import_spec
-      name: (package_identifier)? @alias
+      name: [(package_identifier) (blank_identifier) (dot)]? @alias
       path: (interpreted_string_literal) @path))) @import_list
 
 ; Function declarations
@@ -70,3 +70,6 @@
 ; Var declarations
 (var_spec
   name: (identifier) @var_name) @var
+
+; Comments (used for //go:embed directives)
+(comment) @comment
@@ -1,30 +1,62 @@
+; Package clause (used to group symbols by package)
+(package_clause
+  (package_identifier) @package)
+
 ; Function declarations
 (function_declaration
   name: (identifier) @name
+  type_parameters: (type_parameter_list)? @type_params
   parameters: (parameter_list) @params
-  result: (_)? @result) @function
+  result: (_)? @result
+  body: (block)? @body) @function
 
 ; Method declarations
 (method_declaration
   receiver: (parameter_list) @receiver
   name: (field_identifier) @name
   parameters: (parameter_list) @params
-  result: (_)? @result) @method
+  result: (_)? @result
+  body: (block)? @body) @method
 
 ; Type declarations (struct, interface, type alias)
 (type_declaration
   (type_spec
     name: (type_identifier) @name
+    type_parameters: (type_parameter_list)? @type_params
     type: (_) @type_def)) @type
 
-; Const declarations
-(const_spec
-  name: (identifier) @name
-  type: (_)? @type
-  value: (_)? @value) @const
+; Const declarations (@decl_block is the enclosing const declaration)
+(const_declaration
+  (const_spec
+    name: (identifier) @name
+    type: (_)? @type
+    value: (_)? @value) @const) @decl_block
 
-; Var declarations
-(var_spec
-  name: (identifier) @name
-  type: (_)? @type
-  value: (_)? @value) @var
+; Var declarations (@decl_block is the enclosing var declaration)
+(var_declaration
+  (var_spec
+    name: (identifier) @name
+    type: (_)? @type
+    value: (_)? @value) @var) @decl_block
+
+(var_declaration
+  (var_spec_list
+    (var_spec
+      name: (identifier) @name
+      type: (_)? @type
+      value: (_)? @value) @var)) @decl_block
+
+; Function literals (closures), reported only with IncludeAnonymous
+(func_literal
+  parameters: (parameter_list) @params
+  result: (_)? @result
+  body: (block) @body) @closure
+
+; Embedded struct fields, used to promote methods with PromoteEmbedded
+(type_spec
+  name: (type_identifier) @struct_name
+  type: (struct_type
+    (field_declaration_list
+      (field_declaration
+        !name
+        type: (_) @embedded))))
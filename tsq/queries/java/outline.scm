@@ -0,0 +1,51 @@
+; Package declaration
+(package_declaration
+  [(identifier) (scoped_identifier)] @package)
+
+; Imports, static and on-demand ones by the imported name
+(import_declaration
+  [(identifier) (scoped_identifier)] @path) @import
+
+; Classes, including nested ones
+(class_declaration
+  (modifiers)? @modifiers
+  name: (identifier) @type_name) @class
+
+; Interfaces
+(interface_declaration
+  (modifiers)? @modifiers
+  name: (identifier) @type_name) @interface
+
+; Enums
+(enum_declaration
+  (modifiers)? @modifiers
+  name: (identifier) @type_name) @enum
+
+; Methods and constructors of classes, enums and interfaces
+([
+  (class_declaration
+    name: (identifier) @receiver_type
+    body: (class_body
+      [(method_declaration
+         (modifiers)? @modifiers
+         name: (identifier) @method_name)
+       (constructor_declaration
+         (modifiers)? @modifiers
+         name: (identifier) @method_name)] @method))
+  (enum_declaration
+    name: (identifier) @receiver_type
+    body: (enum_body
+      (enum_body_declarations
+        [(method_declaration
+           (modifiers)? @modifiers
+           name: (identifier) @method_name)
+         (constructor_declaration
+           (modifiers)? @modifiers
+           name: (identifier) @method_name)] @method)))
+  (interface_declaration
+    name: (identifier) @receiver_type
+    body: (interface_body
+      (method_declaration
+        (modifiers)? @modifiers
+        name: (identifier) @method_name) @method) @interface_body)
+])
@@ -0,0 +1,14 @@
+; Method calls
+(method_invocation
+  name: (identifier) @call)
+
+; Type references, constructor calls included
+(type_identifier) @type_ref
+
+; Identifiers (variable references)
+(identifier) @ident
+
+; Field access
+(field_access
+  object: (_) @operand
+  field: (identifier) @field)
@@ -0,0 +1,101 @@
+; Classes, including nested ones
+(class_declaration
+  (modifiers)? @modifiers
+  name: (identifier) @name
+  type_parameters: (type_parameters)? @type_params) @class
+
+; Interfaces (@type_def is the interface_body, making the kind "interface")
+(interface_declaration
+  (modifiers)? @modifiers
+  name: (identifier) @name
+  type_parameters: (type_parameters)? @type_params
+  body: (interface_body) @type_def) @type
+
+; Enums
+(enum_declaration
+  (modifiers)? @modifiers
+  name: (identifier) @name) @enum
+
+; Methods and constructors (@class_name is the enclosing class, enum or
+; interface; members of interfaces also capture @interface_body)
+([
+  (class_declaration
+    name: (identifier) @class_name
+    body: (class_body
+      (method_declaration
+        (modifiers)? @modifiers
+        type_parameters: (type_parameters)? @type_params
+        type: (_) @result
+        name: (identifier) @name
+        parameters: (formal_parameters) @params
+        body: (block)? @body) @method))
+  (enum_declaration
+    name: (identifier) @class_name
+    body: (enum_body
+      (enum_body_declarations
+        (method_declaration
+          (modifiers)? @modifiers
+          type_parameters: (type_parameters)? @type_params
+          type: (_) @result
+          name: (identifier) @name
+          parameters: (formal_parameters) @params
+          body: (block)? @body) @method)))
+  (interface_declaration
+    name: (identifier) @class_name
+    body: (interface_body
+      (method_declaration
+        (modifiers)? @modifiers
+        type_parameters: (type_parameters)? @type_params
+        type: (_) @result
+        name: (identifier) @name
+        parameters: (formal_parameters) @params
+        body: (block)? @body) @method) @interface_body)
+])
+
+([
+  (class_declaration
+    name: (identifier) @class_name
+    body: (class_body
+      (constructor_declaration
+        (modifiers)? @modifiers
+        type_parameters: (type_parameters)? @type_params
+        name: (identifier) @name
+        parameters: (formal_parameters) @params
+        body: (constructor_body) @body) @method))
+  (enum_declaration
+    name: (identifier) @class_name
+    body: (enum_body
+      (enum_body_declarations
+        (constructor_declaration
+          (modifiers)? @modifiers
+          type_parameters: (type_parameters)? @type_params
+          name: (identifier) @name
+          parameters: (formal_parameters) @params
+          body: (constructor_body) @body) @method)))
+])
+
+; Fields, one symbol per declarator; interface constants are fields too
+([
+  (class_declaration
+    name: (identifier) @class_name
+    body: (class_body
+      (field_declaration
+        (modifiers)? @modifiers
+        declarator: (variable_declarator
+          name: (identifier) @name)) @field))
+  (enum_declaration
+    name: (identifier) @class_name
+    body: (enum_body
+      (enum_body_declarations
+        (field_declaration
+          (modifiers)? @modifiers
+          declarator: (variable_declarator
+            name: (identifier) @name)) @field)))
+  (interface_declaration
+    name: (identifier) @class_name
+    body: (interface_body
+      (constant_declaration
+        (modifiers)? @modifiers
+        declarator: (variable_declarator
+          name: (identifier) @name)) @field) @interface_body)
+])
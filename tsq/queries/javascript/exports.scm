@@ -0,0 +1,52 @@
+; Exported names, appended to the symbols and outline queries to tell
+; public symbols from private ones (see JavaScript.Visibility)
+
+; export function f() {}, export class C {}, export default function f() {}
+(export_statement
+  declaration: [
+    (function_declaration name: (identifier) @export.name)
+    (generator_function_declaration name: (identifier) @export.name)
+    (class_declaration name: (identifier) @export.name)
+    (lexical_declaration (variable_declarator name: (identifier) @export.name))
+    (variable_declaration (variable_declarator name: (identifier) @export.name))
+  ])
+
+; export { name, name as alias }
+(export_statement
+  (export_clause
+    (export_specifier
+      name: (identifier) @export.name)))
+
+; export default name
+(export_statement
+  value: (identifier) @export.name)
+
+; module.exports = name
+(assignment_expression
+  left: (member_expression
+    object: (identifier) @export.module
+    property: (property_identifier) @export.exports)
+  right: (identifier) @export.name)
+
+; module.exports = { name, alias: name }
+(assignment_expression
+  left: (member_expression
+    object: (identifier) @export.module
+    property: (property_identifier) @export.exports)
+  right: (object
+    [(shorthand_property_identifier) @export.name
+     (pair value: (identifier) @export.name)]))
+
+; exports.alias = name
+(assignment_expression
+  left: (member_expression
+    object: (identifier) @export.exports)
+  right: (identifier) @export.name)
+
+; module.exports.alias = name
+(assignment_expression
+  left: (member_expression
+    object: (member_expression
+      object: (identifier) @export.module
+      property: (property_identifier) @export.exports))
+  right: (identifier) @export.name)
@@ -0,0 +1,32 @@
+; Imports
+(import_statement
+  source: (string (string_fragment) @path)) @import
+
+; Function declarations
+(function_declaration
+  name: (identifier) @func_name) @function
+
+(generator_function_declaration
+  name: (identifier) @func_name) @function
+
+; Functions and arrow functions bound to variables
+(lexical_declaration
+  (variable_declarator
+    name: (identifier) @func_name
+    value: [(arrow_function) (function_expression)])) @function
+
+(variable_declaration
+  (variable_declarator
+    name: (identifier) @func_name
+    value: [(arrow_function) (function_expression)])) @function
+
+; Classes
+(class_declaration
+  name: (identifier) @type_name) @class
+
+; Class methods
+(class_declaration
+  name: (identifier) @receiver_type
+  body: (class_body
+    (method_definition
+      name: (_) @method_name) @method))
@@ -0,0 +1,20 @@
+; Function and method calls
+(call_expression
+  function: (identifier) @call)
+
+(call_expression
+  function: (member_expression
+    object: (_) @receiver
+    property: (property_identifier) @call))
+
+; Constructor calls
+(new_expression
+  constructor: (identifier) @type_ref)
+
+; Identifiers (variable references)
+(identifier) @ident
+
+; Property access
+(member_expression
+  object: (_) @operand
+  property: (property_identifier) @field)
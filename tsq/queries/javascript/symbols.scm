@@ -0,0 +1,55 @@
+; Function declarations
+(function_declaration
+  name: (identifier) @name
+  parameters: (formal_parameters) @params
+  body: (statement_block) @body) @function
+
+(generator_function_declaration
+  name: (identifier) @name
+  parameters: (formal_parameters) @params
+  body: (statement_block) @body) @function
+
+; Functions and arrow functions bound to const or let
+(lexical_declaration
+  (variable_declarator
+    name: (identifier) @name
+    value: [
+      (arrow_function
+        parameters: (formal_parameters) @params
+        body: (_) @body)
+      (arrow_function
+        parameter: (identifier) @params
+        body: (_) @body)
+      (function_expression
+        parameters: (formal_parameters) @params
+        body: (statement_block) @body)
+    ])) @function
+
+; ... or to var
+(variable_declaration
+  (variable_declarator
+    name: (identifier) @name
+    value: [
+      (arrow_function
+        parameters: (formal_parameters) @params
+        body: (_) @body)
+      (arrow_function
+        parameter: (identifier) @params
+        body: (_) @body)
+      (function_expression
+        parameters: (formal_parameters) @params
+        body: (statement_block) @body)
+    ])) @function
+
+; Classes
+(class_declaration
+  name: (identifier) @name) @class
+
+; Class methods (@class_name is the enclosing class)
+(class_declaration
+  name: (identifier) @class_name
+  body: (class_body
+    (method_definition
+      name: (_) @name
+      parameters: (formal_parameters) @params
+      body: (statement_block) @body) @method))
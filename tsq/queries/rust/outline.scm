@@ -0,0 +1,60 @@
+; Use declarations
+(use_declaration
+  argument: (_) @path) @import
+
+; Functions, at the top level or in a module
+(source_file
+  (function_item
+    (visibility_modifier)? @visibility
+    name: (identifier) @func_name) @function)
+
+(mod_item
+  body: (declaration_list
+    (function_item
+      (visibility_modifier)? @visibility
+      name: (identifier) @func_name) @function))
+
+; Methods and associated functions (@params tells them apart)
+(impl_item
+  !trait
+  type: (_) @receiver_type
+  body: (declaration_list
+    (function_item
+      (visibility_modifier)? @visibility
+      name: (identifier) @method_name
+      parameters: (parameters) @params) @method))
+
+(impl_item
+  trait: (_) @impl_trait
+  type: (_) @receiver_type
+  body: (declaration_list
+    (function_item
+      name: (identifier) @method_name
+      parameters: (parameters) @params) @method))
+
+; Structs, enums and traits
+(struct_item
+  (visibility_modifier)? @visibility
+  name: (type_identifier) @type_name) @struct
+
+(enum_item
+  (visibility_modifier)? @visibility
+  name: (type_identifier) @type_name) @enum
+
+(trait_item
+  (visibility_modifier)? @visibility
+  name: (type_identifier) @type_name) @trait
+
+; Type aliases
+(type_item
+  (visibility_modifier)? @visibility
+  name: (type_identifier) @type_name) @type_alias
+
+; Constants and statics
+(const_item
+  (visibility_modifier)? @visibility
+  name: (identifier) @const_name) @const
+
+(static_item
+  (visibility_modifier)? @visibility
+  name: (identifier) @var_name) @var
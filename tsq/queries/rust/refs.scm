@@ -0,0 +1,23 @@
+; Function and method calls
+(call_expression
+  function: (identifier) @call)
+
+(call_expression
+  function: (field_expression
+    value: (_) @receiver
+    field: (field_identifier) @call))
+
+(call_expression
+  function: (scoped_identifier
+    name: (identifier) @call))
+
+; Type references
+(type_identifier) @type_ref
+
+; Identifiers (variable references)
+(identifier) @ident
+
+; Field access
+(field_expression
+  value: (_) @operand
+  field: (field_identifier) @field)
@@ -0,0 +1,101 @@
+; A match is public if its @visibility is exactly "pub" (see Rust.Visibility)
+
+; Functions, at the top level or in a module
+(source_file
+  (function_item
+    (visibility_modifier)? @visibility
+    name: (identifier) @name
+    type_parameters: (type_parameters)? @type_params
+    parameters: (parameters) @params
+    return_type: (_)? @result
+    body: (block) @body) @function)
+
+(mod_item
+  body: (declaration_list
+    (function_item
+      (visibility_modifier)? @visibility
+      name: (identifier) @name
+      type_parameters: (type_parameters)? @type_params
+      parameters: (parameters) @params
+      return_type: (_)? @result
+      body: (block) @body) @function))
+
+; Methods and associated functions of inherent impl blocks
+(impl_item
+  !trait
+  type: (_) @impl_type
+  body: (declaration_list
+    (function_item
+      (visibility_modifier)? @visibility
+      name: (identifier) @name
+      type_parameters: (type_parameters)? @type_params
+      parameters: (parameters) @params
+      return_type: (_)? @result
+      body: (block) @body) @method))
+
+; ... and of trait impls, as visible as the trait (@impl_trait)
+(impl_item
+  trait: (_) @impl_trait
+  type: (_) @impl_type
+  body: (declaration_list
+    (function_item
+      name: (identifier) @name
+      type_parameters: (type_parameters)? @type_params
+      parameters: (parameters) @params
+      return_type: (_)? @result
+      body: (block) @body) @method))
+
+; Trait methods, with or without a default body (@visibility is the trait's)
+(trait_item
+  (visibility_modifier)? @visibility
+  name: (type_identifier) @impl_type
+  body: (declaration_list
+    [
+      (function_item
+        name: (identifier) @name
+        type_parameters: (type_parameters)? @type_params
+        parameters: (parameters) @params
+        return_type: (_)? @result
+        body: (block) @body)
+      (function_signature_item
+        name: (identifier) @name
+        type_parameters: (type_parameters)? @type_params
+        parameters: (parameters) @params
+        return_type: (_)? @result)
+    ] @method))
+
+; Structs, enums and traits (@type_def's node type sets the kind)
+(struct_item
+  (visibility_modifier)? @visibility
+  name: (type_identifier) @name
+  type_parameters: (type_parameters)? @type_params) @type @type_def
+
+(enum_item
+  (visibility_modifier)? @visibility
+  name: (type_identifier) @name
+  type_parameters: (type_parameters)? @type_params) @type @type_def
+
+(trait_item
+  (visibility_modifier)? @visibility
+  name: (type_identifier) @name
+  type_parameters: (type_parameters)? @type_params) @type @type_def
+
+; Type aliases
+(type_item
+  (visibility_modifier)? @visibility
+  name: (type_identifier) @name
+  type_parameters: (type_parameters)? @type_params
+  type: (_) @type_def) @type
+
+; Constants and statics
+(const_item
+  (visibility_modifier)? @visibility
+  name: (identifier) @name
+  type: (_) @type
+  value: (_)? @value) @const
+
+(static_item
+  (visibility_modifier)? @visibility
+  name: (identifier) @name
+  type: (_) @type
+  value: (_)? @value) @var
@@ -0,0 +1,9 @@
+; Tables
+(create_table
+  (object_reference
+    name: (identifier) @name)) @table
+
+; Views
+(create_view
+  (object_reference
+    name: (identifier) @name)) @view
@@ -0,0 +1,7 @@
+; Table and view references
+(object_reference
+  name: (identifier) @ident)
+
+; Column references
+(field
+  name: (identifier) @ident)
@@ -0,0 +1,13 @@
+; Tables
+(create_table
+  (object_reference
+    name: (identifier) @name)) @table
+
+; Views
+(create_view
+  (object_reference
+    name: (identifier) @name)) @view
+
+; Indexes (the grammar labels the index name "column")
+(create_index
+  column: (identifier) @name) @index
@@ -0,0 +1,27 @@
+; Exported names, appended to the symbols and outline queries to tell
+; public symbols from private ones (see TypeScript.Visibility)
+
+; export function f() {}, export interface I {}, export class C {}, ...
+(export_statement
+  declaration: [
+    (function_declaration name: (identifier) @export.name)
+    (generator_function_declaration name: (identifier) @export.name)
+    (function_signature name: (identifier) @export.name)
+    (class_declaration name: (type_identifier) @export.name)
+    (abstract_class_declaration name: (type_identifier) @export.name)
+    (interface_declaration name: (type_identifier) @export.name)
+    (type_alias_declaration name: (type_identifier) @export.name)
+    (enum_declaration name: (identifier) @export.name)
+    (lexical_declaration (variable_declarator name: (identifier) @export.name))
+    (variable_declaration (variable_declarator name: (identifier) @export.name))
+  ])
+
+; export { name, name as alias }
+(export_statement
+  (export_clause
+    (export_specifier
+      name: (identifier) @export.name)))
+
+; export default name
+(export_statement
+  value: (identifier) @export.name)
@@ -0,0 +1,56 @@
+; Imports
+(import_statement
+  source: (string (string_fragment) @path)) @import
+
+; Function declarations
+(function_declaration
+  name: (identifier) @func_name) @function
+
+(generator_function_declaration
+  name: (identifier) @func_name) @function
+
+(function_signature
+  name: (identifier) @func_name) @function
+
+; Functions and arrow functions bound to variables
+(lexical_declaration
+  (variable_declarator
+    name: (identifier) @func_name
+    value: [(arrow_function) (function_expression)])) @function
+
+(variable_declaration
+  (variable_declarator
+    name: (identifier) @func_name
+    value: [(arrow_function) (function_expression)])) @function
+
+; Interfaces
+(interface_declaration
+  name: (type_identifier) @type_name) @interface
+
+; Type aliases
+(type_alias_declaration
+  name: (type_identifier) @type_name) @type_alias
+
+; Enums
+(enum_declaration
+  name: (identifier) @type_name) @enum
+
+; Classes
+([
+  (class_declaration name: (type_identifier) @type_name)
+  (abstract_class_declaration name: (type_identifier) @type_name)
+]) @class
+
+; Class methods
+([
+  (class_declaration
+    name: (type_identifier) @receiver_type
+    body: (class_body
+      [(method_definition name: (_) @method_name)
+       (abstract_method_signature name: (_) @method_name)] @method))
+  (abstract_class_declaration
+    name: (type_identifier) @receiver_type
+    body: (class_body
+      [(method_definition name: (_) @method_name)
+       (abstract_method_signature name: (_) @method_name)] @method))
+])
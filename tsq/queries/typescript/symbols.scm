@@ -0,0 +1,122 @@
+; Function declarations, with parameter and return type annotations
+(function_declaration
+  name: (identifier) @name
+  type_parameters: (type_parameters)? @type_params
+  parameters: (formal_parameters) @params
+  return_type: (type_annotation)? @result
+  body: (statement_block) @body) @function
+
+(generator_function_declaration
+  name: (identifier) @name
+  type_parameters: (type_parameters)? @type_params
+  parameters: (formal_parameters) @params
+  return_type: (type_annotation)? @result
+  body: (statement_block) @body) @function
+
+; Overloads and declare function
+(function_signature
+  name: (identifier) @name
+  type_parameters: (type_parameters)? @type_params
+  parameters: (formal_parameters) @params
+  return_type: (type_annotation)? @result) @function
+
+; Functions and arrow functions bound to variables
+(lexical_declaration
+  (variable_declarator
+    name: (identifier) @name
+    value: [
+      (arrow_function
+        type_parameters: (type_parameters)? @type_params
+        parameters: (formal_parameters) @params
+        return_type: (type_annotation)? @result
+        body: (_) @body)
+      (arrow_function
+        parameter: (identifier) @params
+        body: (_) @body)
+      (function_expression
+        type_parameters: (type_parameters)? @type_params
+        parameters: (formal_parameters) @params
+        return_type: (type_annotation)? @result
+        body: (statement_block) @body)
+    ])) @function
+
+(variable_declaration
+  (variable_declarator
+    name: (identifier) @name
+    value: [
+      (arrow_function
+        type_parameters: (type_parameters)? @type_params
+        parameters: (formal_parameters) @params
+        return_type: (type_annotation)? @result
+        body: (_) @body)
+      (arrow_function
+        parameter: (identifier) @params
+        body: (_) @body)
+      (function_expression
+        type_parameters: (type_parameters)? @type_params
+        parameters: (formal_parameters) @params
+        return_type: (type_annotation)? @result
+        body: (statement_block) @body)
+    ])) @function
+
+; Interfaces (@type_def is the interface_body, making the kind "interface")
+(interface_declaration
+  name: (type_identifier) @name
+  type_parameters: (type_parameters)? @type_params
+  body: (interface_body) @type_def) @type
+
+; Type aliases
+(type_alias_declaration
+  name: (type_identifier) @name
+  type_parameters: (type_parameters)? @type_params
+  value: (_) @type_def) @type
+
+; Enums
+(enum_declaration
+  name: (identifier) @name) @enum
+
+; Classes
+([
+  (class_declaration
+    name: (type_identifier) @name
+    type_parameters: (type_parameters)? @type_params)
+  (abstract_class_declaration
+    name: (type_identifier) @name
+    type_parameters: (type_parameters)? @type_params)
+]) @class
+
+; Class methods (@class_name is the enclosing class)
+([
+  (class_declaration
+    name: (type_identifier) @class_name
+    body: (class_body
+      [
+        (method_definition
+          name: (_) @name
+          type_parameters: (type_parameters)? @type_params
+          parameters: (formal_parameters) @params
+          return_type: (type_annotation)? @result
+          body: (statement_block) @body)
+        (abstract_method_signature
+          name: (_) @name
+          type_parameters: (type_parameters)? @type_params
+          parameters: (formal_parameters) @params
+          return_type: (type_annotation)? @result)
+      ] @method))
+  (abstract_class_declaration
+    name: (type_identifier) @class_name
+    body: (class_body
+      [
+        (method_definition
+          name: (_) @name
+          type_parameters: (type_parameters)? @type_params
+          parameters: (formal_parameters) @params
+          return_type: (type_annotation)? @result
+          body: (statement_block) @body)
+        (abstract_method_signature
+          name: (_) @name
+          type_parameters: (type_parameters)? @type_params
+          parameters: (formal_parameters) @params
+          return_type: (type_annotation)? @result)
+      ] @method))
+])
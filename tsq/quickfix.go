@@ -0,0 +1,63 @@
+package tsq
+
+// QuickfixEntry is an item in the shape accepted by vim's setqflist() and
+// Neovim's vim.fn.setqflist(). Lines and columns are 1-based.
+type QuickfixEntry struct {
+	Filename string `json:"filename"`
+	Lnum     int    `json:"lnum"`
+	Col      int    `json:"col"`
+	Text     string `json:"text"`
+	Type     string `json:"type"` // E, W or I
+}
+
+// quickfixTypes maps reference kinds to quickfix types, so the list can tell
+// calls (E) and type references (W) from other uses (I) at a glance.
+var quickfixTypes = map[string]string{
+	"call":     "E",
+	"type_ref": "W",
+}
+
+// QuickfixMatches converts query matches to quickfix entries, one per
+// capture, typed I. The text is the capture name and the first line of its
+// text, e.g. "@name: Parse".
+func QuickfixMatches(matches []QueryMatch) []QuickfixEntry {
+	entries := []QuickfixEntry{}
+	for _, m := range matches {
+		for _, c := range m.Captures {
+			entries = append(entries, QuickfixEntry{
+				Filename: m.File,
+				Lnum:     c.Range.Start.Line,
+				Col:      c.Range.Start.Column,
+				Text:     "@" + c.Name + ": " + firstLine(c.Text),
+				Type:     "I",
+			})
+		}
+	}
+	return entries
+}
+
+// QuickfixRefs converts references to quickfix entries. The text is the
+// reference kind and its context line if requested, or the symbol, e.g.
+// "call: helper()"; the type is E for calls, W for type references and I
+// otherwise.
+func QuickfixRefs(refs []Reference) []QuickfixEntry {
+	entries := []QuickfixEntry{}
+	for _, ref := range refs {
+		text := ref.Symbol
+		if ref.Context != "" {
+			text = ref.Context
+		}
+		typ, ok := quickfixTypes[ref.Kind]
+		if !ok {
+			typ = "I"
+		}
+		entries = append(entries, QuickfixEntry{
+			Filename: ref.File,
+			Lnum:     ref.Position.Line,
+			Col:      ref.Position.Column,
+			Text:     ref.Kind + ": " + firstLine(text),
+			Type:     typ,
+		})
+	}
+	return entries
+}
@@ -0,0 +1,51 @@
+package tsq
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteRgMatches writes each capture of matches as a ripgrep-style line,
+//
+//	path:line:column:text
+//
+// so that tools which already parse `rg --vimgrep` output can consume query
+// results. Lines and columns are 1-based. Only the first line of a multi-line
+// capture is written.
+func WriteRgMatches(w io.Writer, matches []QueryMatch) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range matches {
+		for _, c := range m.Captures {
+			writeRgLine(bw, m.File, c.Range.Start, c.Text)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteRgRefs writes references as ripgrep-style lines. The text is the
+// reference's context line if it was requested, and the symbol otherwise.
+func WriteRgRefs(w io.Writer, refs []Reference) error {
+	bw := bufio.NewWriter(w)
+	for _, ref := range refs {
+		text := ref.Symbol
+		if ref.Context != "" {
+			text = ref.Context
+		}
+		writeRgLine(bw, ref.File, ref.Position, text)
+	}
+	return bw.Flush()
+}
+
+func writeRgLine(w *bufio.Writer, file string, pos Position, text string) {
+	fmt.Fprintf(w, "%s:%d:%d:%s\n", file, pos.Line, pos.Column, firstLine(text))
+}
+
+// firstLine returns text up to its first line break.
+func firstLine(text string) string {
+	if i := strings.IndexAny(text, "\r\n"); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
@@ -0,0 +1,92 @@
+package tsq
+
+import (
+	_ "embed"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/rust"
+)
+
+//go:embed queries/rust/symbols.scm
+var rustSymbolsQuery string
+
+//go:embed queries/rust/outline.scm
+var rustOutlineQuery string
+
+//go:embed queries/rust/refs.scm
+var rustRefsQuery string
+
+// Rust implements the Language interface for Rust. Structs, enums and
+// traits keep distinct kinds; functions of impl and trait blocks are
+// methods, or functions for associated functions without self, with the
+// implementing type as Receiver ("Type for Trait" in trait impls).
+type Rust struct{}
+
+func init() {
+	Register(&Rust{})
+}
+
+func (r *Rust) Name() string {
+	return "rust"
+}
+
+func (r *Rust) Extensions() []string {
+	return []string{".rs"}
+}
+
+func (r *Rust) TreeSitterLang() *sitter.Language {
+	return rust.GetLanguage()
+}
+
+func (r *Rust) SymbolsQuery() string {
+	return rustSymbolsQuery
+}
+
+func (r *Rust) OutlineQuery() string {
+	return rustOutlineQuery
+}
+
+func (r *Rust) RefsQuery() string {
+	return rustRefsQuery
+}
+
+// Visibility makes a symbol public if it is declared pub, or belongs to a
+// pub trait or to a trait impl, which are as visible as their trait.
+// Restricted visibility such as pub(crate) is private. Symbols are matched
+// to declarations by position: a symbol's range starts at its name or its
+// declaration, both of which are captured.
+func (r *Rust) Visibility(matches []QueryMatch) func(Symbol) string {
+	public := make(map[Position]bool)
+	for _, match := range matches {
+		vis, ok := findCapture(match, "visibility")
+		if !ok || vis.Text != "pub" {
+			if _, ok := findCapture(match, "impl_trait"); !ok {
+				continue
+			}
+		}
+		for _, c := range match.Captures {
+			public[c.Range.Start] = true
+		}
+	}
+	return func(sym Symbol) string {
+		if public[sym.Range.Start] {
+			return "public"
+		}
+		return "private"
+	}
+}
+
+// isAssociatedFunction reports whether the parameters of a Rust function in
+// an impl or trait block lack a self parameter, making it an associated
+// function rather than a method.
+func isAssociatedFunction(params CaptureResult) bool {
+	if params.NodeType != "parameters" {
+		return false
+	}
+	// self, mut self, &'a mut self, self: Box<Self>, ...
+	first, _, _ := strings.Cut(trimParens(params.Text), ",")
+	first, _, _ = strings.Cut(first, ":")
+	fields := strings.Fields(first)
+	return len(fields) == 0 || strings.TrimPrefix(fields[len(fields)-1], "&") != "self"
+}
@@ -1,10 +1,20 @@
 package tsq
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"math/rand"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
 )
 
 // defaultIgnoreDirs returns the default list of directories to ignore.
@@ -30,17 +40,62 @@ func defaultIgnoreDirs() map[string]struct{} {
 	}
 }
 
+// ignoreDirSet returns the default directories to ignore plus extra, or nil,
+// which newScanner takes for the defaults, if there are no extra ones.
+func ignoreDirSet(extra []string) map[string]struct{} {
+	if len(extra) == 0 {
+		return nil
+	}
+	dirs := defaultIgnoreDirs()
+	for _, name := range extra {
+		dirs[name] = struct{}{}
+	}
+	return dirs
+}
+
 // scannerConfig holds scanner configuration.
 type scannerConfig struct {
-	root       string
-	language   Language
-	ignoreDirs map[string]struct{}
-	maxBytes   int64
+	root         string
+	language     Language // nil detects each file's language by extension
+	ignoreDirs   map[string]struct{}
+	maxBytes     int64 // 0 uses the language default, negative disables the limit
+	skipMinified bool
+	pathPattern  string // doublestar glob matched against the path relative to root
+	relativeTo   string // base of DisplayPath: "" or "root" for root, "git" for the git worktree root
+	baseDir      string // if set, base of DisplayPath instead of root
+	sample       int    // if positive, keep a seeded random sample of this many files
+	seed         int64  // seed for sample
+	maxFiles     int    // if positive, stop the walk once this many files are collected
+	hidden       bool   // scan dot-prefixed files and directories not in ignoreDirs
+	ignoreSingle bool   // apply ignoreDirs and hidden to the file given to collectFile too
+	gitignore    bool   // skip what the .gitignore files of root, its subdirectories and, on the OS, its git worktree exclude
+	fsys         fs.FS  // if set, root and files are slash-separated paths in fsys instead of the OS filesystem
 }
 
+const (
+	// sniffBytes is how much of a file is sampled by looksMinifiedOrBinary.
+	sniffBytes = 1024
+
+	// maxSniffLineLength is the longest line allowed in the sample before a
+	// file is treated as minified.
+	maxSniffLineLength = 500
+
+	// minSniffNewlines is the fewest newlines a full sample may hold before
+	// a file is treated as minified: fewer means its lines average over 256
+	// bytes, though none need be longer than maxSniffLineLength.
+	minSniffNewlines = sniffBytes / 256
+)
+
+// errMaxFiles stops a walk that has collected maxFiles files.
+var errMaxFiles = errors.New("max files reached")
+
 // scanner discovers files for processing.
 type scanner struct {
 	cfg scannerConfig
+
+	// truncated is set by collect or collectChanged when it stopped at
+	// maxFiles with files left unvisited.
+	truncated bool
 }
 
 // newScanner creates a new scanner with the given configuration.
@@ -48,15 +103,36 @@ func newScanner(cfg scannerConfig) *scanner {
 	if cfg.ignoreDirs == nil {
 		cfg.ignoreDirs = defaultIgnoreDirs()
 	}
+	if cfg.maxBytes == 0 && cfg.language != nil {
+		cfg.maxBytes = LanguageMaxBytes(cfg.language)
+	}
 	return &scanner{cfg: cfg}
 }
 
 // collect finds all matching files and returns them as FileJobs.
 func (s *scanner) collect() ([]FileJob, error) {
+	if err := s.validatePathPattern(); err != nil {
+		return nil, err
+	}
+	if s.cfg.fsys != nil {
+		return s.collectFS()
+	}
+
 	absRoot, err := filepath.Abs(s.cfg.root)
 	if err != nil {
 		return nil, fmt.Errorf("resolve root: %w", err)
 	}
+	displayRoot, err := s.displayRoot(absRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var ignore *gitignore
+	if s.cfg.gitignore {
+		ignore = &gitignore{}
+		ignore.loadParents(absRoot)
+	}
+	read := gitignoreReader(nil, absRoot)
 
 	var jobs []FileJob
 	err = filepath.WalkDir(absRoot, func(path string, d fs.DirEntry, err error) error {
@@ -66,75 +142,565 @@ func (s *scanner) collect() ([]FileJob, error) {
 
 		if d.IsDir() {
 			if path == absRoot {
+				if ignore != nil {
+					ignore.load("", read)
+				}
 				return nil
 			}
 			if s.shouldIgnoreDir(d.Name()) {
 				return filepath.SkipDir
 			}
+			if ignore != nil {
+				rel := relSlash(absRoot, path)
+				if ignore.ignored(rel, true) {
+					return filepath.SkipDir
+				}
+				ignore.load(rel, read)
+			}
 			return nil
 		}
 
-		if !s.isSupportedFile(d.Name()) {
+		language := s.fileLanguage(d.Name())
+		if language == nil {
+			return nil
+		}
+		if ignore != nil && ignore.ignored(relSlash(absRoot, path), false) {
 			return nil
 		}
 
-		if s.cfg.maxBytes > 0 {
-			info, err := d.Info()
-			if err != nil {
-				// Skip files we can't stat
+		info, err := d.Info()
+		if err != nil {
+			// Skip files we can't stat
+			return nil
+		}
+		if maxBytes := s.maxBytes(language); maxBytes > 0 && info.Size() > maxBytes {
+			return nil
+		}
+
+		if s.cfg.skipMinified && looksMinifiedOrBinary(nil, path) {
+			return nil
+		}
+
+		rel := relSlash(absRoot, path)
+		if !s.matchesPathPattern(rel) {
+			return nil
+		}
+
+		if s.cfg.maxFiles > 0 && len(jobs) == s.cfg.maxFiles {
+			s.truncated = true
+			return errMaxFiles
+		}
+		jobs = append(jobs, FileJob{
+			AbsPath:     path,
+			DisplayPath: displayPath(displayRoot, path, rel),
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+			Language:    language,
+		})
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errMaxFiles) {
+		return nil, err
+	}
+
+	return s.sampleFiles(jobs), nil
+}
+
+// collectFS is collect for a scanner reading from an fs.FS. Jobs carry the
+// file's path in the FS as their AbsPath.
+func (s *scanner) collectFS() ([]FileJob, error) {
+	root, err := s.fsPath(s.cfg.root)
+	if err != nil {
+		return nil, err
+	}
+
+	var ignore *gitignore
+	if s.cfg.gitignore {
+		ignore = &gitignore{}
+	}
+	read := gitignoreReader(s.cfg.fsys, root)
+	relPath := func(path string) string {
+		if root == "." {
+			return path
+		}
+		return strings.TrimPrefix(path, root+"/")
+	}
+
+	var jobs []FileJob
+	err = fs.WalkDir(s.cfg.fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path == root {
+				if ignore != nil {
+					ignore.load("", read)
+				}
 				return nil
 			}
-			if info.Size() > s.cfg.maxBytes {
-				return nil
+			if s.shouldIgnoreDir(d.Name()) {
+				return fs.SkipDir
 			}
+			if ignore != nil {
+				rel := relPath(path)
+				if ignore.ignored(rel, true) {
+					return fs.SkipDir
+				}
+				ignore.load(rel, read)
+			}
+			return nil
+		}
+
+		language := s.fileLanguage(d.Name())
+		if language == nil {
+			return nil
+		}
+		if ignore != nil && ignore.ignored(relPath(path), false) {
+			return nil
 		}
 
-		rel, err := filepath.Rel(absRoot, path)
+		info, err := d.Info()
 		if err != nil {
-			rel = path
+			// Skip files we can't stat
+			return nil
+		}
+		if maxBytes := s.maxBytes(language); maxBytes > 0 && info.Size() > maxBytes {
+			return nil
+		}
+
+		if s.cfg.skipMinified && looksMinifiedOrBinary(s.cfg.fsys, path) {
+			return nil
+		}
+
+		rel := relPath(path)
+		if !s.matchesPathPattern(rel) {
+			return nil
 		}
 
+		if s.cfg.maxFiles > 0 && len(jobs) == s.cfg.maxFiles {
+			s.truncated = true
+			return errMaxFiles
+		}
 		jobs = append(jobs, FileJob{
 			AbsPath:     path,
-			DisplayPath: filepath.ToSlash(rel),
+			DisplayPath: rel,
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+			Language:    language,
 		})
 		return nil
 	})
 
+	if err != nil && !errors.Is(err, errMaxFiles) {
+		return nil, err
+	}
+
+	return s.sampleFiles(jobs), nil
+}
+
+// relSlash returns path relative to absRoot with forward slashes, or path
+// itself if it isn't under absRoot.
+func relSlash(absRoot, path string) string {
+	rel, err := filepath.Rel(absRoot, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// reportTruncated adds a "max_files" diagnostic if collect or
+// collectChanged stopped at maxFiles.
+func (s *scanner) reportTruncated(diags *diagnostics) {
+	if !s.truncated {
+		return
+	}
+	diags.add(Diagnostic{
+		File:    s.cfg.root,
+		Kind:    "max_files",
+		Message: fmt.Sprintf("stopped scanning after %d files", s.cfg.maxFiles),
+	})
+}
+
+// fsPath validates name as a path in the scanner's fs.FS, treating "" as the
+// root, and reports options that need the OS filesystem.
+func (s *scanner) fsPath(name string) (string, error) {
+	if s.cfg.relativeTo == "git" {
+		return "", errors.New("path-relative-to git is not supported with an fs.FS")
+	}
+	if s.cfg.baseDir != "" {
+		return "", errors.New("base-dir is not supported with an fs.FS")
+	}
+	if name == "" {
+		return ".", nil
+	}
+	name = path.Clean(filepath.ToSlash(name))
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("invalid fs.FS path %q", name)
+	}
+	return name, nil
+}
+
+// collectChanged returns the supported files under root that changed on HEAD
+// relative to the given base ref, as reported by git. The files are
+// filtered as collect filters those of a walk.
+func (s *scanner) collectChanged(base string) ([]FileJob, error) {
+	if err := s.validatePathPattern(); err != nil {
+		return nil, err
+	}
+	if s.cfg.fsys != nil {
+		return nil, errors.New("changed-since is not supported with an fs.FS")
+	}
+
+	absRoot, err := filepath.Abs(s.cfg.root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve root: %w", err)
+	}
+
+	displayRoot, err := s.displayRoot(absRoot)
 	if err != nil {
 		return nil, err
 	}
 
-	return jobs, nil
+	paths, err := gitChangedFiles(absRoot, base)
+	if err != nil {
+		return nil, err
+	}
+
+	var ignore *gitignore
+	if s.cfg.gitignore {
+		ignore = &gitignore{}
+		ignore.loadParents(absRoot)
+	}
+	read := gitignoreReader(nil, absRoot)
+	if ignore != nil {
+		ignore.load("", read)
+	}
+
+	var jobs []FileJob
+	for _, rel := range paths {
+		language := s.fileLanguage(rel)
+		if language == nil || s.inIgnoredDir(rel) || !s.matchesPathPattern(rel) {
+			continue
+		}
+		if ignore != nil && ignore.ignoredFile(rel, read) {
+			continue
+		}
+
+		path := filepath.Join(absRoot, filepath.FromSlash(rel))
+		info, err := os.Stat(path)
+		if err != nil {
+			// Skip files we can't stat
+			continue
+		}
+		if maxBytes := s.maxBytes(language); maxBytes > 0 && info.Size() > maxBytes {
+			continue
+		}
+		if s.cfg.skipMinified && looksMinifiedOrBinary(nil, path) {
+			continue
+		}
+
+		if s.cfg.maxFiles > 0 && len(jobs) == s.cfg.maxFiles {
+			s.truncated = true
+			break
+		}
+		jobs = append(jobs, FileJob{
+			AbsPath:     path,
+			DisplayPath: displayPath(displayRoot, path, rel),
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+			Language:    language,
+		})
+	}
+
+	return s.sampleFiles(jobs), nil
 }
 
 // collectSingle returns a single file as a FileJob.
 func (s *scanner) collectSingle(filePath string) (FileJob, error) {
+	if s.cfg.fsys != nil {
+		name, err := s.fsPath(filePath)
+		if err != nil {
+			return FileJob{}, err
+		}
+		job := FileJob{AbsPath: name, DisplayPath: path.Base(name), Language: s.singleLanguage(name)}
+		if info, err := fs.Stat(s.cfg.fsys, name); err == nil {
+			job.Size = info.Size()
+			job.ModTime = info.ModTime()
+		}
+		return job, nil
+	}
+
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
 		return FileJob{}, fmt.Errorf("resolve path: %w", err)
 	}
+	displayRoot, err := s.displayRoot(filepath.Dir(absPath))
+	if err != nil {
+		return FileJob{}, err
+	}
 
-	return FileJob{
+	job := FileJob{
 		AbsPath:     absPath,
-		DisplayPath: filepath.Base(absPath),
-	}, nil
+		DisplayPath: displayPath(displayRoot, absPath, filepath.Base(absPath)),
+		Language:    s.singleLanguage(absPath),
+	}
+	if info, err := os.Stat(absPath); err == nil {
+		job.Size = info.Size()
+		job.ModTime = info.ModTime()
+	}
+	return job, nil
+}
+
+// collectFile returns the file given by the File option of a scan as its
+// only job. With ignoreSingle, a file a directory scan would skip, because
+// a directory on its path is ignored or it is hidden, yields no jobs and an
+// "ignored" diagnostic instead.
+func (s *scanner) collectFile(filePath string, diags *diagnostics) ([]FileJob, error) {
+	if s.cfg.ignoreSingle && s.isIgnoredPath(filePath) {
+		diags.add(Diagnostic{
+			File:    filePath,
+			Kind:    "ignored",
+			Message: "skipped by the ignore rules of directory scans",
+		})
+		return nil, nil
+	}
+	job, err := s.collectSingle(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if job.Language == nil {
+		return nil, noLanguageError(filePath)
+	}
+	return []FileJob{job}, nil
+}
+
+// isIgnoredPath reports whether the file itself is hidden or a directory in
+// filePath is ignored. As directory scans only test paths below their root,
+// only directories below the working directory are tested: the ancestors
+// of an absolute path, or of one outside the working directory, don't
+// count.
+func (s *scanner) isIgnoredPath(filePath string) bool {
+	rel := filepath.Clean(filePath)
+	if s.cfg.fsys == nil && filepath.IsAbs(rel) {
+		if wd, err := os.Getwd(); err == nil {
+			if r, err := filepath.Rel(wd, rel); err == nil {
+				rel = r
+			}
+		}
+	}
+	rel = filepath.ToSlash(rel)
+	if !s.cfg.hidden && isHidden(path.Base(rel)) {
+		return true
+	}
+	if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return false
+	}
+	return s.inIgnoredDir(rel)
+}
+
+// modTime formats the job's modification time as RFC3339 in UTC, or returns
+// "" if it is unknown.
+func (j FileJob) modTime() string {
+	if j.ModTime.IsZero() {
+		return ""
+	}
+	return j.ModTime.UTC().Format(time.RFC3339)
+}
+
+// sampleFiles returns a reproducible random sample of the configured number
+// of jobs, chosen by a shuffle seeded with the configured seed. The sample is
+// kept in scan order. All jobs are returned if no sample is configured or
+// there are not more jobs than the sample size.
+func (s *scanner) sampleFiles(jobs []FileJob) []FileJob {
+	if s.cfg.sample <= 0 || len(jobs) <= s.cfg.sample {
+		return jobs
+	}
+	rng := rand.New(rand.NewSource(s.cfg.seed))
+	picked := rng.Perm(len(jobs))[:s.cfg.sample]
+	slices.Sort(picked)
+
+	sample := make([]FileJob, len(picked))
+	for i, idx := range picked {
+		sample[i] = jobs[idx]
+	}
+	return sample
+}
+
+// scanRoot returns the directory a scan of path, or of file if set, starts
+// from.
+func scanRoot(path, file string) string {
+	if file != "" {
+		return filepath.Dir(file)
+	}
+	return path
+}
+
+// displayRoot returns the directory DisplayPaths are made relative to, or ""
+// to keep paths relative to the scan root.
+func (s *scanner) displayRoot(absRoot string) (string, error) {
+	switch s.cfg.relativeTo {
+	case "", "root":
+		if s.cfg.baseDir != "" {
+			base, err := filepath.Abs(s.cfg.baseDir)
+			if err != nil {
+				return "", fmt.Errorf("resolve base dir: %w", err)
+			}
+			return base, nil
+		}
+		return "", nil
+	case "git":
+		if s.cfg.baseDir != "" {
+			return "", errors.New("base-dir can't be combined with path-relative-to git")
+		}
+		if root, ok := findGitRoot(absRoot); ok {
+			return root, nil
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("invalid path-relative-to %q: want root or git", s.cfg.relativeTo)
+	}
+}
+
+// displayPath returns path relative to displayRoot in slash form, or
+// fallback if there is no display root.
+func displayPath(displayRoot, path, fallback string) string {
+	if displayRoot == "" {
+		return fallback
+	}
+	rel, err := filepath.Rel(displayRoot, path)
+	if err != nil {
+		return fallback
+	}
+	return filepath.ToSlash(rel)
+}
+
+// validatePathPattern reports a malformed path pattern before any files are
+// walked.
+func (s *scanner) validatePathPattern() error {
+	if s.cfg.pathPattern != "" && !doublestar.ValidatePattern(s.cfg.pathPattern) {
+		return fmt.Errorf("invalid path pattern %q", s.cfg.pathPattern)
+	}
+	return nil
+}
+
+// matchesPathPattern reports whether the slash-separated relative path
+// matches the configured path pattern, if any.
+func (s *scanner) matchesPathPattern(rel string) bool {
+	return s.cfg.pathPattern == "" || doublestar.MatchUnvalidated(s.cfg.pathPattern, rel)
 }
 
 func (s *scanner) shouldIgnoreDir(name string) bool {
-	_, ok := s.cfg.ignoreDirs[name]
-	return ok
+	if _, ok := s.cfg.ignoreDirs[name]; ok {
+		return true
+	}
+	return !s.cfg.hidden && isHidden(name)
+}
+
+// inIgnoredDir reports whether any directory in the slash-separated relative
+// path is ignored.
+func (s *scanner) inIgnoredDir(rel string) bool {
+	dirs := strings.Split(rel, "/")
+	for _, dir := range dirs[:len(dirs)-1] {
+		if s.shouldIgnoreDir(dir) {
+			return true
+		}
+	}
+	return false
 }
 
-func (s *scanner) isSupportedFile(name string) bool {
+// fileLanguage returns the language a scanned file is parsed with: the
+// configured language if the file has one of its extensions or, when
+// auto-detecting, the language registered for its extension. It returns nil
+// for files that aren't scanned.
+func (s *scanner) fileLanguage(name string) Language {
+	if !s.cfg.hidden && isHidden(path.Base(name)) {
+		return nil
+	}
+	if s.cfg.language == nil {
+		return fileLanguage(name)
+	}
 	ext := strings.ToLower(filepath.Ext(name))
 	if ext == "" {
-		return false
+		return nil
 	}
 	for _, e := range s.cfg.language.Extensions() {
 		if ext == e {
+			return s.cfg.language
+		}
+	}
+	return nil
+}
+
+// singleLanguage returns the language of a file named explicitly, which is
+// the configured language whatever its extension, or its detected language.
+func (s *scanner) singleLanguage(name string) Language {
+	if s.cfg.language != nil {
+		return s.cfg.language
+	}
+	return fileLanguage(name)
+}
+
+// maxBytes returns the size limit for files of language: the configured
+// one or, if unset when auto-detecting, the language's default.
+func (s *scanner) maxBytes(language Language) int64 {
+	if s.cfg.maxBytes == 0 {
+		return LanguageMaxBytes(language)
+	}
+	return s.cfg.maxBytes
+}
+
+// isHidden reports whether a file or directory name is dot-prefixed.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// looksMinifiedOrBinary samples the start of a file and reports whether it
+// contains a NUL byte (binary content), or a line longer than
+// maxSniffLineLength or too few newlines (minified or generated content).
+// Unreadable files are reported as binary so they are skipped.
+func looksMinifiedOrBinary(fsys fs.FS, path string) bool {
+	f, err := openFile(fsys, path)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffBytes)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return true
+	}
+	sample := buf[:n]
+
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+
+	lineStart := 0
+	for i, b := range sample {
+		if b == '\n' {
+			lineStart = i + 1
+		} else if i-lineStart >= maxSniffLineLength {
 			return true
 		}
 	}
-	return false
+	return len(sample) == sniffBytes && bytes.Count(sample, []byte("\n")) < minSniffNewlines
+}
+
+// openFile opens path in fsys, or in the OS filesystem if fsys is nil.
+func openFile(fsys fs.FS, path string) (fs.File, error) {
+	if fsys == nil {
+		return os.Open(path)
+	}
+	return fsys.Open(path)
+}
+
+// readFile reads path from fsys, or from the OS filesystem if fsys is nil.
+func readFile(fsys fs.FS, path string) ([]byte, error) {
+	if fsys == nil {
+		return os.ReadFile(path)
+	}
+	return fs.ReadFile(fsys, path)
 }
@@ -0,0 +1,497 @@
+package tsq
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScannerSkipMinified(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "tsq-scanner-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"normal.go": "package p\n\nfunc A() {}\n",
+		"short.go":  "package p; func B() {}",
+		"min.go":    "package p;" + strings.Repeat("var x = 1;", 100),
+		"bin.go":    "package p\n\x00\x01\x02",
+		// Lines of 300 bytes, within maxSniffLineLength, but only three to
+		// the kilobyte.
+		"wrapped.go": "package p;" + strings.Repeat(strings.Repeat("var x = 1;", 30)+"\n", 5),
+		// As long a file of ordinary lines is kept.
+		"long.go": "package p\n" + strings.Repeat("var x = 1 // a comment\n", 100),
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644))
+	}
+
+	collect := func(skip bool) []string {
+		sc := newScanner(scannerConfig{root: tmpDir, language: Get("go"), skipMinified: skip})
+		jobs, err := sc.collect()
+		require.NoError(t, err)
+
+		var names []string
+		for _, job := range jobs {
+			names = append(names, job.DisplayPath)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	require.Equal(t, []string{"bin.go", "long.go", "min.go", "normal.go", "short.go", "wrapped.go"}, collect(false))
+	require.Equal(t, []string{"long.go", "normal.go", "short.go"}, collect(true))
+}
+
+// bigGo is Go with a larger default size limit.
+type bigGo struct{ Go }
+
+func (b *bigGo) DefaultMaxBytes() int64 { return 4 * 1024 * 1024 }
+
+func TestScannerLanguageMaxBytes(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "tsq-scanner-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	big := "package p\n\n// " + strings.Repeat("x", 3*1024*1024) + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "big.go"), []byte(big), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "small.go"), []byte("package p\n"), 0644))
+
+	collect := func(lang Language, maxBytes int64) []string {
+		sc := newScanner(scannerConfig{root: tmpDir, language: lang, maxBytes: maxBytes})
+		jobs, err := sc.collect()
+		require.NoError(t, err)
+
+		var names []string
+		for _, job := range jobs {
+			names = append(names, job.DisplayPath)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	require.Equal(t, int64(2*1024*1024), LanguageMaxBytes(Get("go")))
+	require.Equal(t, int64(4*1024*1024), LanguageMaxBytes(&bigGo{}))
+
+	require.Equal(t, []string{"small.go"}, collect(Get("go"), 0), "go default skips the 3MB file")
+	require.Equal(t, []string{"big.go", "small.go"}, collect(&bigGo{}, 0), "larger default lets it through")
+	require.Equal(t, []string{"small.go"}, collect(&bigGo{}, 1024*1024), "explicit limit wins")
+	require.Equal(t, []string{"big.go", "small.go"}, collect(Get("go"), -1), "negative disables the limit")
+}
+
+func TestScannerSample(t *testing.T) {
+	tmpDir := t.TempDir()
+	generateTestFiles(t, tmpDir, 50, false)
+
+	collect := func(sample int, seed int64) []string {
+		sc := newScanner(scannerConfig{root: tmpDir, language: Get("go"), sample: sample, seed: seed})
+		jobs, err := sc.collect()
+		require.NoError(t, err)
+
+		var names []string
+		for _, job := range jobs {
+			names = append(names, job.DisplayPath)
+		}
+		return names
+	}
+
+	first := collect(5, 42)
+	require.Len(t, first, 5)
+	require.True(t, sort.StringsAreSorted(first), "sample keeps scan order")
+	require.Equal(t, first, collect(5, 42), "same seed picks the same files")
+	require.NotEqual(t, first, collect(5, 7), "different seeds pick different files")
+
+	require.Len(t, collect(0, 42), 50, "no sample keeps every file")
+	require.Len(t, collect(100, 42), 50, "sample larger than the tree keeps every file")
+}
+
+func TestScannerMaxFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	generateTestFiles(t, tmpDir, 20, false)
+
+	sc := newScanner(scannerConfig{root: tmpDir, language: Get("go"), maxFiles: 7})
+	jobs, err := sc.collect()
+	require.NoError(t, err)
+	require.Len(t, jobs, 7)
+	require.True(t, sc.truncated)
+
+	sc = newScanner(scannerConfig{root: tmpDir, language: Get("go"), maxFiles: 20})
+	jobs, err = sc.collect()
+	require.NoError(t, err)
+	require.Len(t, jobs, 20)
+	require.False(t, sc.truncated, "a cap equal to the file count isn't hit")
+
+	var diags []Diagnostic
+	results, err := Symbols(SymbolsOptions{Path: tmpDir, MaxFiles: 3, Diagnostics: &diags})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.Equal(t, []Diagnostic{{File: tmpDir, Kind: "max_files", Message: "stopped scanning after 3 files"}}, diags)
+}
+
+func TestScannerHidden(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"main.go", ".x.go", ".hidden/a.go", "pkg/.y.go", ".git/b.go"} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+	}
+
+	collect := func(hidden bool) []string {
+		jobs, err := newScanner(scannerConfig{root: tmpDir, language: Get("go"), hidden: hidden}).collect()
+		require.NoError(t, err)
+
+		var names []string
+		for _, job := range jobs {
+			names = append(names, job.DisplayPath)
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"main.go"}, collect(false))
+	require.Equal(t, []string{".hidden/a.go", ".x.go", "main.go", "pkg/.y.go"}, collect(true), ".git stays ignored")
+}
+
+func TestScannerIgnoreDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"main.go", "gen/a.go", "pkg/gen/b.go", "pkg/c.go", "vendor/d.go"} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+	}
+
+	jobs, err := newScanner(scannerConfig{root: tmpDir, language: Get("go"), ignoreDirs: ignoreDirSet([]string{"gen"})}).collect()
+	require.NoError(t, err)
+
+	var names []string
+	for _, job := range jobs {
+		names = append(names, job.DisplayPath)
+	}
+	require.Equal(t, []string{"main.go", "pkg/c.go"}, names, "vendor stays ignored")
+}
+
+func TestScannerRespectIgnoreSingle(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"main.go", "vendor/foo/bar.go", ".x.go", ".work/proj/x.go"} {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("package p\n\nfunc F() {}\n"), 0o644))
+	}
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	defer os.Chdir(wd)
+	chdir := func(dir string) {
+		t.Helper()
+		require.NoError(t, os.Chdir(filepath.Join(tmpDir, filepath.FromSlash(dir))))
+	}
+
+	symbols := func(file string, respect bool) ([]SymbolsResult, []Diagnostic) {
+		var diags []Diagnostic
+		results, err := Symbols(SymbolsOptions{
+			File:                filepath.FromSlash(file),
+			RespectIgnoreSingle: respect,
+			Diagnostics:         &diags,
+		})
+		require.NoError(t, err)
+		return results, diags
+	}
+
+	chdir(".")
+	results, diags := symbols("vendor/foo/bar.go", false)
+	require.Len(t, results, 1, "single files are analyzed regardless of ignore rules by default")
+	require.Empty(t, diags)
+
+	results, diags = symbols("vendor/foo/bar.go", true)
+	require.Empty(t, results)
+	require.Len(t, diags, 1)
+	require.Equal(t, "ignored", diags[0].Kind)
+
+	results, diags = symbols(filepath.Join(tmpDir, "vendor/foo/bar.go"), true)
+	require.Empty(t, results, "absolute paths are tested below the working directory")
+	require.Len(t, diags, 1)
+
+	results, diags = symbols(".x.go", true)
+	require.Empty(t, results, "hidden files are ignored")
+	require.Len(t, diags, 1)
+
+	results, diags = symbols("main.go", true)
+	require.Len(t, results, 1)
+	require.Empty(t, diags)
+
+	// Hidden or ignored ancestors of the working directory, or of a file
+	// outside it, are not tested, as a scan root's aren't.
+	chdir(".work/proj")
+	for _, file := range []string{"x.go", filepath.Join(tmpDir, ".work/proj/x.go")} {
+		results, diags = symbols(file, true)
+		require.Len(t, results, 1, file)
+		require.Empty(t, diags, file)
+	}
+	chdir("vendor")
+	results, diags = symbols(filepath.Join(tmpDir, ".work/proj/x.go"), true)
+	require.Len(t, results, 1)
+	require.Empty(t, diags)
+}
+
+func TestScannerModTime(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "main.go")
+	require.NoError(t, os.WriteFile(file, []byte("package main\n\nfunc Main() {}\n"), 0o644))
+	mtime := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+	require.NoError(t, os.Chtimes(file, mtime, mtime))
+	want := "2024-03-01T11:30:00Z"
+
+	results, err := Symbols(SymbolsOptions{Path: tmpDir, WithModTime: true})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, want, results[0].ModTime)
+
+	results, err = Symbols(SymbolsOptions{File: file, WithModTime: true})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, want, results[0].ModTime)
+
+	results, err = Symbols(SymbolsOptions{Path: tmpDir})
+	require.NoError(t, err)
+	require.Empty(t, results[0].ModTime, "mod time is opt-in")
+
+	outline, err := Outline(OutlineOptions{File: file, WithModTime: true})
+	require.NoError(t, err)
+	require.Equal(t, want, outline.ModTime)
+
+	fsys := fstest.MapFS{"main.go": {Data: []byte("package main\n\nfunc Main() {}\n"), ModTime: mtime}}
+	results, err = Symbols(SymbolsOptions{FS: fsys, WithModTime: true})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, want, results[0].ModTime)
+}
+
+func TestScannerFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":                    {Data: []byte("module example.com/m\n")},
+		"main.go":                   {Data: []byte("package main\n\nfunc Main() {}\n")},
+		"pkg/util/util.go":          {Data: []byte("package util\n\nfunc Helper() {}\n\ntype Thing struct{}\n")},
+		"pkg/util/README.md":        {Data: []byte("# util\n")},
+		"vendor/dep/dep.go":         {Data: []byte("package dep\n\nfunc Vendored() {}\n")},
+		"pkg/min/min.go":            {Data: []byte("package min\n\nvar x = \"" + strings.Repeat("a", 600) + "\"\n")},
+		"pkg/util/util_internal.go": {Data: []byte("package util\n\nfunc internal() {}\n")},
+	}
+
+	collect := func(cfg scannerConfig) []string {
+		cfg.language = Get("go")
+		cfg.fsys = fsys
+		jobs, err := newScanner(cfg).collect()
+		require.NoError(t, err)
+
+		var names []string
+		for _, job := range jobs {
+			names = append(names, job.DisplayPath)
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"main.go", "pkg/min/min.go", "pkg/util/util.go", "pkg/util/util_internal.go"}, collect(scannerConfig{}))
+	require.Equal(t, []string{"min/min.go", "util/util.go", "util/util_internal.go"}, collect(scannerConfig{root: "pkg"}))
+	require.Equal(t, []string{"main.go", "pkg/util/util.go", "pkg/util/util_internal.go"}, collect(scannerConfig{skipMinified: true}))
+	require.Equal(t, []string{"pkg/util/util.go"}, collect(scannerConfig{pathPattern: "**/util.go"}))
+
+	_, err := newScanner(scannerConfig{language: Get("go"), fsys: fsys, root: "../outside"}).collect()
+	require.Error(t, err)
+	_, err = newScanner(scannerConfig{language: Get("go"), fsys: fsys, relativeTo: "git"}).collect()
+	require.Error(t, err)
+
+	// The parser reads the scanned files from the same FS.
+	results, err := Symbols(SymbolsOptions{FS: fsys, Path: "pkg/util", Jobs: 2})
+	require.NoError(t, err)
+	var got []string
+	for _, r := range results {
+		for _, sym := range r.Symbols {
+			got = append(got, r.File+":"+sym.Name)
+		}
+	}
+	sort.Strings(got)
+	require.Equal(t, []string{"util.go:Helper", "util.go:Thing", "util_internal.go:internal"}, got)
+
+	matches, err := Query(QueryOptions{
+		FS:            fsys,
+		File:          "pkg/util/util.go",
+		Query:         `(function_declaration name: (identifier) @name)`,
+		FileOverrides: map[string][]byte{"./pkg/util/util.go": []byte("package util\n\nfunc Overridden() {}\n")},
+	})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	require.Equal(t, "util.go", matches[0].File)
+	require.Equal(t, "Overridden", matches[0].Captures[0].Text)
+}
+
+func TestScannerAutoDetect(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":        {Data: []byte("package main\n")},
+		"tools/gen.py":   {Data: []byte("def gen():\n    pass\n")},
+		"web/app.ts":     {Data: []byte("export function start() {}\n")},
+		"web/Button.TSX": {Data: []byte("export const Button = () => null;\n")},
+		"README":         {Data: []byte("no extension\n")},
+	}
+
+	jobs, err := newScanner(scannerConfig{fsys: fsys}).collect()
+	require.NoError(t, err)
+
+	got := make(map[string]string)
+	for _, job := range jobs {
+		got[job.DisplayPath] = job.Language.Name()
+	}
+	require.Equal(t, map[string]string{
+		"main.go":        "go",
+		"web/app.ts":     "typescript",
+		"web/Button.TSX": "tsx",
+	}, got)
+
+	// A file named explicitly needs a registered language too.
+	var diags diagnostics
+	_, err = newScanner(scannerConfig{fsys: fsys}).collectFile("tools/gen.py", &diags)
+	require.EqualError(t, err, `tools/gen.py: no registered language for ".py" files`)
+}
+
+// TestScannerBaseDir checks that display paths are relative to baseDir
+// rather than the scanned directory or file.
+func TestScannerBaseDir(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "tsq-scanner-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	sub := filepath.Join(tmpDir, "internal", "svc")
+	require.NoError(t, os.MkdirAll(filepath.Join(sub, "store"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sub, "svc.go"), []byte("package svc\n\nfunc Serve() { Open() }\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sub, "store", "store.go"), []byte("package store\n\nfunc Open() {}\n"), 0644))
+
+	collect := func(cfg scannerConfig) []string {
+		t.Helper()
+		cfg.language = Get("go")
+		jobs, err := newScanner(cfg).collect()
+		require.NoError(t, err)
+		var names []string
+		for _, job := range jobs {
+			names = append(names, job.DisplayPath)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	require.Equal(t, []string{"store/store.go", "svc.go"}, collect(scannerConfig{root: sub}))
+	require.Equal(t, []string{"internal/svc/store/store.go", "internal/svc/svc.go"}, collect(scannerConfig{root: sub, baseDir: tmpDir}))
+	require.Equal(t, []string{"../store.go"}, collect(scannerConfig{root: filepath.Join(sub, "store"), baseDir: filepath.Join(sub, "store", "testdata")}))
+
+	// A single file is named relative to baseDir too, instead of by its base
+	// name.
+	var diags diagnostics
+	jobs, err := newScanner(scannerConfig{language: Get("go"), baseDir: tmpDir}).collectFile(filepath.Join(sub, "svc.go"), &diags)
+	require.NoError(t, err)
+	require.Equal(t, "internal/svc/svc.go", jobs[0].DisplayPath)
+
+	// Results carry the same paths.
+	results, err := Symbols(SymbolsOptions{Path: sub, BaseDir: tmpDir, Jobs: 1})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "internal/svc/store/store.go", results[0].File)
+
+	refs, err := Refs(RefsOptions{Symbol: "Open", Path: sub, BaseDir: tmpDir, Jobs: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, refs.References)
+	for _, ref := range refs.References {
+		require.True(t, strings.HasPrefix(ref.File, "internal/svc/"), ref.File)
+	}
+
+	_, err = newScanner(scannerConfig{root: sub, baseDir: tmpDir, relativeTo: "git"}).collect()
+	require.EqualError(t, err, "base-dir can't be combined with path-relative-to git")
+	_, err = newScanner(scannerConfig{fsys: fstest.MapFS{}, baseDir: "."}).collect()
+	require.EqualError(t, err, "base-dir is not supported with an fs.FS")
+}
+
+func TestScannerGitignore(t *testing.T) {
+	files := map[string]string{
+		".gitignore":           "# generated code\ngen/\n*_mock.go\n!keep_mock.go\n/root_only.go\n",
+		"main.go":              "",
+		"root_only.go":         "",
+		"gen/types.go":         "",
+		"api/api.go":           "",
+		"api/api_mock.go":      "",
+		"api/keep_mock.go":     "",
+		"api/root_only.go":     "",
+		"api/.gitignore":       "local.go\n",
+		"api/local.go":         "",
+		"other/local.go":       "",
+		"other/gen.go":         "",
+		"other/gen/nested.go":  "",
+		"other/generated/a.go": "",
+	}
+	tmpDir := t.TempDir()
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+
+	collect := func(cfg scannerConfig) []string {
+		cfg.language = Get("go")
+		jobs, err := newScanner(cfg).collect()
+		require.NoError(t, err)
+
+		var names []string
+		for _, job := range jobs {
+			names = append(names, job.DisplayPath)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	want := []string{
+		"api/api.go", "api/keep_mock.go", "api/root_only.go",
+		"main.go",
+		"other/gen.go", "other/generated/a.go", "other/local.go",
+	}
+	require.Equal(t, want, collect(scannerConfig{root: tmpDir, gitignore: true}))
+	require.Equal(t, want, collect(scannerConfig{fsys: fsys, gitignore: true}))
+	require.Len(t, collect(scannerConfig{root: tmpDir}), len(files)-2, "every .go file without gitignore")
+}
+
+func TestScannerGitignoreWorktree(t *testing.T) {
+	files := map[string]string{
+		".git/HEAD":          "ref: refs/heads/main\n",
+		".gitignore":         "gen/\n/sub/root_only.go\n*.pb.go\n",
+		"sub/.gitignore":     "!keep.pb.go\n",
+		"sub/main.go":        "",
+		"sub/root_only.go":   "",
+		"sub/gen/x.go":       "",
+		"sub/api/api.pb.go":  "",
+		"sub/api/keep.pb.go": "",
+		"sub/api/api.go":     "",
+	}
+	tmpDir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	jobs, err := newScanner(scannerConfig{
+		root:      filepath.Join(tmpDir, "sub"),
+		language:  Get("go"),
+		gitignore: true,
+	}).collect()
+	require.NoError(t, err)
+
+	var names []string
+	for _, job := range jobs {
+		names = append(names, job.DisplayPath)
+	}
+	sort.Strings(names)
+	require.Equal(t, []string{"api/api.go", "api/keep.pb.go", "main.go"}, names,
+		"the worktree root's .gitignore applies to a scan of a subdirectory")
+}
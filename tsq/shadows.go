@@ -0,0 +1,132 @@
+package tsq
+
+import (
+	"errors"
+	"path"
+	"runtime"
+	"sort"
+)
+
+// Shadow is a local declaration that reuses the name of a package-level
+// symbol, hiding it for the rest of the scope.
+type Shadow struct {
+	Name       string   `json:"name"`
+	Location   Location `json:"shadowing_location"`
+	Definition Location `json:"shadowed_definition"`
+}
+
+// Location is a position in a file.
+type Location struct {
+	File     string   `json:"file"`
+	Position Position `json:"position"`
+}
+
+// goLocalDeclsQuery captures the names Go code declares inside functions:
+// short variable declarations, parameters and results, var and const specs
+// and range variables. var and const specs also match at package level;
+// those are told apart by position.
+const goLocalDeclsQuery = `
+(short_var_declaration left: (expression_list (identifier) @local))
+(parameter_declaration name: (identifier) @local)
+(variadic_parameter_declaration name: (identifier) @local)
+(var_spec name: (identifier) @local)
+(const_spec name: (identifier) @local)
+(range_clause left: (expression_list (identifier) @local))
+`
+
+// Shadows finds local variables, parameters and constants that shadow a
+// package-level symbol declared in the same directory. It is a name-based
+// heuristic for Go: every file of a directory is taken to be one package
+// and the scopes between the local and the package are not considered.
+func Shadows(opts ShadowsOptions) ([]Shadow, error) {
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.Jobs == 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+
+	language := Get("go")
+	if language == nil {
+		return nil, errors.New("go language not registered")
+	}
+
+	query, err := newQuery(language.SymbolsQuery()+goLocalDeclsQuery, language)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := newScanner(scannerConfig{
+		root:     opts.Path,
+		language: language,
+		maxBytes: opts.MaxBytes,
+	})
+	files, err := sc.collect()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return []Shadow{}, nil
+	}
+
+	type decl struct {
+		name  string
+		loc   Location
+		local bool
+	}
+	decls := runWorkers(language, query, files, workerConfig{jobs: opts.Jobs}, func(job FileJob, matches []QueryMatch, _ []byte) []decl {
+		var out []decl
+		for _, match := range matches {
+			if c, ok := findCapture(match, "local"); ok {
+				if c.Text != "_" && insideFunction(matches, c.Range.Start) {
+					out = append(out, decl{name: c.Text, loc: Location{job.DisplayPath, c.Range.Start}, local: true})
+				}
+				continue
+			}
+			sym := parseSymbolFromMatch(match, sourceOptions{})
+			if sym == nil || sym.Kind == "closure" || sym.Kind == "method" || sym.Name == "_" {
+				continue
+			}
+			name, _ := findCapture(match, "name")
+			if (sym.Kind == "var" || sym.Kind == "const") && insideFunction(matches, name.Range.Start) {
+				continue
+			}
+			out = append(out, decl{name: sym.Name, loc: Location{job.DisplayPath, name.Range.Start}})
+		}
+		return out
+	})
+
+	// Several patterns can capture the same local (a var_spec is also a
+	// symbol), so locals are keyed by location.
+	sort.Slice(decls, func(i, j int) bool {
+		return locationBefore(decls[i].loc, decls[j].loc)
+	})
+	packageLevel := make(map[[2]string]Location) // directory and name -> first definition
+	for _, d := range decls {
+		key := [2]string{path.Dir(d.loc.File), d.name}
+		if _, ok := packageLevel[key]; !ok && !d.local {
+			packageLevel[key] = d.loc
+		}
+	}
+
+	shadows := []Shadow{}
+	seen := make(map[Location]bool)
+	for _, d := range decls {
+		if !d.local || seen[d.loc] {
+			continue
+		}
+		seen[d.loc] = true
+		if def, ok := packageLevel[[2]string{path.Dir(d.loc.File), d.name}]; ok {
+			shadows = append(shadows, Shadow{Name: d.name, Location: d.loc, Definition: def})
+		}
+	}
+	return shadows, nil
+}
+
+// locationBefore orders locations by file, then position.
+func locationBefore(a, b Location) bool {
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	return positionBefore(a.Position, b.Position)
+}
@@ -0,0 +1,109 @@
+package tsq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// snippetRefLen is the number of hex digits of a snippet's SHA-256 kept in
+// its ref.
+const snippetRefLen = 16
+
+// SnippetRef returns the content-addressed handle of a source snippet: the
+// first 16 hex digits of its SHA-256. Equal snippets share a ref.
+func SnippetRef(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])[:snippetRefLen]
+}
+
+// ReadSnippet returns the snippet stored under ref in a snippet cache
+// directory, as written by Symbols with SnippetCache set.
+func ReadSnippet(dir, ref string) (string, error) {
+	if !validSnippetRef(ref) {
+		return "", fmt.Errorf("invalid snippet ref %q", ref)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ref))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("snippet %s not found in %s", ref, dir)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// storeSnippets moves the Source of every symbol in results, including
+// block members, into dir, replacing it with its SnippetRef.
+func storeSnippets(dir string, results []SymbolsResult) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create snippet cache: %w", err)
+	}
+	stored := make(map[string]bool)
+	var store func(symbols []Symbol) error
+	store = func(symbols []Symbol) error {
+		for i := range symbols {
+			sym := &symbols[i]
+			if sym.Source != "" {
+				ref := SnippetRef(sym.Source)
+				if !stored[ref] {
+					if err := writeSnippet(dir, ref, sym.Source); err != nil {
+						return err
+					}
+					stored[ref] = true
+				}
+				sym.SnippetRef, sym.Source = ref, ""
+			}
+			if err := store(sym.Members); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, r := range results {
+		if err := store(r.Symbols); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSnippet stores source under ref unless it is already cached. The
+// file is written under a temporary name and renamed into place, so
+// concurrent runs never see a partial snippet.
+func writeSnippet(dir, ref, source string) error {
+	path := filepath.Join(dir, ref)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	f, err := os.CreateTemp(dir, ".snippet-*")
+	if err != nil {
+		return fmt.Errorf("write snippet: %w", err)
+	}
+	_, err = f.WriteString(source)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("write snippet: %w", err)
+	}
+	return nil
+}
+
+// validSnippetRef reports whether ref has the form SnippetRef produces,
+// which also keeps it from naming a path outside the cache.
+func validSnippetRef(ref string) bool {
+	if len(ref) != snippetRefLen {
+		return false
+	}
+	_, err := hex.DecodeString(ref)
+	return err == nil
+}
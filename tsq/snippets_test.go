@@ -0,0 +1,46 @@
+package tsq
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSnippetRefs checks that snippets stored by Symbols resolve back to the
+// source the symbols would otherwise have inlined.
+func TestSnippetRefs(t *testing.T) {
+	dir := t.TempDir()
+	src := "package main\n\nfunc Hello() {\n\tprintln(\"hi\")\n}\n\ntype T struct{}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0o644))
+
+	inlined, err := Symbols(SymbolsOptions{Path: dir, IncludeSource: true, MaxSourceLines: 10})
+	require.NoError(t, err)
+
+	cache := filepath.Join(t.TempDir(), "snippets")
+	results, err := Symbols(SymbolsOptions{Path: dir, IncludeSource: true, MaxSourceLines: 10, SnippetCache: cache})
+	require.NoError(t, err)
+
+	require.Len(t, results, 1)
+	require.Len(t, results[0].Symbols, len(inlined[0].Symbols))
+	for i, sym := range results[0].Symbols {
+		require.Empty(t, sym.Source)
+		require.Equal(t, SnippetRef(inlined[0].Symbols[i].Source), sym.SnippetRef)
+
+		snippet, err := ReadSnippet(cache, sym.SnippetRef)
+		require.NoError(t, err)
+		require.Equal(t, inlined[0].Symbols[i].Source, snippet)
+	}
+
+	located, err := Symbols(SymbolsOptions{Path: dir, IncludeSource: true, MaxSourceLines: 10, SnippetCache: cache, SourceWithLocation: true})
+	require.NoError(t, err)
+	for i, sym := range located[0].Symbols {
+		require.Equal(t, results[0].Symbols[i].SnippetRef, sym.SnippetRef)
+	}
+
+	_, err = ReadSnippet(cache, SnippetRef("never stored"))
+	require.ErrorContains(t, err, "not found")
+	_, err = ReadSnippet(cache, "../../etc/passwd")
+	require.ErrorContains(t, err, "invalid snippet ref")
+}
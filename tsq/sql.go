@@ -0,0 +1,68 @@
+package tsq
+
+import (
+	_ "embed"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/sql"
+)
+
+//go:embed queries/sql/symbols.scm
+var sqlSymbolsQuery string
+
+//go:embed queries/sql/outline.scm
+var sqlOutlineQuery string
+
+//go:embed queries/sql/refs.scm
+var sqlRefsQuery string
+
+// SQL implements the Language interface for SQL. Its symbols are tables,
+// views and indexes. It is mostly useful for code injected into Go string
+// literals (see SymbolsOptions.Injections).
+type SQL struct{}
+
+func init() {
+	Register(&SQL{})
+}
+
+func (s *SQL) Name() string {
+	return "sql"
+}
+
+func (s *SQL) Extensions() []string {
+	return []string{".sql"}
+}
+
+func (s *SQL) TreeSitterLang() *sitter.Language {
+	return sql.GetLanguage()
+}
+
+func (s *SQL) SymbolsQuery() string {
+	return sqlSymbolsQuery
+}
+
+func (s *SQL) OutlineQuery() string {
+	return sqlOutlineQuery
+}
+
+func (s *SQL) RefsQuery() string {
+	return sqlRefsQuery
+}
+
+func (s *SQL) LineComment() string {
+	return "--"
+}
+
+// sqlObjectKinds are the capture names of SQL symbols, used as their kinds.
+var sqlObjectKinds = []string{"table", "view", "index"}
+
+// schemaObjectCapture returns the capture of a match declaring an SQL schema
+// object, named by its kind.
+func schemaObjectCapture(match QueryMatch) (CaptureResult, bool) {
+	for _, kind := range sqlObjectKinds {
+		if c, ok := findCapture(match, kind); ok {
+			return c, true
+		}
+	}
+	return CaptureResult{}, false
+}
@@ -0,0 +1,106 @@
+package tsq
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"sort"
+)
+
+// LanguageStats summarizes the files of one language in a scan.
+type LanguageStats struct {
+	Language string         `json:"language"`
+	Files    int            `json:"files"`
+	Lines    int            `json:"lines"`
+	Symbols  map[string]int `json:"symbols"` // symbol count by kind
+}
+
+// Stats counts the files, lines and symbols (by kind) under a path, per
+// language. With AllLanguages every registered language is counted and
+// languages without files are left out; results are sorted by language
+// name.
+func Stats(opts StatsOptions) ([]LanguageStats, error) {
+	if opts.Language == "" {
+		opts.Language = "go"
+	}
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.Jobs == 0 {
+		opts.Jobs = runtime.NumCPU()
+	}
+
+	names := []string{opts.Language}
+	if opts.AllLanguages {
+		names = List()
+		sort.Strings(names)
+	}
+
+	stats := []LanguageStats{}
+	for _, name := range names {
+		language := Get(name)
+		if language == nil {
+			return nil, errors.New(name + " language not registered")
+		}
+		s, err := languageStats(language, opts)
+		if err != nil {
+			return nil, err
+		}
+		if s.Files > 0 || !opts.AllLanguages {
+			stats = append(stats, s)
+		}
+	}
+	return stats, nil
+}
+
+// languageStats scans the files of one language and folds their counts.
+func languageStats(language Language, opts StatsOptions) (LanguageStats, error) {
+	stats := LanguageStats{Language: language.Name(), Symbols: map[string]int{}}
+
+	query, err := newQuery(language.SymbolsQuery(), language)
+	if err != nil {
+		return stats, err
+	}
+	sc := newScanner(scannerConfig{
+		root:       opts.Path,
+		language:   language,
+		maxBytes:   opts.MaxBytes,
+		hidden:     opts.Hidden,
+		ignoreDirs: ignoreDirSet(opts.IgnoreDirs),
+		gitignore:  opts.RespectGitignore,
+	})
+	files, err := sc.collect()
+	if err != nil {
+		return stats, err
+	}
+
+	type fileStats struct {
+		lines int
+		kinds []string
+	}
+	results := runWorkers(language, query, files, workerConfig{jobs: opts.Jobs}, func(_ FileJob, matches []QueryMatch, source []byte) []fileStats {
+		fs := fileStats{lines: countLines(source)}
+		for _, sym := range extractSymbols(matches, source, SymbolsOptions{Language: language.Name(), Visibility: "all"}) {
+			fs.kinds = append(fs.kinds, sym.Kind)
+		}
+		return []fileStats{fs}
+	})
+	for _, fs := range results {
+		stats.Files++
+		stats.Lines += fs.lines
+		for _, kind := range fs.kinds {
+			stats.Symbols[kind]++
+		}
+	}
+	return stats, nil
+}
+
+// countLines counts the lines of source, including a last line without a
+// trailing newline.
+func countLines(source []byte) int {
+	n := bytes.Count(source, []byte("\n"))
+	if len(source) > 0 && source[len(source)-1] != '\n' {
+		n++
+	}
+	return n
+}
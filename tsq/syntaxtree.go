@@ -0,0 +1,87 @@
+package tsq
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// TreeNode is a node of a file's syntax tree, for walking the tree without a
+// tree-sitter binding.
+type TreeNode struct {
+	Type     string      `json:"type"`
+	Named    bool        `json:"named"`
+	Range    Range       `json:"range"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// SyntaxTree parses a file and returns its syntax tree, rooted at the
+// language's top-level node (source_file for Go).
+func SyntaxTree(opts SyntaxTreeOptions) (*TreeNode, error) {
+	if opts.File == "" {
+		return nil, errors.New("file is required")
+	}
+	if opts.Language == "" {
+		opts.Language = "go"
+	}
+
+	language := Get(opts.Language)
+	if language == nil {
+		return nil, errors.New(opts.Language + " language not registered")
+	}
+
+	job, err := newScanner(scannerConfig{language: language}).collectSingle(opts.File)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newParser(language)
+	p.preserveEOL = opts.PreserveEOL
+	tree, _, err := p.parseFile(job.AbsPath)
+	if err != nil {
+		return nil, err
+	}
+	return buildTreeNode(tree.RootNode(), opts, 1), nil
+}
+
+// buildTreeNode converts n, at the given 1-based depth, and its descendants
+// down to opts.MaxDepth.
+func buildTreeNode(n *sitter.Node, opts SyntaxTreeOptions, depth int) *TreeNode {
+	node := &TreeNode{Type: n.Type(), Named: n.IsNamed(), Range: nodeRange(n)}
+	if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+		return node
+	}
+	for i := 0; i < int(n.ChildCount()); i++ {
+		child := n.Child(i)
+		if opts.NamedOnly && !child.IsNamed() {
+			continue
+		}
+		node.Children = append(node.Children, buildTreeNode(child, opts, depth+1))
+	}
+	return node
+}
+
+// Sexp renders the tree as an s-expression, e.g.
+// (source_file (package_clause "package" (package_identifier))). Anonymous
+// nodes are quoted.
+func (n *TreeNode) Sexp() string {
+	var sb strings.Builder
+	n.writeSexp(&sb)
+	return sb.String()
+}
+
+func (n *TreeNode) writeSexp(sb *strings.Builder) {
+	if !n.Named {
+		sb.WriteString(strconv.Quote(n.Type))
+		return
+	}
+	sb.WriteString("(")
+	sb.WriteString(n.Type)
+	for _, child := range n.Children {
+		sb.WriteString(" ")
+		child.writeSexp(sb)
+	}
+	sb.WriteString(")")
+}
@@ -0,0 +1,87 @@
+package tsq
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Todo is a TODO-style annotation found in a comment.
+type Todo struct {
+	File    string `json:"file"`
+	Marker  string `json:"marker"`          // TODO, FIXME, XXX, HACK
+	Owner   string `json:"owner,omitempty"` // from TODO(owner)
+	Text    string `json:"text"`
+	Line    int    `json:"line"`
+	Comment Range  `json:"comment"`          // the comment containing the annotation
+	Symbol  string `json:"symbol,omitempty"` // enclosing or documented declaration
+}
+
+// todoPattern matches a comment line that starts with a marker, an optional
+// parenthesized owner and the annotation text, e.g. "// TODO(alice): fix".
+var todoPattern = regexp.MustCompile(`^\s*(?://+|/\*+|\*+)?\s*(TODO|FIXME|XXX|HACK)(?:\(([^)]*)\))?(?::|\s|$)\s*(.*)`)
+
+// Todos lists TODO-style annotations in comments. A comment spanning several
+// lines can hold several annotations; each is reported on its own line.
+func Todos(opts TodosOptions) ([]Todo, error) {
+	copts := CommentsOptions{
+		Language: opts.Language,
+		Path:     opts.Path,
+		File:     opts.File,
+		Jobs:     opts.Jobs,
+		MaxBytes: opts.MaxBytes,
+	}
+	language, files, q, err := prepareComments(&copts)
+	if err != nil || len(files) == 0 {
+		return []Todo{}, err
+	}
+
+	cfg := workerConfig{jobs: copts.Jobs}
+	todos := runWorkers(language, q, files, cfg, func(job FileJob, matches []QueryMatch, source []byte) []Todo {
+		comments, _ := classifyComments(matches, source)
+
+		var out []Todo
+		for _, c := range comments {
+			for _, todo := range parseTodos(c) {
+				if opts.Owner != "" && todo.Owner != opts.Owner {
+					continue
+				}
+				todo.File = job.DisplayPath
+				out = append(out, todo)
+			}
+		}
+		return out
+	})
+	if todos == nil {
+		todos = []Todo{}
+	}
+
+	sort.Slice(todos, func(i, j int) bool {
+		if todos[i].File != todos[j].File {
+			return todos[i].File < todos[j].File
+		}
+		return todos[i].Line < todos[j].Line
+	})
+	return todos, nil
+}
+
+// parseTodos extracts the annotations from a comment, one per line at most.
+func parseTodos(c Comment) []Todo {
+	var todos []Todo
+	for i, line := range strings.Split(c.Text, "\n") {
+		m := todoPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(m[3]), "*/"))
+		todos = append(todos, Todo{
+			Marker:  m[1],
+			Owner:   strings.TrimSpace(m[2]),
+			Text:    text,
+			Line:    c.Range.Start.Line + i,
+			Comment: c.Range,
+			Symbol:  c.Symbol,
+		})
+	}
+	return todos
+}
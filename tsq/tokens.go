@@ -0,0 +1,14 @@
+package tsq
+
+// bytesPerToken approximates how many bytes of JSON or source code map to one
+// LLM token. Real tokenizers vary by model; this errs on the side of
+// overestimating so budgets are not exceeded.
+const bytesPerToken = 4
+
+// EstimateTokens returns an approximate LLM token count for n bytes of output.
+func EstimateTokens(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return (n + bytesPerToken - 1) / bytesPerToken
+}
@@ -0,0 +1,21 @@
+package tsq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	require.Equal(t, 0, EstimateTokens(0))
+	require.Equal(t, 1, EstimateTokens(1))
+
+	// The estimate never decreases as output grows
+	prev := 0
+	for n := 0; n <= 10000; n += 7 {
+		est := EstimateTokens(n)
+		require.GreaterOrEqual(t, est, prev, "estimate for %d bytes", n)
+		prev = est
+	}
+	require.Greater(t, EstimateTokens(10000), EstimateTokens(100))
+}
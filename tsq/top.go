@@ -0,0 +1,186 @@
+package tsq
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// rankedSymbol is a symbol with the score it is ranked by in topSymbols.
+type rankedSymbol struct {
+	file    string
+	modTime string
+	sym     Symbol
+	score   int
+}
+
+// ranksAbove reports whether a ranks above b: higher score first, then by
+// file and position so the result doesn't depend on arrival order.
+func (a rankedSymbol) ranksAbove(b rankedSymbol) bool {
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	if a.file != b.file {
+		return a.file < b.file
+	}
+	if a.sym.Range.Start.Line != b.sym.Range.Start.Line {
+		return a.sym.Range.Start.Line < b.sym.Range.Start.Line
+	}
+	return a.sym.Range.Start.Column < b.sym.Range.Start.Column
+}
+
+// topHeap is a min-heap with the lowest ranked symbol at the root, so the
+// symbol to evict is always at hand.
+type topHeap []rankedSymbol
+
+func (h topHeap) Len() int           { return len(h) }
+func (h topHeap) Less(i, j int) bool { return h[j].ranksAbove(h[i]) }
+func (h topHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *topHeap) Push(x any)        { *h = append(*h, x.(rankedSymbol)) }
+func (h *topHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// topN keeps the n highest ranked symbols offered to it.
+type topN struct {
+	n int
+	h topHeap
+}
+
+func (t *topN) offer(r rankedSymbol) {
+	if len(t.h) < t.n {
+		heap.Push(&t.h, r)
+		return
+	}
+	if r.ranksAbove(t.h[0]) {
+		t.h[0] = r
+		heap.Fix(&t.h, 0)
+	}
+}
+
+// sorted returns the kept symbols, best first.
+func (t *topN) sorted() []rankedSymbol {
+	ranked := slices.Clone(t.h)
+	slices.SortFunc(ranked, func(a, b rankedSymbol) int {
+		if a.ranksAbove(b) {
+			return -1
+		}
+		if b.ranksAbove(a) {
+			return 1
+		}
+		return 0
+	})
+	return ranked
+}
+
+// validTop reports invalid Top and By options.
+func validTop(opts SymbolsOptions) error {
+	switch opts.By {
+	case "", "size", "lines", "refs":
+	default:
+		return fmt.Errorf("invalid by %q: want size, lines or refs", opts.By)
+	}
+	if opts.Top > 0 && opts.PromoteEmbedded {
+		return errors.New("top can't be combined with promote-embedded")
+	}
+	return nil
+}
+
+// declsByName maps the position of each declared name in matches to the
+// capture of its whole declaration.
+func declsByName(matches []QueryMatch) map[Position]CaptureResult {
+	decls := make(map[Position]CaptureResult)
+	for _, match := range matches {
+		name, ok := findCapture(match, "name")
+		if !ok {
+			continue
+		}
+		if decl, ok := declCapture(match); ok {
+			decls[name.Range.Start] = decl
+		}
+	}
+	return decls
+}
+
+// topSymbols streams the symbols of files through a bounded heap and returns
+// the opts.Top best by opts.By. Consecutive symbols from the same file are
+// grouped into one SymbolsResult.
+func topSymbols(ctx context.Context, language Language, query *query, files []FileJob, opts SymbolsOptions, changed changedLines, diags *diagnostics) ([]SymbolsResult, error) {
+	var refCounts map[string]int
+	if opts.By == "refs" {
+		// Auto-detected languages are counted separately and summed.
+		languages := []string{opts.Language}
+		if language == nil {
+			languages = languages[:0]
+			for _, f := range files {
+				if !slices.Contains(languages, f.Language.Name()) {
+					languages = append(languages, f.Language.Name())
+				}
+			}
+		}
+		refCounts = make(map[string]int)
+		for _, name := range languages {
+			spots, err := Hotspots(HotspotsOptions{
+				Language: name,
+				Path:     scanRoot(opts.Path, opts.File),
+				Jobs:     opts.Jobs,
+				MaxBytes: opts.MaxBytes,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, spot := range spots {
+				refCounts[spot.Name] += spot.References
+			}
+		}
+	}
+
+	top := &topN{n: opts.Top}
+	streamWorkers(language, query, files, symbolsWorkerConfig(ctx, language, opts, diags), func(job FileJob, matches []QueryMatch, source []byte) []rankedSymbol {
+		symbols := changed.filterSymbols(job.AbsPath, fileSymbols(matches, source, jobSymbolsOptions(job, opts)), matches)
+
+		// Symbol ranges cover the name; size and lines measure the whole
+		// declaration, found by the position of its name.
+		decls := declsByName(matches)
+
+		ranked := make([]rankedSymbol, len(symbols))
+		for i, sym := range symbols {
+			r := rankedSymbol{file: job.DisplayPath, sym: sym}
+			if opts.WithModTime {
+				r.modTime = job.modTime()
+			}
+			decl, ok := decls[sym.Range.Start]
+			if !ok {
+				decl = CaptureResult{Range: sym.Range}
+			}
+			switch opts.By {
+			case "lines":
+				r.score = decl.Range.End.Line - decl.Range.Start.Line + 1
+			case "refs":
+				r.score = refCounts[sym.Name]
+			default:
+				r.score = decl.endByte - decl.startByte
+			}
+			ranked[i] = r
+		}
+		return ranked
+	}, top.offer)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	results := []SymbolsResult{}
+	for _, r := range top.sorted() {
+		if n := len(results); n > 0 && results[n-1].File == r.file {
+			results[n-1].Symbols = append(results[n-1].Symbols, r.sym)
+			continue
+		}
+		results = append(results, SymbolsResult{File: r.file, ModTime: r.modTime, Symbols: []Symbol{r.sym}})
+	}
+	return results, nil
+}
@@ -0,0 +1,66 @@
+package tsq
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopNArrivalOrder(t *testing.T) {
+	var symbols []rankedSymbol
+	for i := range 50 {
+		symbols = append(symbols, rankedSymbol{
+			file:  fmt.Sprintf("f%02d.go", i),
+			sym:   Symbol{Name: fmt.Sprintf("Func%d", i)},
+			score: (i * 37) % 50, // a permutation of 0..49
+		})
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for range 20 {
+		rng.Shuffle(len(symbols), func(i, j int) { symbols[i], symbols[j] = symbols[j], symbols[i] })
+
+		top := &topN{n: 3}
+		for _, s := range symbols {
+			top.offer(s)
+		}
+
+		var scores []int
+		for _, r := range top.sorted() {
+			scores = append(scores, r.score)
+		}
+		require.Equal(t, []int{49, 48, 47}, scores)
+	}
+}
+
+func TestTopNTies(t *testing.T) {
+	// Equal scores are broken by file then position, whatever the order.
+	a := rankedSymbol{file: "a.go", sym: Symbol{Name: "A"}, score: 1}
+	b := rankedSymbol{file: "b.go", sym: Symbol{Name: "B"}, score: 1}
+	c := rankedSymbol{file: "c.go", sym: Symbol{Name: "C"}, score: 1}
+
+	for _, order := range [][]rankedSymbol{{a, b, c}, {c, b, a}, {b, c, a}} {
+		top := &topN{n: 2}
+		for _, s := range order {
+			top.offer(s)
+		}
+		require.Equal(t, []rankedSymbol{a, b}, top.sorted())
+	}
+}
+
+func TestTopSymbolsJobs(t *testing.T) {
+	dir := t.TempDir()
+	generateTestFiles(t, dir, 20, true)
+
+	// Func19_* call Func18_*, so file_19.go holds the longest functions and,
+	// with concurrent workers, they arrive in no particular order.
+	want, err := Symbols(SymbolsOptions{Path: dir, Jobs: 1, Top: 5, By: "lines"})
+	require.NoError(t, err)
+	for range 5 {
+		got, err := Symbols(SymbolsOptions{Path: dir, Jobs: 8, Top: 5, By: "lines"})
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+}
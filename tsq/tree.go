@@ -0,0 +1,59 @@
+package tsq
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// SymbolTree nests per-file symbols by directory, following the components
+// of each result's File: {"a": {"b": {"c.go": [...symbols]}}}. Directories
+// are maps keyed by entry name and files map to their symbols; files at the
+// root level sit in the top map. Results for the same file are merged.
+func SymbolTree(results []SymbolsResult) map[string]any {
+	tree := make(map[string]any)
+	for _, r := range results {
+		dir, name := treeDir(tree, r.File)
+		if symbols, ok := dir[name].([]Symbol); ok {
+			dir[name] = append(symbols, r.Symbols...)
+			continue
+		}
+		dir[name] = r.Symbols
+	}
+	return tree
+}
+
+// OutlineTree places an outline in a directory tree by its File, with the
+// file mapped to the outline itself.
+func OutlineTree(outline FileOutline) map[string]any {
+	tree := make(map[string]any)
+	dir, name := treeDir(tree, outline.File)
+	dir[name] = outline
+	return tree
+}
+
+// treeDir returns the map for the directory containing path, creating the
+// maps along the way, and the file's name within it. Empty and "."
+// components are skipped, so "./a.go" and "/src/a.go" nest like "a.go" and
+// "src/a.go".
+func treeDir(tree map[string]any, path string) (map[string]any, string) {
+	var parts []string
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return tree, path
+	}
+
+	dir := tree
+	for _, part := range parts[:len(parts)-1] {
+		sub, ok := dir[part].(map[string]any)
+		if !ok {
+			sub = make(map[string]any)
+			dir[part] = sub
+		}
+		dir = sub
+	}
+	return dir, parts[len(parts)-1]
+}
@@ -1,6 +1,12 @@
 // Package tsq provides a tree-sitter based API for exploring code.
 package tsq
 
+import (
+	"time"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
 // Position represents a location in a source file.
 type Position struct {
 	Line   int `json:"line"`
@@ -15,21 +21,43 @@ type Range struct {
 
 // Symbol represents a code symbol (function, type, variable, etc).
 type Symbol struct {
-	Name       string `json:"name"`
-	Kind       string `json:"kind"`       // function, type, method, var, const, interface, struct, field
-	Visibility string `json:"visibility"` // public, private
-	File       string `json:"file"`
-	Range      Range  `json:"range"`
-	Signature  string `json:"signature,omitempty"` // function signature or type definition
-	Source     string `json:"source,omitempty"`    // actual source code (optional)
-	Receiver   string `json:"receiver,omitempty"`  // for methods: the receiver type
-	Doc        string `json:"doc,omitempty"`       // documentation comment
+	Name            string   `json:"name"`
+	Kind            string   `json:"kind"`       // function, type, method, var, const, interface, struct, field, closure, const_block, var_block; class in JavaScript, TypeScript and Java, enum in TypeScript, Rust and Java (and Go with DetectEnums), trait in Rust; table, view, index in SQL
+	Visibility      string   `json:"visibility"` // public, private
+	File            string   `json:"file"`
+	Range           Range    `json:"range"`
+	Signature       string   `json:"signature,omitempty"`        // function signature or type definition
+	TypeParams      string   `json:"type_params,omitempty"`      // for generic functions and types: the type parameter list, e.g. [T, U any]
+	Source          string   `json:"source,omitempty"`           // actual source code (optional)
+	SnippetRef      string   `json:"snippet_ref,omitempty"`      // handle of Source in a snippet cache, in place of Source (optional)
+	Body            string   `json:"body,omitempty"`             // for functions/methods: the body block (optional)
+	Receiver        string   `json:"receiver,omitempty"`         // for methods: the receiver type
+	ReceiverPointer bool     `json:"receiver_pointer,omitempty"` // for methods: whether the receiver is a pointer
+	Doc             string   `json:"doc,omitempty"`              // documentation comment
+	Value           string   `json:"value,omitempty"`            // for consts in iota blocks: the resolved integer value; for Go enum members: the value (optional)
+	NumParams       *int     `json:"num_params,omitempty"`       // for functions/methods: parameter count, set with WithArity (optional)
+	NumResults      *int     `json:"num_results,omitempty"`      // for functions/methods: result count, set with WithArity (optional)
+	ReturnsError    bool     `json:"returns_error,omitempty"`    // for functions/methods: whether the last result is of type error
+	QualifiedName   string   `json:"qualified_name,omitempty"`   // pkg.Name or pkg.Receiver.Name (optional)
+	Enclosing       string   `json:"enclosing,omitempty"`        // for closures: the enclosing declaration
+	Embeds          []string `json:"embeds,omitempty"`           // for structs: embedded types, "*T" if embedded as a pointer (optional)
+	LayoutHint      string   `json:"layout_hint,omitempty"`      // for structs: estimated field offsets and size, and a smaller reordering if any (optional)
+	Promoted        bool     `json:"promoted,omitempty"`         // for methods: promoted from an embedded type
+	PromotedFrom    string   `json:"promoted_from,omitempty"`    // for promoted methods: the embedded type
+	Directives      []string `json:"directives,omitempty"`       // //go: directive lines right above the declaration (optional)
+	Members         []Symbol `json:"members,omitempty"`          // for const/var blocks and Go enums: the grouped specs
+	Language        string   `json:"language,omitempty"`         // for symbols in injected code: the embedded language (optional)
 }
 
 // ImportInfo represents an import statement.
 type ImportInfo struct {
 	Path  string `json:"path"`
 	Alias string `json:"alias,omitempty"`
+	Group string `json:"group,omitempty"` // std, third_party, local (optional)
+
+	// UsageCount is how many times the package name is referenced in the
+	// file (optional). It is nil for blank and dot imports.
+	UsageCount *int `json:"usage_count,omitempty"`
 }
 
 // FileOutline represents the structural overview of a file.
@@ -37,16 +65,42 @@ type FileOutline struct {
 	File    string       `json:"file"`
 	Package string       `json:"package"`
 	Imports []ImportInfo `json:"imports,omitempty"`
+	Embeds  []string     `json:"embeds,omitempty"` // //go:embed patterns
 	Symbols []Symbol     `json:"symbols"`
+	Errors  []Range      `json:"errors,omitempty"`   // regions where parsing recovered from a syntax error
+	ModTime string       `json:"mod_time,omitempty"` // file modification time, RFC3339 (optional)
 }
 
 // Reference represents a usage of a symbol.
 type Reference struct {
-	Symbol   string   `json:"symbol"`
-	Kind     string   `json:"kind"` // call, type_ref, field_access, identifier
-	File     string   `json:"file"`
-	Position Position `json:"position"`
-	Context  string   `json:"context,omitempty"` // surrounding code snippet
+	Symbol   string     `json:"symbol"`
+	Kind     string     `json:"kind"` // call, type_ref, field_access, identifier
+	File     string     `json:"file"`
+	Position Position   `json:"position"`
+	Context  string     `json:"context,omitempty"` // surrounding code snippet
+	Offsets  *ByteRange `json:"offsets,omitempty"` // byte span of the reference in the file (optional)
+
+	// EnclosingSymbol is the function or method (Receiver.Name) the
+	// reference is in, or the package-level var or const it initializes
+	// (optional).
+	EnclosingSymbol string `json:"enclosing_symbol,omitempty"`
+
+	// Package is the package clause of the reference's file (optional).
+	Package string `json:"package,omitempty"`
+
+	// ID identifies the reference across edits (optional, see
+	// RefsOptions.WithID).
+	ID string `json:"id,omitempty"`
+
+	// declaration is set if the reference is the name of the symbol's
+	// declaration rather than a use of it.
+	declaration bool
+}
+
+// ByteRange is a half-open span of byte offsets, [Start, End), in a file.
+type ByteRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
 }
 
 // QueryMatch represents a raw tree-sitter query match.
@@ -56,16 +110,36 @@ type QueryMatch struct {
 	Captures []CaptureResult `json:"captures"`
 }
 
+// LegendEntry describes a capture name defined in a query.
+type LegendEntry struct {
+	Name     string `json:"name"`
+	Captures int    `json:"captures"` // number of captures with this name in the results
+}
+
+// PatternCount is the number of matches a query pattern produced.
+type PatternCount struct {
+	Pattern int `json:"pattern"` // 0-based index, as in QueryMatch.Pattern
+	Matches int `json:"matches"`
+}
+
 // CaptureResult represents a single capture within a query match.
 type CaptureResult struct {
-	Name     string `json:"name"`
-	NodeType string `json:"node_type"`
-	Text     string `json:"text"`
-	Range    Range  `json:"range"`
+	Name      string `json:"name"`
+	NodeType  string `json:"node_type"`
+	Text      string `json:"text"`
+	Sanitized bool   `json:"sanitized,omitempty"` // invalid UTF-8 in Text was replaced with U+FFFD
+	Range     Range  `json:"range"`
+	Context   string `json:"context,omitempty"` // surrounding source bytes (optional)
+
+	startByte, endByte int          // byte offsets of the node in the parsed source
+	node               *sitter.Node // the captured node, released once a file's matches are filtered
 }
 
 // FileJob represents a file to be processed.
 type FileJob struct {
 	AbsPath     string
 	DisplayPath string
+	Size        int64     // size on disk in bytes, 0 if unknown
+	ModTime     time.Time // last modification time, zero if unknown
+	Language    Language  // language the file is parsed with, nil if unknown
 }
@@ -0,0 +1,79 @@
+package tsq
+
+import (
+	_ "embed"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+//go:embed queries/typescript/symbols.scm
+var tsSymbolsQuery string
+
+//go:embed queries/typescript/outline.scm
+var tsOutlineQuery string
+
+//go:embed queries/typescript/refs.scm
+var tsRefsQuery string
+
+//go:embed queries/typescript/exports.scm
+var tsExportsQuery string
+
+// TypeScript implements the Language interface for TypeScript. Besides the
+// JavaScript symbols it reports interfaces, type aliases and enums, and
+// signatures carry type annotations. Visibility follows exports, as in
+// JavaScript.
+type TypeScript struct{}
+
+// TSX implements the Language interface for TypeScript with JSX. It shares
+// TypeScript's queries; only the grammar differs.
+type TSX struct {
+	TypeScript
+}
+
+func init() {
+	Register(&TypeScript{})
+	Register(&TSX{})
+}
+
+func (t *TypeScript) Name() string {
+	return "typescript"
+}
+
+func (t *TypeScript) Extensions() []string {
+	return []string{".ts", ".mts", ".cts"}
+}
+
+func (t *TypeScript) TreeSitterLang() *sitter.Language {
+	return typescript.GetLanguage()
+}
+
+func (t *TypeScript) SymbolsQuery() string {
+	return tsSymbolsQuery + tsExportsQuery
+}
+
+func (t *TypeScript) OutlineQuery() string {
+	return tsOutlineQuery + tsExportsQuery
+}
+
+func (t *TypeScript) RefsQuery() string {
+	return tsRefsQuery
+}
+
+// Visibility makes exported symbols public, as JavaScript.Visibility does.
+func (t *TypeScript) Visibility(matches []QueryMatch) func(Symbol) string {
+	return exportVisibility(matches)
+}
+
+func (t *TSX) Name() string {
+	return "tsx"
+}
+
+func (t *TSX) Extensions() []string {
+	return []string{".tsx"}
+}
+
+func (t *TSX) TreeSitterLang() *sitter.Language {
+	return tsx.GetLanguage()
+}
@@ -1,11 +1,16 @@
 package tsq
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -35,7 +40,7 @@ func TestRunWorkers(t *testing.T) {
 			defer os.RemoveAll(tmpDir)
 
 			// Generate test files and collect expected function names
-			expectedFuncs := generateTestFiles(t, tmpDir, tc.fileCount)
+			expectedFuncs := generateTestFiles(t, tmpDir, tc.fileCount, false)
 
 			if tc.fileCount == 0 {
 				// Edge case: no files to process
@@ -45,7 +50,7 @@ func TestRunWorkers(t *testing.T) {
 				query, err := newQuery(`(function_declaration name: (identifier) @name)`, language)
 				require.NoError(t, err)
 
-				results := runWorkers(language, query, []FileJob{}, tc.jobs, extractFunctionNames)
+				results := runWorkers(language, query, []FileJob{}, workerConfig{jobs: tc.jobs}, extractFunctionNames)
 				require.Empty(t, results)
 				return
 			}
@@ -69,7 +74,7 @@ func TestRunWorkers(t *testing.T) {
 			require.NoError(t, err)
 
 			// Run workers with a process function that extracts function names
-			results := runWorkers(language, query, files, tc.jobs, extractFunctionNames)
+			results := runWorkers(language, query, files, workerConfig{jobs: tc.jobs}, extractFunctionNames)
 
 			// Verify results
 			require.Len(t, results, tc.fileCount, "should have one result per file")
@@ -84,9 +89,11 @@ func TestRunWorkers(t *testing.T) {
 }
 
 // generateTestFiles creates N Go files, each with a unique function.
-// Returns the expected function names.
-func generateTestFiles(t *testing.T, dir string, count int) []string {
-	t.Helper()
+// Returns the expected function names. With full, each file also declares
+// a struct, a method, a few more functions, consts and vars, and cross-file
+// calls, so every symbol kind and reference kind is exercised.
+func generateTestFiles(tb testing.TB, dir string, count int, full bool) []string {
+	tb.Helper()
 
 	var expected []string
 	for i := range count {
@@ -98,9 +105,12 @@ func generateTestFiles(t *testing.T, dir string, count int) []string {
 
 func %s() {}
 `, funcName)
+		if full {
+			content += generateDecls(i)
+		}
 
 		err := os.WriteFile(filePath, []byte(content), 0644)
-		require.NoError(t, err)
+		require.NoError(tb, err)
 
 		expected = append(expected, funcName)
 	}
@@ -108,6 +118,23 @@ func %s() {}
 	return expected
 }
 
+// generateDecls returns the extra declarations of the i-th full test file.
+func generateDecls(i int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\nconst Limit%d = %d\n\nvar counter%d int\n\n", i, i, i)
+	fmt.Fprintf(&sb, "type Item%d struct {\n\tName  string\n\tValue int\n}\n\n", i)
+	fmt.Fprintf(&sb, "func (it *Item%d) String() string {\n\treturn it.Name\n}\n", i)
+	for j := range 5 {
+		fmt.Fprintf(&sb, "\nfunc Func%d_%d(a, b int) (int, error) {\n", i, j)
+		fmt.Fprintf(&sb, "\tcounter%d++\n\tit := &Item%d{Name: \"x\", Value: a + b}\n", i, i)
+		if i > 0 {
+			fmt.Fprintf(&sb, "\t_, _ = Func%d_%d(a, b)\n", i-1, j)
+		}
+		fmt.Fprintf(&sb, "\treturn len(it.String()), nil\n}\n")
+	}
+	return sb.String()
+}
+
 // extractFunctionNames is a process function that extracts function names from matches.
 func extractFunctionNames(job FileJob, matches []QueryMatch, _ []byte) []string {
 	var names []string
@@ -120,3 +147,221 @@ func extractFunctionNames(job FileJob, matches []QueryMatch, _ []byte) []string
 	}
 	return names
 }
+
+// TestRunWorkersSchedule checks that dispatching the largest files first
+// changes only the processing order, not the results.
+func TestRunWorkersSchedule(t *testing.T) {
+	tmpDir := t.TempDir()
+	expectedFuncs := generateTestFiles(t, tmpDir, 20, false)
+	sort.Strings(expectedFuncs)
+
+	language := Get("go")
+	files, err := newScanner(scannerConfig{root: tmpDir, language: language}).collect()
+	require.NoError(t, err)
+	for _, f := range files {
+		require.Positive(t, f.Size, f.DisplayPath)
+	}
+
+	query, err := newQuery(`(function_declaration name: (identifier) @name)`, language)
+	require.NoError(t, err)
+
+	for _, schedule := range []string{"", "size"} {
+		results := runWorkers(language, query, files, workerConfig{jobs: 4, schedule: schedule}, extractFunctionNames)
+		sort.Strings(results)
+		require.Equal(t, expectedFuncs, results, "schedule %q", schedule)
+	}
+
+	sorted := largestFirst([]FileJob{{DisplayPath: "a", Size: 1}, {DisplayPath: "b", Size: 30}, {DisplayPath: "c", Size: 5}})
+	require.Equal(t, []string{"b", "c", "a"}, []string{sorted[0].DisplayPath, sorted[1].DisplayPath, sorted[2].DisplayPath})
+}
+
+// TestRunWorkersQueryError checks that the files of a language whose query
+// doesn't compile are skipped with one diagnostic for the language.
+func TestRunWorkersQueryError(t *testing.T) {
+	tmpDir := t.TempDir()
+	generateTestFiles(t, tmpDir, 3, false)
+	files, err := newScanner(scannerConfig{root: tmpDir}).collect()
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	var diags diagnostics
+	cfg := workerConfig{
+		jobs:    2,
+		queries: newLanguageQueries(func(Language) string { return "(no_such_node) @x" }),
+		diags:   &diags,
+	}
+	require.Empty(t, runWorkers(nil, nil, files, cfg, extractFunctionNames))
+
+	var got []Diagnostic
+	diags.flush(&got)
+	require.Len(t, got, 1)
+	require.Equal(t, "query_error", got[0].Kind)
+	require.Contains(t, got[0].Message, "go files skipped: compile query")
+}
+
+// TestSymbolsContextCancel checks that cancelling the context mid-scan stops
+// the worker pool early and returns the context's error.
+func TestSymbolsContextCancel(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "tsq-workers-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	const fileCount = 2000
+	generateTestFiles(t, tmpDir, fileCount, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var seen atomic.Int64
+	start := time.Now()
+	results, err := SymbolsContext(ctx, SymbolsOptions{
+		Language: "go",
+		Path:     tmpDir,
+		Jobs:     4,
+		SymbolHook: func(*Symbol) bool {
+			if seen.Add(1) == 10 {
+				cancel()
+			}
+			return true
+		},
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, results)
+	require.Less(t, seen.Load(), int64(fileCount/2), "workers should stop soon after the cancel")
+	require.Less(t, time.Since(start), 10*time.Second)
+
+	// The other entry points check the context too.
+	_, err = QueryContext(ctx, QueryOptions{Query: "(function_declaration) @fn", Path: tmpDir})
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = RefsContext(ctx, RefsOptions{Symbol: "Func1", Language: "go", Path: tmpDir})
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = OutlineContext(ctx, OutlineOptions{File: filepath.Join(tmpDir, "file_0.go")})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// An uncancelled context finds every file.
+	results, err = SymbolsContext(context.Background(), SymbolsOptions{Language: "go", Path: tmpDir, Jobs: 4})
+	require.NoError(t, err)
+	require.Len(t, results, fileCount)
+}
+
+// TestStreamOutlines checks that every file's outline is emitted once, and
+// that an emit error stops the stream and is returned.
+func TestStreamOutlines(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "tsq-workers-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	expected := generateTestFiles(t, tmpDir, 200, false)
+
+	var names []string
+	err = StreamOutlines(context.Background(), OutlineOptions{Language: "go", Path: tmpDir, Jobs: 4}, func(outline FileOutline) error {
+		require.Len(t, outline.Symbols, 1)
+		names = append(names, outline.Symbols[0].Name)
+		return nil
+	})
+	require.NoError(t, err)
+	sort.Strings(names)
+	sort.Strings(expected)
+	require.Equal(t, expected, names)
+
+	stop := fmt.Errorf("stop")
+	emitted := 0
+	err = StreamOutlines(context.Background(), OutlineOptions{Path: tmpDir, Jobs: 4}, func(FileOutline) error {
+		emitted++
+		if emitted == 5 {
+			return stop
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, stop)
+	require.Equal(t, 5, emitted)
+}
+
+func TestQueryStream(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "tsq-workers-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	generateTestFiles(t, tmpDir, 200, false)
+	opts := QueryOptions{
+		Query: "(function_declaration name: (identifier) @name)",
+		Path:  tmpDir,
+		Jobs:  4,
+	}
+
+	want, err := Query(opts)
+	require.NoError(t, err)
+	require.Len(t, want, 200)
+
+	var legend []LegendEntry
+	opts.Legend = &legend
+	stream, errc := QueryStream(context.Background(), opts)
+	count := 0
+	for range stream {
+		count++
+	}
+	require.NoError(t, <-errc)
+	require.Equal(t, len(want), count)
+	require.Equal(t, []LegendEntry{{Name: "name", Captures: 200}}, legend)
+
+	// Cancelling stops the stream and reports why.
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, errc = QueryStream(ctx, QueryOptions{Query: opts.Query, Path: tmpDir, Jobs: 4})
+	<-stream
+	cancel()
+	for range stream {
+	}
+	require.ErrorIs(t, <-errc, context.Canceled)
+}
+
+func TestQueryResolved(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "tsq-workers-test-*")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	generateTestFiles(t, tmpDir, 3, false)
+
+	var diags []Diagnostic
+	var resolved QueryOptions
+	_, err = Query(QueryOptions{
+		Find:        "function",
+		Path:        tmpDir,
+		Diagnostics: &diags,
+		Resolved:    &resolved,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, QueryOptions{
+		Query:    findQueries["function"],
+		Find:     "function",
+		Language: "go",
+		Path:     tmpDir,
+		Jobs:     runtime.NumCPU(),
+		Schedule: "fifo",
+		MaxBytes: LanguageMaxBytes(Get("go")),
+	}, resolved)
+}
+
+func TestQueryPatternIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.go"), []byte("package a\n\nfunc F() {}\n\ntype T int\n"), 0644))
+
+	opts := QueryOptions{
+		Query: "(function_declaration name: (identifier) @fn) (type_spec name: (type_identifier) @type)",
+		Path:  tmpDir,
+	}
+	patterns := func() []int {
+		matches, err := Query(opts)
+		require.NoError(t, err)
+		var patterns []int
+		for _, m := range matches {
+			patterns = append(patterns, m.Pattern)
+		}
+		return patterns
+	}
+
+	require.Equal(t, []int{0, 1}, patterns(), "every pattern by default")
+	second := 1
+	opts.PatternIndex = &second
+	require.Equal(t, []int{1}, patterns())
+}